@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// maxResponseLen bounds a response added with /8ball-add, in runes.
+const maxResponseLen = 200
+
+// validateNewResponse checks that text can join resps, holding it to the
+// same DISABLED_RESPONSES and FILTER_PROFANITY rules as the loaded lists.
+func validateNewResponse(text string, resps []string) error {
+	switch {
+	case text == "":
+		return errors.New("the response is empty")
+	case utf8.RuneCountInString(text) > maxResponseLen:
+		return fmt.Errorf("the response is longer than %d characters", maxResponseLen)
+	case slices.ContainsFunc(resps, func(r string) bool { return strings.EqualFold(r, text) }):
+		return fmt.Errorf("%q is already a response", text)
+	case slices.Contains(cfg.DisabledResponses, text):
+		return fmt.Errorf("%q is disabled by DISABLED_RESPONSES", text)
+	}
+	if w, ok := containsWord(text, cfg.ProfanityWords); ok {
+		return fmt.Errorf("the response contains %q, which FILTER_PROFANITY drops", w)
+	}
+	return nil
+}
+
+// addResponse appends text to the global response list, also writing it to
+// RESPONSES_FILE when persist is set and the list came from there. It
+// returns the new list and whether it was persisted.
+func addResponse(text string, persist bool) (*responseSet, bool, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	live := currentResponses()
+	if err := validateNewResponse(text, live.Responses); err != nil {
+		return nil, false, err
+	}
+	persisted := false
+	if persist && live.Provenance.Source == provenanceFile {
+		if err := appendToResponsesFile(live.Provenance.Path, text); err != nil {
+			return nil, false, err
+		}
+		persisted = true
+	}
+
+	next := *live
+	next.Responses = append(slices.Clip(live.Responses), text)
+	next.Provenance.Count = len(next.Responses)
+	liveResponses.Store(&next)
+	return &next, persisted, nil
+}
+
+// appendToResponsesFile adds text to the responses file at path, keeping
+// its existing entries as they are. The file is replaced atomically so a
+// crash can't leave it half-written.
+func appendToResponsesFile(path, text string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("reading responses file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading responses file: %w", err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parsing responses file %s: %w", path, err)
+	}
+	entry, err := json.Marshal(text)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(append(entries, entry), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".responses-*.json")
+	if err != nil {
+		return fmt.Errorf("writing responses file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing responses file: %w", err)
+	}
+	if _, err := tmp.Write(append(out, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing responses file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing responses file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing responses file: %w", err)
+	}
+	return nil
+}
+
+// addResponseCommand adds the command text as a new response.
+func addResponseCommand(cmd slack.SlashCommand) *Reply {
+	text := normalizeText(cmd.Text)
+	s, persisted, err := addResponse(text, cfg.PersistAddedResponses)
+	if err != nil {
+		return &Reply{Text: "🎱 Couldn't add that: " + err.Error() + "."}
+	}
+	log.Printf("Admin %s added response %q", logID(cmd.UserID), text)
+	msg := fmt.Sprintf("🎱 Added %q. There are now %d responses.", text, len(s.Responses))
+	if !persisted {
+		msg += " It will be forgotten on restart or reload."
+	}
+	return &Reply{Text: msg}
+}
+
+// handleAddResponse processes the admin-only /8ball-add slash command
+func handleAddResponse(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, adminOnly("add responses", addResponseCommand))
+}
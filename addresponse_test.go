@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// addViaHTTP sends /8ball-add text as userID and returns the reply text.
+func addViaHTTP(t *testing.T, userID, text string) string {
+	t.Helper()
+	body := url.Values{"command": {"/8ball-add"}, "text": {text}, "user_id": {userID}, "team_id": {"T1"}, "channel_id": {"C1"}}.Encode()
+	w := httptest.NewRecorder()
+	handleAddResponse(w, signedRequest("/8ball-add", formContentType, body), testSecret)
+	var msg slack.WebhookMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("decoding reply %q: %v", w.Body, err)
+	}
+	return msg.Text
+}
+
+func TestAddResponse(t *testing.T) {
+	c := withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN"})
+	reply := addViaHTTP(t, "UADMIN", "  Outlook   excellent. ")
+	want := `🎱 Added "Outlook excellent.". There are now ` + strconv.Itoa(len(c.Responses)+1) + " responses. It will be forgotten on restart or reload."
+	if reply != want {
+		t.Errorf("reply = %q, want %q", reply, want)
+	}
+	live := currentResponses()
+	if !slices.Equal(live.Responses, append(slices.Clone(c.Responses), "Outlook excellent.")) || live.Provenance.Count != len(live.Responses) {
+		t.Errorf("responses after adding = %v (count %d)", live.Responses, live.Provenance.Count)
+	}
+	if len(cfg.Responses) != len(c.Responses) {
+		t.Error("adding changed the loaded configuration's list")
+	}
+}
+
+func TestAddResponseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		user string
+		text string
+		want string
+	}{
+		{"non-admin", nil, "U1", "Outlook excellent.", "Sorry, only admins can add responses."},
+		{"empty", nil, "UADMIN", "   ", "Couldn't add that: the response is empty."},
+		{"duplicate", nil, "UADMIN", "it is CERTAIN.", `Couldn't add that: "it is CERTAIN." is already a response.`},
+		{"too long", nil, "UADMIN", strings.Repeat("a", maxResponseLen+1), "is longer than 200 characters"},
+		{"disabled", map[string]string{"DISABLED_RESPONSES": "Outlook excellent."}, "UADMIN", "Outlook excellent.", "disabled by DISABLED_RESPONSES"},
+		{"profane", map[string]string{"FILTER_PROFANITY": "true"}, "UADMIN", "Hell yes.", "which FILTER_PROFANITY drops"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"ADMIN_USERS": "UADMIN"}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			c := withConfig(t, env)
+			if reply := addViaHTTP(t, tt.user, tt.text); !strings.Contains(reply, tt.want) {
+				t.Errorf("reply = %q, want %q", reply, tt.want)
+			}
+			if got := currentResponses().Responses; !slices.Equal(got, c.Responses) {
+				t.Errorf("responses changed to %v", got)
+			}
+		})
+	}
+}
+
+func TestAddResponsePersists(t *testing.T) {
+	path := writeFile(t, "responses.json", `["Yes.", {"text": "No.", "category": "no"}]`)
+	withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN", "RESPONSES_FILE": path, "PERSIST_ADDED_RESPONSES": "true"})
+	reply := addResponseCommand(slack.SlashCommand{UserID: "UADMIN", Text: "Outlook excellent."})
+	if reply.Text != `🎱 Added "Outlook excellent.". There are now 3 responses.` {
+		t.Errorf("reply = %q", reply.Text)
+	}
+	entries, err := loadResponsesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if texts := entryTexts(entries, map[string]responseEntry{}); !slices.Equal(texts, []string{"Yes.", "No.", "Outlook excellent."}) {
+		t.Errorf("responses file now has %v", texts)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"category": "no"`) {
+		t.Errorf("responses file lost the existing entry's metadata: %s", data)
+	}
+
+	// Persisting needs a file to write to.
+	withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN", "RESPONSES_FILE": "", "PERSIST_ADDED_RESPONSES": "true"})
+	if reply := addResponseCommand(slack.SlashCommand{UserID: "UADMIN", Text: "Outlook excellent."}); !strings.HasSuffix(reply.Text, "forgotten on restart or reload.") {
+		t.Errorf("reply without a file = %q", reply.Text)
+	}
+}
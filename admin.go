@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// adminOnly wraps fn so it only runs for admins, refusing everyone else with
+// a message naming the action.
+func adminOnly(action string, fn commandFunc) commandFunc {
+	return func(cmd slack.SlashCommand) *Reply {
+		if !cfg.isAdmin(cmd.UserID) {
+			logWarn("Rejected non-admin %s trying to %s", logID(cmd.UserID), action)
+			return &Reply{Text: fmt.Sprintf("Sorry, only admins can %s.", action)}
+		}
+		return fn(cmd)
+	}
+}
+
+// parseUserArg extracts a user ID from command text, accepting a raw ID such
+// as "U123" or a mention such as "<@U123|sam>".
+func parseUserArg(text string) string {
+	id := strings.TrimSpace(text)
+	if strings.HasPrefix(id, "<@") && strings.HasSuffix(id, ">") {
+		id = strings.TrimSuffix(strings.TrimPrefix(id, "<@"), ">")
+		id, _, _ = strings.Cut(id, "|")
+	}
+	return id
+}
+
+// resetUserCommand clears everything stored about one user, including their
+// count towards USER_DAILY_LIMIT.
+func resetUserCommand(cmd slack.SlashCommand) *Reply {
+	target := parseUserArg(cmd.Text)
+	if target == "" {
+		return &Reply{Text: "Usage: /8ball-reset @user"}
+	}
+
+	// Forget the daily count too, so the user can ask again straight away.
+	reset := users.reset(target)
+	if userAnswers.forget(target) {
+		reset = true
+	}
+	if !reset {
+		return &Reply{Text: fmt.Sprintf("🎱 Nothing stored for <@%s>.", target)}
+	}
+	log.Printf("Admin %s reset state for user %s", logID(cmd.UserID), logID(target))
+	return &Reply{Text: fmt.Sprintf("🎱 Reset state for <@%s>.", target)}
+}
+
+// handleResetUser processes the admin-only /8ball-reset slash command
+func handleResetUser(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, adminOnly("reset users", resetUserCommand))
+}
+
+// configCommand shows the effective configuration with secrets masked, to
+// explain why the bot is behaving the way it is.
+func configCommand(cmd slack.SlashCommand) *Reply {
+	out, err := json.MarshalIndent(cfg.Redacted(), "", "  ")
+	if err != nil {
+		logError("Error marshaling config: %v", err)
+		return &Reply{Text: "🎱 Couldn't show the config; see the logs."}
+	}
+	return &Reply{Text: "```\n" + string(out) + "\n```"}
+}
+
+// handleConfig processes the admin-only /8ball-config slash command
+func handleConfig(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, adminOnly("view the config", configCommand))
+}
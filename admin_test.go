@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestResetUserCommand(t *testing.T) {
+	withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN"})
+	run := commands["/8ball-reset"].run
+	users.recordAnswer("U1", "Will it rain?", "Yes.", time.Now())
+
+	tests := []struct {
+		name string
+		cmd  slack.SlashCommand
+		want string
+	}{
+		{"non-admin", slack.SlashCommand{UserID: "U2", Text: "U1"}, "only admins can reset users"},
+		{"no target", slack.SlashCommand{UserID: "UADMIN"}, "Usage: /8ball-reset @user"},
+		{"existing user by mention", slack.SlashCommand{UserID: "UADMIN", Text: "<@U1|sam>"}, "Reset state for <@U1>."},
+		{"already reset", slack.SlashCommand{UserID: "UADMIN", Text: "U1"}, "Nothing stored for <@U1>."},
+		{"nonexistent user", slack.SlashCommand{UserID: "UADMIN", Text: "U404"}, "Nothing stored for <@U404>."},
+	}
+	for _, tt := range tests {
+		if reply := run(tt.cmd); !strings.Contains(reply.Text, tt.want) {
+			t.Errorf("%s: reply %q lacks %q", tt.name, reply.Text, tt.want)
+		}
+	}
+	if _, ok := users.lastAnswer("U1"); ok {
+		t.Error("U1's history survived the reset")
+	}
+}
+
+func TestResetUserClearsDailyLimit(t *testing.T) {
+	withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN", "USER_DAILY_LIMIT": "1"})
+	req := askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}
+	if resp := ask(req); !resp.answered() {
+		t.Fatalf("first ask = %q, want an answer", resp.Text)
+	}
+	if resp := ask(req); resp.Text != replyUserDailyLimit {
+		t.Fatalf("second ask = %q, want the daily limit", resp.Text)
+	}
+	commands["/8ball-reset"].run(slack.SlashCommand{UserID: "UADMIN", Text: "U1"})
+	if resp := ask(req); !resp.answered() {
+		t.Errorf("ask after the reset = %q, want an answer", resp.Text)
+	}
+}
+
+func TestParseUserArg(t *testing.T) {
+	tests := []struct{ text, want string }{
+		{"U123", "U123"},
+		{" <@U123> ", "U123"},
+		{"<@W123|sam>", "W123"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := parseUserArg(tt.text); got != tt.want {
+			t.Errorf("parseUserArg(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestConfigRedacted(t *testing.T) {
+	c := withConfig(t, map[string]string{
+		"SLACK_BOT_TOKEN":      "xoxb-secret",
+		"SLACK_SIGNING_SECRET": "signing-secret",
+		"SLACK_APP_TOKEN":      "xapp-secret",
+		"WEBHOOK_TOKEN":        "webhook-secret",
+		"STRICT_QUESTIONS":     "false",
+	})
+	r := c.Redacted()
+	for name, got := range map[string]string{
+		"BotToken":      r.BotToken,
+		"SigningSecret": r.SigningSecret,
+		"AppToken":      r.AppToken,
+		"WebhookToken":  r.WebhookToken,
+	} {
+		if got != redacted {
+			t.Errorf("%s = %q, want it redacted", name, got)
+		}
+	}
+	if r.StrictQuestions || len(r.Responses) != len(c.Responses) || r.AnswerDelay != c.AnswerDelay {
+		t.Errorf("Redacted changed non-secret fields: %+v", r)
+	}
+	if c.BotToken != "xoxb-secret" {
+		t.Errorf("Redacted masked the original's BotToken")
+	}
+
+	// Unset secrets stay empty, so the dump shows they aren't configured.
+	if empty := (Config{}).Redacted(); empty.BotToken != "" || empty.WebhookToken != "" {
+		t.Errorf("Redacted filled in unset secrets: %+v", empty)
+	}
+}
+
+func TestHandleConfig(t *testing.T) {
+	withConfig(t, map[string]string{
+		"ADMIN_USERS":     "UADMIN",
+		"SLACK_BOT_TOKEN": "xoxb-secret",
+		"WEBHOOK_TOKEN":   "webhook-secret",
+		"ANSWER_DELAY":    "1s",
+	})
+	tests := []struct {
+		user     string
+		want     []string
+		dontWant []string
+	}{
+		{"UADMIN", []string{`"bot_token": "[redacted]"`, `"webhook_token": "[redacted]"`, `"answer_delay": 1000000000`, "It is certain"}, []string{"xoxb-secret", "webhook-secret"}},
+		{"U1", []string{"only admins can view the config"}, []string{"bot_token"}},
+	}
+	for _, tt := range tests {
+		body := url.Values{"command": {"/8ball-config"}, "user_id": {tt.user}, "team_id": {"T1"}, "channel_id": {"C1"}}.Encode()
+		w := httptest.NewRecorder()
+		handleConfig(w, signedRequest("/8ball-config", formContentType, body), testSecret)
+		var msg slack.WebhookMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+			t.Fatalf("%s: decoding reply %q: %v", tt.user, w.Body, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(msg.Text, want) {
+				t.Errorf("%s: reply lacks %s", tt.user, want)
+			}
+		}
+		for _, bad := range tt.dontWant {
+			if strings.Contains(msg.Text, bad) {
+				t.Errorf("%s: reply contains %s", tt.user, bad)
+			}
+		}
+	}
+}
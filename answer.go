@@ -0,0 +1,440 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockedSource makes a rand.Source safe for use from concurrent handlers.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+func newLockedRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{src: rand.NewSource(seed).(rand.Source64)})
+}
+
+// rng is shared by all requests.
+var rng = newLockedRand(time.Now().UnixNano())
+
+// Response categories for the built-in answers.
+const (
+	categoryYes   = "yes"
+	categoryNo    = "no"
+	categoryMaybe = "maybe"
+)
+
+// knownCategory reports whether category is one of the above.
+func knownCategory(category string) bool {
+	switch category {
+	case categoryYes, categoryNo, categoryMaybe:
+		return true
+	}
+	return false
+}
+
+// confidenceFor is how strongly an answer in category leans towards yes,
+// from 0 to 1, for integrations that act on answers. Unknown categories
+// are neutral.
+func confidenceFor(category string) float64 {
+	switch category {
+	case categoryYes:
+		return 0.8
+	case categoryNo:
+		return 0.2
+	}
+	return 0.5
+}
+
+// builtinCategories classifies the built-in answers of every theme.
+var builtinCategories = map[string]string{
+	// classic
+	"It is certain.":             categoryYes,
+	"It is decidedly so.":        categoryYes,
+	"Without a doubt.":           categoryYes,
+	"Yes - definitely.":          categoryYes,
+	"You may rely on it.":        categoryYes,
+	"As I see it, yes.":          categoryYes,
+	"Most likely.":               categoryYes,
+	"Outlook good.":              categoryYes,
+	"Yes.":                       categoryYes,
+	"Reply hazy, try again.":     categoryMaybe,
+	"Ask again later.":           categoryMaybe,
+	"Better not tell you now.":   categoryMaybe,
+	"Cannot predict now.":        categoryMaybe,
+	"Concentrate and ask again.": categoryMaybe,
+	"Signs point to no.":         categoryNo,
+	"Don't count on it.":         categoryNo,
+	"My reply is no.":            categoryNo,
+	"My sources say no.":         categoryNo,
+	"Outlook not so good.":       categoryNo,
+	"Very doubtful.":             categoryNo,
+
+	// short ("Yes." is classic)
+	"Definitely.": categoryYes,
+	"Sure.":       categoryYes,
+	"Likely.":     categoryYes,
+	"Unclear.":    categoryMaybe,
+	"Later.":      categoryMaybe,
+	"No.":         categoryNo,
+	"Nope.":       categoryNo,
+	"Doubtful.":   categoryNo,
+
+	// long
+	"The spirits have conferred at length, and their verdict is a resounding yes.":           categoryYes,
+	"Every sign I can read points the same way: go ahead, it will work out.":                 categoryYes,
+	"All the omens are aligned in your favour; you may proceed with confidence.":             categoryYes,
+	"The mists are swirling too thickly for a clear answer. Take a breath and ask me again.": categoryMaybe,
+	"I see many possible futures, and not one of them settles it. Ask again later.":          categoryMaybe,
+	"The signs are unmistakable, I'm afraid: this is not the path to take.":                  categoryNo,
+	"I have looked long and hard into the murk, and the answer that comes back is no.":       categoryNo,
+
+	// pirate
+	"Aye, without a doubt.":             categoryYes,
+	"Aye aye, cap'n!":                   categoryYes,
+	"The winds be in yer favour.":       categoryYes,
+	"As sure as the tide.":              categoryYes,
+	"Shiver me timbers, yes!":           categoryYes,
+	"X marks the spot.":                 categoryYes,
+	"The fog be too thick to tell.":     categoryMaybe,
+	"Ask again when the tide turns.":    categoryMaybe,
+	"Consult the parrot and ask again.": categoryMaybe,
+	"Me compass be spinnin'.":           categoryMaybe,
+	"Walk the plank, ye scallywag.":     categoryNo,
+	"Nay.":                              categoryNo,
+	"Not on yer life, matey.":           categoryNo,
+	"Davy Jones says no.":               categoryNo,
+	"The kraken disagrees.":             categoryNo,
+
+	// corporate
+	"Let's circle back: yes.":             categoryYes,
+	"That's a win-win.":                   categoryYes,
+	"Green light from leadership.":        categoryYes,
+	"Fully aligned.":                      categoryYes,
+	"Ship it.":                            categoryYes,
+	"Let's take that offline.":            categoryMaybe,
+	"Let's park that until next quarter.": categoryMaybe,
+	"Pending stakeholder review.":         categoryMaybe,
+	"That's not on the roadmap.":          categoryNo,
+	"We're deprioritizing that.":          categoryNo,
+	"Hard pass from legal.":               categoryNo,
+	"Not in this fiscal year.":            categoryNo,
+}
+
+// categoryOf returns the category of a response, or "" if it is unknown.
+// Categories given in the response overrides file cover custom answers.
+func categoryOf(text string) string {
+	if category, ok := builtinCategories[text]; ok {
+		return category
+	}
+	return currentResponses().Overrides.Meta[text].Category
+}
+
+// Canned replies for input the 8-ball won't answer.
+const (
+	replyNoQuestion  = "Where's the question?"
+	replyOpenEnded   = "I'm not a tarot deck. Yes or no questions please."
+	replyTrivial     = "🎱 Ask me a real question."
+	replyNoWords     = "🎱 Use your words."
+	replyNoResponses = "🎱 The 8-ball is empty. Ask an admin to check its responses."
+	replyNotYesNo    = "🎱 Try phrasing that as a yes or no question, like \"Should I...?\" or \"Will it...?\""
+)
+
+// defaultOpenQuestionWords are the interrogatives that mark a question as
+// open-ended rather than yes/no.
+var defaultOpenQuestionWords = []string{"who", "what", "when", "where", "why", "how", "if"}
+
+// compileQuestionWords builds the open-question pattern from a word list.
+// Words are matched as literal, case-insensitive prefixes of the question.
+func compileQuestionWords(words []string) (*regexp.Regexp, error) {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = regexp.QuoteMeta(strings.ToLower(w))
+	}
+	re, err := regexp.Compile("(?i)^(" + strings.Join(quoted, "|") + ")")
+	if err != nil {
+		return nil, fmt.Errorf("invalid OPEN_QUESTION_WORDS: %w", err)
+	}
+	return re, nil
+}
+
+// defaultTrivialWords are rhetorical one-word "questions".
+var defaultTrivialWords = []string{"right", "really", "seriously", "huh", "eh", "ok", "okay", "sure", "hmm", "yeah"}
+
+// isTrivialQuestion reports whether text is a single word from
+// cfg.TrivialWords followed by punctuation, e.g. "Right?" or "Really?!".
+func isTrivialQuestion(text string) bool {
+	word := strings.TrimRight(strings.TrimSpace(text), trailingPunct)
+	if word == "" || strings.ContainsAny(word, " \t") {
+		return false
+	}
+	_, ok := cfg.TrivialWords[strings.ToLower(word)]
+	return ok
+}
+
+// yesNoFormWords are the auxiliaries yes/no questions usually start with.
+var yesNoFormWords = []string{
+	"am", "are", "can", "could", "did", "do", "does", "has", "have", "is",
+	"may", "might", "must", "shall", "should", "was", "were", "will", "would",
+}
+
+// startsWithYesNoForm reports whether text starts with a yes/no auxiliary
+// such as "Should" or "Is", including contractions like "Isn't".
+func startsWithYesNoForm(text string) bool {
+	first, _, _ := strings.Cut(strings.TrimSpace(text), " ")
+	first = strings.ToLower(strings.TrimRight(first, trailingPunct+","))
+	first = strings.TrimSuffix(strings.ReplaceAll(first, "’", "'"), "n't")
+	// "won't", "can't" and "shan't" don't simply drop their "n't"
+	switch first {
+	case "wo":
+		first = "will"
+	case "ca":
+		first = "can"
+	case "sha":
+		first = "shall"
+	}
+	return slices.Contains(yesNoFormWords, first)
+}
+
+// compileOpenQuestion compiles the open-question pattern once at startup so
+// a bad configuration fails fast instead of erroring mid-request. A raw
+// pattern takes precedence over the word list; it is matched
+// case-insensitively.
+func compileOpenQuestion(pattern string, words []string) (*regexp.Regexp, error) {
+	if pattern != "" {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OPEN_QUESTION_PATTERN %q: %w", pattern, err)
+		}
+		return re, nil
+	}
+	if len(words) == 0 {
+		words = defaultOpenQuestionWords
+	}
+	return compileQuestionWords(words)
+}
+
+// pickIndex chooses a position in a list of n responses.
+func pickIndex(r *rand.Rand, n int) int {
+	return r.Intn(n)
+}
+
+// Errors returned by Answer for questions it won't answer.
+var (
+	ErrNoQuestion      = errors.New("input is not a question")
+	ErrTrivialQuestion = errors.New("question is trivial")
+	ErrNoWords         = errors.New("question has no words")
+	ErrOpenEnded       = errors.New("question is not yes/no")
+	ErrEmptyResponses  = errors.New("no responses to choose from")
+	ErrNotYesNoForm    = errors.New("question does not start with a yes/no form")
+)
+
+// How a Response was chosen.
+const (
+	sourceRandom  = "random"
+	sourceForced  = "forced"
+	sourceReask   = "reask"
+	sourceSession = "session"
+)
+
+// Response is the reply to a question.
+type Response struct {
+	Text string
+	// Index is the position of Text in the response list it was picked
+	// from, or -1 for a canned reply to a rejected question.
+	Index int
+	// Category is categoryYes, categoryNo, categoryMaybe or "" if unknown.
+	Category string
+	// Source says how the answer was chosen, e.g. sourceRandom.
+	Source string
+	// Note is shown after the answer, e.g. a milestone celebration.
+	Note string
+	// Reason is SHOW_REASON's mock rationale for the answer, if any.
+	Reason string
+	// Trace is how the reply was decided on, with DEBUG_TRACE.
+	Trace decisionTrace
+}
+
+// answered reports whether resp is a genuine answer rather than a canned
+// reply to a rejected question.
+func (resp Response) answered() bool {
+	return resp.Index >= 0
+}
+
+// pickedResponse builds the Response for resps[idx].
+func pickedResponse(resps []string, idx int, source string) Response {
+	return Response{Text: resps[idx], Index: idx, Category: categoryOf(resps[idx]), Source: source}
+}
+
+// shouldAnswer returns the Err sentinel for input the 8-ball won't answer,
+// or nil. Lenient (non-strict) channels also get open-ended questions
+// answered, and skip REQUIRE_YESNO_FORM.
+func shouldAnswer(text string, strict bool) error {
+	if !strings.HasSuffix(text, "?") {
+		return ErrNoQuestion
+	}
+	if !hasWords(text) {
+		return ErrNoWords
+	}
+
+	if isTrivialQuestion(text) {
+		return ErrTrivialQuestion
+	}
+	if !strict {
+		return nil
+	}
+
+	// The pattern is case-insensitive, so there's no need to copy the
+	// question into lower case first.
+	if cfg.OpenQuestion.MatchString(text) {
+		return ErrOpenEnded
+	}
+
+	if cfg.RequireYesNoForm && !startsWithYesNoForm(text) {
+		return ErrNotYesNoForm
+	}
+	return nil
+}
+
+// Answer picks a reply to a question from resps. Input the 8-ball won't
+// answer yields one of the Err sentinels so each frontend can respond in its
+// own way; strict says whether to insist on yes/no questions.
+//
+// With DEBUG_TRACE the Response, even for a rejected question, carries the
+// rules consulted on the way.
+func Answer(text string, resps []string, strict bool, r *rand.Rand) (Response, error) {
+	var trace decisionTrace
+	text = normalizeText(text)
+	if err := shouldAnswer(text, strict); err != nil {
+		trace.add("rejected: " + err.Error())
+		return Response{Index: -1, Trace: trace}, err
+	}
+	trace.add("question checks passed")
+
+	if len(resps) == 0 {
+		trace.add("no responses")
+		return Response{Index: -1, Trace: trace}, ErrEmptyResponses
+	}
+
+	traced := func(resp Response, rule string) (Response, error) {
+		trace.add(rule)
+		resp.Trace = trace
+		return resp, nil
+	}
+
+	// End-to-end tests can queue the next answer in testhooks builds
+	if idx, ok := forcedNextIndex(len(resps)); ok {
+		return traced(pickedResponse(resps, idx, sourceRandom), "test hook forced the answer")
+	}
+
+	// Trigger words fix the sentiment, if the list has an answer to match
+	if category, ok := forcedCategory(text, cfg.Force); ok {
+		if idx := pickCategory(r, resps, category); idx >= 0 {
+			return traced(pickedResponse(resps, idx, sourceForced), "forced word: "+category)
+		}
+		trace.add("forced word: no " + category + " answer")
+	} else {
+		trace.add("forced words: no match")
+	}
+
+	// Occasionally refuse to commit, like a real 8-ball's murky window
+	if cfg.ReaskRate > 0 && r.Float64() < cfg.ReaskRate {
+		if idx := pickCategory(r, resps, categoryMaybe); idx >= 0 {
+			return traced(pickedResponse(resps, idx, sourceReask), "reask")
+		}
+	}
+
+	// Provide a random response. Bag mode deals every answer once per
+	// cycle, so it ignores any boost.
+	if cfg.SelectionMode == selectionBag {
+		idx := slices.Index(resps, bags.next(resps, r))
+		return traced(pickedResponse(resps, idx, sourceRandom), "shuffle bag")
+	}
+	now := clock()
+	weights := responseWeights(resps, now)
+	if cfg.Boost.active(now) {
+		trace.add("boost")
+	}
+	if liveFlags().Mood && moodWeights(now.Hour()) != nil {
+		trace.add("mood")
+	}
+	if cfg.SelectionMode == selectionDecay {
+		usage := answerUsage.weights(resps, now)
+		for i := range weights {
+			usage[i] *= weights[i]
+		}
+		weights = usage
+	}
+	idx := pickWeighted(r, len(resps), weights)
+	if cfg.SelectionMode == selectionDecay {
+		answerUsage.use(resps[idx], now)
+		return traced(pickedResponse(resps, idx, sourceRandom), "decay")
+	}
+	return traced(pickedResponse(resps, idx, sourceRandom), "random")
+}
+
+// errorReply is the Slack reply for an error from Answer.
+func errorReply(err error) string {
+	switch {
+	case errors.Is(err, ErrNoQuestion):
+		return replyNoQuestion
+	case errors.Is(err, ErrTrivialQuestion):
+		return replyTrivial
+	case errors.Is(err, ErrNoWords):
+		return replyNoWords
+	case errors.Is(err, ErrOpenEnded):
+		return replyOpenEnded
+	case errors.Is(err, ErrNotYesNoForm):
+		return replyNotYesNo
+	}
+	return replyNoResponses
+}
+
+// answer is Answer with rejections turned into their canned Slack replies.
+func answer(text string, resps []string, strict bool, r *rand.Rand) Response {
+	resp, err := Answer(text, resps, strict, r)
+	if err != nil {
+		return Response{Text: errorReply(err), Index: -1, Trace: resp.Trace}
+	}
+	return resp
+}
+
+// pickCategory chooses a random position among the responses in category,
+// or returns -1 if there are none.
+func pickCategory(r *rand.Rand, resps []string, category string) int {
+	var candidates []int
+	for i, text := range resps {
+		if categoryOf(text) == category {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return -1
+	}
+	return candidates[pickIndex(r, len(candidates))]
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestAnswerIndexMatchesText(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"random", nil},
+		{"forced word", map[string]string{"FORCE_YES_WORDS": "pizza"}},
+		{"reask", map[string]string{"REASK_RATE": "1"}},
+		{"bag", map[string]string{"SELECTION_MODE": "bag"}},
+		{"decay", map[string]string{"SELECTION_MODE": "decay"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withConfig(t, tt.env)
+			if c.SelectionMode == selectionDecay {
+				answerUsage = newDecayCounter(c.DecayHalfLife)
+				t.Cleanup(func() { answerUsage = nil })
+			}
+			r := rand.New(rand.NewSource(1))
+			for range 50 {
+				resp, err := Answer("Should we get pizza?", c.Responses, true, r)
+				if err != nil {
+					t.Fatalf("Answer: %v", err)
+				}
+				if resp.Index < 0 || resp.Index >= len(c.Responses) || c.Responses[resp.Index] != resp.Text {
+					t.Fatalf("Answer = %q at index %d, which holds something else", resp.Text, resp.Index)
+				}
+			}
+		})
+	}
+}
+
+func TestAnswerRejectionIndex(t *testing.T) {
+	c := withConfig(t, nil)
+	for _, question := range []string{"Where's the question", "Why is the sky blue?", "Really?"} {
+		resp, err := Answer(question, c.Responses, true, rand.New(rand.NewSource(1)))
+		if err == nil || resp.Index != -1 {
+			t.Errorf("Answer(%q) = index %d, %v; want -1 and an error", question, resp.Index, err)
+		}
+	}
+}
+
+func TestPickIndexInRange(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 20} {
+		for range 100 {
+			if i := pickIndex(r, n); i < 0 || i >= n {
+				t.Fatalf("pickIndex(%d) = %d", n, i)
+			}
+		}
+	}
+}
+
+// replyLog matches the log line every slash command answer gets.
+var replyLog = regexp.MustCompile(`Replying with: (.*) \(index (-?\d+)\)`)
+
+func TestLoggedIndexMatchesReply(t *testing.T) {
+	c := withConfig(t, nil)
+	buf := captureLog(t)
+
+	for _, question := range []string{"Will it rain?", "Why?"} {
+		buf.Reset()
+		askCommand(slack.SlashCommand{Command: "/ask8ball", Text: question, UserID: "U1", ChannelID: "C1", TeamID: "T1"})
+		m := replyLog.FindStringSubmatch(buf.String())
+		if m == nil {
+			t.Fatalf("%q: no reply log line in %q", question, buf.String())
+		}
+		idx, _ := strconv.Atoi(m[2])
+		if idx == -1 {
+			if slices.Contains(c.Responses, m[1]) {
+				t.Errorf("%q: logged index -1 for the answer %q", question, m[1])
+			}
+			continue
+		}
+		if c.Responses[idx] != m[1] {
+			t.Errorf("%q: logged %q at index %d, which holds %q", question, m[1], idx, c.Responses[idx])
+		}
+	}
+}
+
+func TestReaskRate(t *testing.T) {
+	tests := []struct {
+		rate      string
+		wantReask bool
+	}{
+		{"1", true},
+		{"0", false},
+	}
+	for _, tt := range tests {
+		t.Run("REASK_RATE="+tt.rate, func(t *testing.T) {
+			c := withConfig(t, map[string]string{"REASK_RATE": tt.rate})
+			r := rand.New(rand.NewSource(42))
+			for range 100 {
+				resp, err := Answer("Will it rain?", c.Responses, true, r)
+				if err != nil {
+					t.Fatalf("Answer: %v", err)
+				}
+				if reask := resp.Source == sourceReask; reask != tt.wantReask {
+					t.Fatalf("Answer = %q from %s, want reask %v", resp.Text, resp.Source, tt.wantReask)
+				}
+				if tt.wantReask && resp.Category != categoryMaybe {
+					t.Fatalf("reask answer %q is %s, want %s", resp.Text, resp.Category, categoryMaybe)
+				}
+			}
+		})
+	}
+}
+
+func TestReaskRateValidation(t *testing.T) {
+	for _, rate := range []string{"-0.1", "1.5", "often"} {
+		t.Setenv("REASK_RATE", rate)
+		if _, err := loadConfig(); err == nil {
+			t.Errorf("REASK_RATE=%s loaded, want an error", rate)
+		}
+	}
+}
+
+func TestOpenQuestionWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		question string
+		wantOpen bool
+	}{
+		{"default what", nil, "What is for lunch?", true},
+		{"default why lower case", nil, "why not?", true},
+		{"default yes/no", nil, "Will it rain?", false},
+		{"default which", nil, "Which one is better?", false},
+		{"custom which", map[string]string{"OPEN_QUESTION_WORDS": "which, whom"}, "Which one is better?", true},
+		{"custom whom", map[string]string{"OPEN_QUESTION_WORDS": "which, whom"}, "Whom should I ask?", true},
+		{"custom replaces default", map[string]string{"OPEN_QUESTION_WORDS": "which, whom"}, "What is for lunch?", false},
+		{"custom with metacharacters", map[string]string{"OPEN_QUESTION_WORDS": "a.b"}, "axb then?", false},
+		{"pattern wins over words", map[string]string{"OPEN_QUESTION_PATTERN": `^how\b`, "OPEN_QUESTION_WORDS": "which"}, "Which one is better?", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withConfig(t, tt.env)
+			_, err := Answer(tt.question, c.Responses, true, rand.New(rand.NewSource(1)))
+			if open := errors.Is(err, ErrOpenEnded); open != tt.wantOpen {
+				t.Errorf("Answer(%q) error = %v, want open-ended %v", tt.question, err, tt.wantOpen)
+			}
+		})
+	}
+}
+
+func TestOpenQuestionPatternMustCompile(t *testing.T) {
+	t.Setenv("OPEN_QUESTION_PATTERN", "(what")
+	if _, err := loadConfig(); err == nil {
+		t.Error("an invalid OPEN_QUESTION_PATTERN loaded, want an error")
+	}
+}
+
+func TestIsTrivialQuestion(t *testing.T) {
+	withConfig(t, nil)
+	for _, word := range defaultTrivialWords {
+		for _, text := range []string{word + "?", strings.ToUpper(word) + "?!", " " + word + "?? "} {
+			if !isTrivialQuestion(text) {
+				t.Errorf("isTrivialQuestion(%q) = false, want true", text)
+			}
+		}
+	}
+	for _, text := range []string{"Go?", "Right now?", "Is that right?", "?", ""} {
+		if isTrivialQuestion(text) {
+			t.Errorf("isTrivialQuestion(%q) = true, want false", text)
+		}
+	}
+}
+
+func TestTrivialWordsConfig(t *testing.T) {
+	withConfig(t, map[string]string{"TRIVIAL_WORDS": "Innit, ja"})
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Innit?", true},
+		{"ja?", true},
+		{"Right?", false},
+	}
+	for _, tt := range tests {
+		if got := isTrivialQuestion(tt.text); got != tt.want {
+			t.Errorf("isTrivialQuestion(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTrivialQuestionReply(t *testing.T) {
+	c := withConfig(t, nil)
+	r := rand.New(rand.NewSource(1))
+	if resp := answer("Huh?", c.Responses, true, r); resp.Text != replyTrivial || resp.Index != -1 {
+		t.Errorf("answer(Huh?) = %q at %d, want the trivial reply", resp.Text, resp.Index)
+	}
+	if resp := answer("Go?", c.Responses, true, r); resp.Index < 0 {
+		t.Errorf("answer(Go?) = %q, want an answer", resp.Text)
+	}
+}
+
+func TestCompileOpenQuestionErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		words   []string
+		wantErr string
+	}{
+		{"(what", nil, "invalid OPEN_QUESTION_PATTERN"},
+		{`\p{Nope}`, nil, "invalid OPEN_QUESTION_PATTERN"},
+	}
+	for _, tt := range tests {
+		if _, err := compileOpenQuestion(tt.pattern, tt.words); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("compileOpenQuestion(%q) error = %v, want %q", tt.pattern, err, tt.wantErr)
+		}
+	}
+	// Words are quoted, so any list compiles.
+	if _, err := compileOpenQuestion("", []string{"(", "[x", `\`}); err != nil {
+		t.Errorf("compileOpenQuestion with metacharacter words: %v", err)
+	}
+}
+
+func TestStartupChecksNeedCompiledPattern(t *testing.T) {
+	c := withConfig(t, map[string]string{"SLACK_BOT_TOKEN": "xoxb-1", "SLACK_SIGNING_SECRET": "0123456789abcdef"})
+	if err := startupChecks(c); err != nil {
+		t.Fatalf("startupChecks: %v", err)
+	}
+	c.OpenQuestion = nil
+	if err := startupChecks(c); err == nil {
+		t.Error("startupChecks passed without a compiled open-question pattern")
+	}
+}
+
+// BenchmarkOpenQuestion compares the precompiled pattern with compiling it
+// per request, as regexp.MatchString did before. On a typical laptop:
+//
+//	BenchmarkOpenQuestion/precompiled            150 ns/op      0 B/op    0 allocs/op
+//	BenchmarkOpenQuestion/compiled_per_request 10500 ns/op   5784 B/op   52 allocs/op
+func BenchmarkOpenQuestion(b *testing.B) {
+	const pattern = "(?i)^(who|what|when|where|why|how|if)"
+	re, err := compileOpenQuestion("", defaultOpenQuestionWords)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("precompiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			re.MatchString("Will the deploy work?")
+		}
+	})
+	b.Run("compiled per request", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			regexp.MatchString(pattern, "Will the deploy work?")
+		}
+	})
+}
+
+func TestAnswerErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		text      string
+		empty     bool
+		want      error
+		wantReply string
+	}{
+		{"no question mark", nil, "It will rain", false, ErrNoQuestion, replyNoQuestion},
+		{"empty", nil, "", false, ErrNoQuestion, replyNoQuestion},
+		{"no words", nil, "?!?", false, ErrNoWords, replyNoWords},
+		{"emoji only", nil, "🤔 :thinking_face:?", false, ErrNoWords, replyNoWords},
+		{"trivial", nil, "Seriously?", false, ErrTrivialQuestion, replyTrivial},
+		{"open-ended", nil, "Why is it raining?", false, ErrOpenEnded, replyOpenEnded},
+		{"not yes/no form", map[string]string{"REQUIRE_YESNO_FORM": "true"}, "It will rain, you think?", false, ErrNotYesNoForm, replyNotYesNo},
+		{"empty responses", nil, "Will it rain?", true, ErrEmptyResponses, replyNoResponses},
+		{"answered", nil, "Will it rain?", false, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withConfig(t, tt.env)
+			resps := c.Responses
+			if tt.empty {
+				resps = nil
+			}
+			_, err := Answer(tt.text, resps, true, rand.New(rand.NewSource(1)))
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Answer(%q) error = %v, want %v", tt.text, err, tt.want)
+			}
+			if tt.want != nil {
+				if got := errorReply(err); got != tt.wantReply {
+					t.Errorf("errorReply = %q, want %q", got, tt.wantReply)
+				}
+			}
+		})
+	}
+}
+
+func TestStartsWithYesNoForm(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Should I ship?", true},
+		{"Is it ready?", true},
+		{"Make pizza?", false},
+		{"are we there yet?", true},
+		{"CAN it wait?", true},
+		{"Isn't it late?", true},
+		{"Won't it break?", true},
+		{"Can't we wait?", true},
+		{"Shan't we go?", true},
+		{"Doesn’t it work?", true},
+		{"Will?", true},
+		{"Is, like, it over?", true},
+		{"Island time?", false},
+		{"Why is it red?", false},
+		{"It will rain, you think?", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := startsWithYesNoForm(tt.text); got != tt.want {
+			t.Errorf("startsWithYesNoForm(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRequireYesNoForm(t *testing.T) {
+	for _, require := range []string{"true", "false"} {
+		c := withConfig(t, map[string]string{"REQUIRE_YESNO_FORM": require})
+		_, err := Answer("Make pizza?", c.Responses, true, rand.New(rand.NewSource(1)))
+		if want := require == "true"; errors.Is(err, ErrNotYesNoForm) != want {
+			t.Errorf("REQUIRE_YESNO_FORM=%s: Answer(\"Make pizza?\") error = %v", require, err)
+		}
+		if _, err := Answer("Should I ship?", c.Responses, true, rand.New(rand.NewSource(1))); err != nil {
+			t.Errorf("REQUIRE_YESNO_FORM=%s: Answer(\"Should I ship?\") error = %v", require, err)
+		}
+	}
+}
+
+func TestRandomSeedConfig(t *testing.T) {
+	tests := []struct {
+		env     string
+		set     bool
+		want    int64
+		wantErr bool
+	}{
+		{"", false, 0, false},
+		{"42", true, 42, false},
+		{"-7", true, -7, false},
+		{"forty-two", false, 0, true},
+		{"1.5", false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("RANDOM_SEED", tt.env)
+		c, err := loadConfig()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("RANDOM_SEED=%q: loadConfig error = %v, want error %v", tt.env, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if (c.RandomSeed != nil) != tt.set || tt.set && *c.RandomSeed != tt.want {
+			t.Errorf("RANDOM_SEED=%q: RandomSeed = %v, want %d (set %v)", tt.env, c.RandomSeed, tt.want, tt.set)
+		}
+	}
+}
+
+// answerSequence asks n questions with r and returns the answers.
+func answerSequence(t *testing.T, resps []string, r *rand.Rand, n int) []string {
+	t.Helper()
+	var out []string
+	for i := range n {
+		resp, err := Answer("Will it rain on day "+strconv.Itoa(i)+"?", resps, true, r)
+		if err != nil {
+			t.Fatalf("Answer: %v", err)
+		}
+		out = append(out, resp.Text)
+	}
+	return out
+}
+
+func TestRandomSeedReproducible(t *testing.T) {
+	c := withConfig(t, map[string]string{"RANDOM_SEED": "42"})
+	// Two processes started with the same seed.
+	first := answerSequence(t, c.Responses, newLockedRand(*c.RandomSeed), 50)
+	second := answerSequence(t, c.Responses, newLockedRand(*c.RandomSeed), 50)
+	if !slices.Equal(first, second) {
+		t.Errorf("same seed gave different answers:\n%q\n%q", first, second)
+	}
+	if other := answerSequence(t, c.Responses, newLockedRand(43), 50); slices.Equal(first, other) {
+		t.Error("a different seed gave the same 50 answers")
+	}
+
+	// The same holds end to end once main has seeded rng.
+	var runs [2][]string
+	for i := range runs {
+		seedRNG(t, *c.RandomSeed)
+		for range 20 {
+			runs[i] = append(runs[i], ask(askRequest{UserID: "U1", Question: "Will it rain?"}).Text)
+		}
+		purgeAll()
+	}
+	if !slices.Equal(runs[0], runs[1]) {
+		t.Errorf("seeded asks differ between runs:\n%q\n%q", runs[0], runs[1])
+	}
+}
+
+func TestLenientChannelSkipsStrictChecks(t *testing.T) {
+	c := withConfig(t, map[string]string{"REQUIRE_YESNO_FORM": "true"})
+	for _, text := range []string{"Why is it raining?", "It will rain, you think?"} {
+		if _, err := Answer(text, c.Responses, false, rand.New(rand.NewSource(1))); err != nil {
+			t.Errorf("lenient Answer(%q) = %v, want an answer", text, err)
+		}
+	}
+	if _, err := Answer("It will rain", c.Responses, false, rand.New(rand.NewSource(1))); !errors.Is(err, ErrNoQuestion) {
+		t.Errorf("lenient Answer without a question mark = %v, want ErrNoQuestion", err)
+	}
+}
+
+func TestConfidenceFor(t *testing.T) {
+	tests := []struct {
+		category string
+		want     float64
+	}{
+		{categoryYes, 0.8},
+		{categoryNo, 0.2},
+		{categoryMaybe, 0.5},
+		{"", 0.5},
+		{"sideways", 0.5},
+	}
+	for _, tt := range tests {
+		if got := confidenceFor(tt.category); got != tt.want {
+			t.Errorf("confidenceFor(%q) = %v, want %v", tt.category, got, tt.want)
+		}
+	}
+}
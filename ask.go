@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// askRequest is a question together with where it was asked.
+type askRequest struct {
+	EnterpriseID string
+	TeamID       string
+	ChannelID    string
+	UserID       string
+	Question     string
+	// Length is lengthNormal, or picks from the --short or --long lists.
+	Length string
+	// Session, when set, is the "session:<id>" the question was asked in.
+	Session string
+	// Reroll skips the consensus and answer caches, for Shake again.
+	Reroll bool
+	// Exclude are answers to avoid, such as those already drawn for a
+	// reading, as long as others are left.
+	Exclude []string
+}
+
+// keyQuestion is req's question as the cache keys see it, so answers of
+// different lengths or sessions are cached apart.
+func (req askRequest) keyQuestion() string {
+	if req.Session != "" {
+		return req.Length + "\x00" + req.Session + "\x00" + req.Question
+	}
+	if req.Length == "" || req.Length == lengthNormal {
+		return req.Question
+	}
+	return req.Length + "\x00" + req.Question
+}
+
+// answerCache is set at startup when ANSWER_CACHE_TTL is configured.
+var answerCache *ttlCache
+
+// consensusCache is set at startup when CONSENSUS_WINDOW is configured.
+var consensusCache *ttlCache
+
+// cacheKey identifies a user's question regardless of spacing or case.
+func cacheKey(userID, question string) string {
+	return userID + "\x00" + strings.ToLower(normalizeText(question))
+}
+
+// consensusKey identifies a question within a channel, whoever asks it.
+func consensusKey(channelID, question string) string {
+	return channelID + "\x00" + strings.ToLower(normalizeText(question))
+}
+
+// ask answers req with the responses for its team and records the outcome.
+// It is the entry point shared by every way of asking the 8-ball, so it is
+// where answers are counted towards the daily limits and MILESTONES, and
+// where DEFLECT_THIRD_PARTY turns away questions about someone else.
+func ask(req askRequest) Response {
+	var trace decisionTrace
+	if liveFlags().DeflectThirdParty {
+		if name, ok := thirdPartySubject(normalizeText(req.Question)); ok {
+			trace.add("deflect third party: " + name)
+			resp := Response{Text: deflection(name), Index: -1, Trace: trace}
+			logTrace(resp)
+			return resp
+		}
+		trace.add("deflect third party: no match")
+	}
+	slots, refusal := reserveAnswer(req.TeamID, req.ChannelID, req.UserID)
+	if refusal != "" {
+		trace.add("daily limit reached")
+		resp := Response{Text: refusal, Index: -1, Trace: trace}
+		logTrace(resp)
+		return resp
+	}
+	resp := cachedAsk(req)
+	resp.Trace = append(trace, resp.Trace...)
+	logTrace(resp)
+	if !resp.answered() {
+		slots.release()
+	}
+	if resp.answered() {
+		questionLength.Observe(float64(utf8.RuneCountInString(normalizeText(req.Question))))
+	}
+	if resp.answered() && len(cfg.Milestones) > 0 {
+		resp.Note = milestoneNote(askCounts.add(milestoneKey(req)))
+	}
+	return resp
+}
+
+// cachedAsk answers req from the consensus or answer cache when it can.
+//
+// In consensus mode everyone asking the same question in a channel within
+// the window gets the same answer. That takes precedence over the per-user
+// answer cache: a user who already has a cached answer still sees the
+// channel's consensus, so the team never sees conflicting verdicts.
+func cachedAsk(req askRequest) Response {
+	if req.Reroll {
+		return reroll(req)
+	}
+	if consensusCache != nil {
+		key := consensusKey(req.ChannelID, req.keyQuestion())
+		if resp, ok := consensusCache.get(key, clock()); ok {
+			recordAudit(req, resp, clock())
+			resp.Trace = nil
+			resp.Trace.add("consensus cache hit")
+			return resp
+		}
+		resp := reroll(req)
+		if resp.answered() {
+			consensusCache.put(key, resp, clock())
+		}
+		return resp
+	}
+
+	if answerCache != nil {
+		if resp, ok := answerCache.get(cacheKey(req.UserID, req.keyQuestion()), clock()); ok {
+			recordAudit(req, resp, clock())
+			resp.Trace = nil
+			resp.Trace.add("answer cache hit")
+			return resp
+		}
+	}
+	return reroll(req)
+}
+
+// reroll is ask without consulting the answer cache, though the fresh answer
+// still replaces any cached one. It never changes a channel's consensus.
+func reroll(req askRequest) Response {
+	now := clock()
+	start := time.Now()
+	// Asking isn't tied to a request context: mentions and button presses
+	// are answered after Slack has had its acknowledgement.
+	resps, err := responseProvider.Responses(context.Background(), req.EnterpriseID, req.TeamID, req.ChannelID)
+	if err != nil {
+		logError("Error getting responses: %v", err)
+		return Response{Text: replyNoResponses, Index: -1}
+	}
+	resps = lengthResponses(req, resps)
+	pool := resps
+	if len(req.Exclude) > 0 {
+		rest := slices.DeleteFunc(slices.Clone(resps), func(s string) bool { return slices.Contains(req.Exclude, s) })
+		if len(rest) > 0 {
+			pool = rest
+		}
+	}
+	strict := strictFor(req.ChannelID)
+	r := pickerFor(req.UserID, req.Question)
+	var resp Response
+	if req.Session != "" {
+		// Everyone in the session gets the same answer, so none of the
+		// per-user or per-channel variety applies.
+		resp = sessionAnswer(req.Session, req.Question, resps, strict)
+		resp.Trace.add("session: " + req.Session)
+	} else if channelRecent == nil || req.ChannelID == "" {
+		resp = answer(req.Question, pool, strict, r)
+	} else {
+		// Pick from the list minus the channel's recent answers, but keep
+		// the index pointing into the full list.
+		resp = answer(req.Question, channelRecent.avoid(req.ChannelID, pool), strict, r)
+		var trace decisionTrace
+		trace.add("avoid the channel's recent answers")
+		resp.Trace = append(trace, resp.Trace...)
+		if resp.answered() {
+			resp.Index = slices.Index(resps, resp.Text)
+			channelRecent.add(req.ChannelID, resp.Text)
+		}
+	}
+	if len(pool) < len(resps) && resp.answered() {
+		resp.Index = slices.Index(resps, resp.Text)
+	}
+	observeSelection(time.Since(start))
+	if resp.answered() {
+		if liveFlags().ShowReason {
+			resp.Reason = reasonFor(resp.Category, r)
+		}
+		stats.record(resp.Text)
+		answersTotal.WithLabelValues(metricCategory(resp)).Inc()
+		users.recordAnswer(req.UserID, req.Question, resp.Text, now)
+		recordAudit(req, resp, now)
+		if answerCache != nil {
+			answerCache.put(cacheKey(req.UserID, req.keyQuestion()), resp, now)
+		}
+	}
+	return resp
+}
+
+// slowSelection is how long selecting an answer may take before it is
+// logged as a warning.
+const slowSelection = 50 * time.Millisecond
+
+// observeSelection records that selecting an answer took d, warning when it
+// was slow.
+func observeSelection(d time.Duration) {
+	selectionSeconds.Observe(d.Seconds())
+	if d > slowSelection {
+		logWarn("answer selection took %v (SELECTION_MODE=%s)", d, cfg.SelectionMode)
+	}
+}
+
+// recordAudit adds an answer to the audit file, if there is one.
+func recordAudit(req askRequest, resp Response, t time.Time) {
+	if audit == nil {
+		return
+	}
+	if err := audit.record(req, resp, t); err != nil {
+		logError("Error writing audit log: %v", err)
+	}
+}
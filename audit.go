@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditEntry is one line of the audit file.
+type auditEntry struct {
+	TS         time.Time `json:"ts"`
+	UserID     string    `json:"user_id"`
+	Question   string    `json:"question"`
+	Answer     string    `json:"answer"`
+	Category   string    `json:"category"`
+	Confidence float64   `json:"confidence"`
+}
+
+// auditLog appends every answer given to a JSONL file. Writes are serialized
+// so concurrent answers never interleave within a line.
+type auditLog struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// audit is set at startup when AUDIT_FILE is configured.
+var audit *auditLog
+
+// openAuditLog opens path for appending, creating it if needed.
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening AUDIT_FILE: %w", err)
+	}
+	return &auditLog{w: f}, nil
+}
+
+// record appends resp as the answer to req, given at t. With ANONYMIZE_LOGS
+// the user ID is replaced by a hash.
+func (a *auditLog) record(req askRequest, resp Response, t time.Time) error {
+	userID := req.UserID
+	if cfg.AnonymizeLogs {
+		userID = anonymize(userID)
+	}
+	line, err := json.Marshal(auditEntry{
+		TS:         t.UTC(),
+		UserID:     userID,
+		Question:   normalizeText(req.Question),
+		Answer:     resp.Text,
+		Category:   resp.Category,
+		Confidence: confidenceFor(resp.Category),
+	})
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.w.Write(append(line, '\n'))
+	return err
+}
+
+// close closes the audit file.
+func (a *auditLog) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.w.Close()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withAudit sets audit to a fresh file for the rest of the test and returns
+// its path.
+func withAudit(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	a, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit = a
+	t.Cleanup(func() {
+		audit = nil
+		a.close()
+	})
+	return path
+}
+
+// readAudit decodes every line of the audit file at path.
+func readAudit(t *testing.T, path string) []auditEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("audit line %q isn't JSON: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAuditRecordsAnswers(t *testing.T) {
+	for _, anonymized := range []bool{false, true} {
+		withConfig(t, map[string]string{"ANONYMIZE_LOGS": strconv.FormatBool(anonymized)})
+		now := time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC)
+		setClock(t, now)
+		path := withAudit(t)
+
+		resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: " Will  it rain? "})
+		ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Why is it raining?"})
+
+		entries := readAudit(t, path)
+		if len(entries) != 1 {
+			t.Fatalf("ANONYMIZE_LOGS=%v: %d audit lines, want one for the answer only", anonymized, len(entries))
+		}
+		want := auditEntry{TS: now, UserID: "U1", Question: "Will it rain?", Answer: resp.Text, Category: resp.Category, Confidence: confidenceFor(resp.Category)}
+		if anonymized {
+			want.UserID = anonymize("U1")
+		}
+		if entries[0] != want {
+			t.Errorf("ANONYMIZE_LOGS=%v: audit line = %+v, want %+v", anonymized, entries[0], want)
+		}
+	}
+}
+
+func TestAuditAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(`{"answer":"earlier"}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	withConfig(t, nil)
+	a, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.record(askRequest{UserID: "U1", Question: "Will it rain?"}, Response{Text: "Yes."}, time.Now())
+	a.close()
+	if entries := readAudit(t, path); len(entries) != 2 || entries[0].Answer != "earlier" || entries[1].Answer != "Yes." {
+		t.Errorf("audit file = %+v, want the earlier line kept", entries)
+	}
+}
+
+// TestAuditConcurrentWrites records from many goroutines; with -race it
+// also checks the writes are synchronized.
+func TestAuditConcurrentWrites(t *testing.T) {
+	withConfig(t, nil)
+	path := withAudit(t)
+	const writers, each = 8, 50
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range each {
+				// Long lines make interleaving likely if writes weren't serialized.
+				q := "Will writer " + strconv.Itoa(i) + " finish line " + strconv.Itoa(j) + strings.Repeat(" and on", 300) + "?"
+				audit.record(askRequest{UserID: "U" + strconv.Itoa(i), Question: q}, Response{Text: "Yes."}, time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+	if entries := readAudit(t, path); len(entries) != writers*each {
+		t.Errorf("%d audit lines, want %d", len(entries), writers*each)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+)
+
+// Answer selection modes for SELECTION_MODE.
+const (
+	selectionRandom = "random"
+	selectionBag    = "bag"
+)
+
+// shuffleBag deals a list's answers in a random order, reshuffling only once
+// every answer has been dealt, so none repeats within a cycle.
+type shuffleBag struct {
+	r *rand.Rand
+
+	mu    sync.Mutex
+	items []string
+	pos   int
+}
+
+func newShuffleBag(items []string, r *rand.Rand) *shuffleBag {
+	return &shuffleBag{r: r, items: append([]string(nil), items...)}
+}
+
+// next deals the next answer, reshuffling at the start of every cycle.
+func (b *shuffleBag) next() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pos == 0 {
+		b.r.Shuffle(len(b.items), func(i, j int) {
+			b.items[i], b.items[j] = b.items[j], b.items[i]
+		})
+	}
+	item := b.items[b.pos]
+	b.pos = (b.pos + 1) % len(b.items)
+	return item
+}
+
+// maxShuffleBags bounds how many distinct lists have a bag; past that the
+// bags are discarded and start new cycles.
+const maxShuffleBags = 1000
+
+// bagStore keeps one shuffleBag per distinct response list.
+type bagStore struct {
+	mu   sync.Mutex
+	bags map[string]*shuffleBag
+}
+
+// bags is shared by all requests.
+var bags = &bagStore{bags: make(map[string]*shuffleBag)}
+
+// next deals the next answer from the bag for resps, which must not be empty.
+func (s *bagStore) next(resps []string, r *rand.Rand) string {
+	key := strings.Join(resps, "\x00")
+
+	s.mu.Lock()
+	b, ok := s.bags[key]
+	if !ok {
+		if len(s.bags) >= maxShuffleBags {
+			clear(s.bags)
+		}
+		b = newShuffleBag(resps, r)
+		s.bags[key] = b
+	}
+	s.mu.Unlock()
+	return b.next()
+}
+
+// purge drops every bag, returning how many there were. Lists get fresh
+// bags on their next answer.
+func (s *bagStore) purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.bags)
+	clear(s.bags)
+	return n
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestShuffleBagCycles(t *testing.T) {
+	items := numbered("answer", 20)
+	b := newShuffleBag(items, rand.New(rand.NewSource(1)))
+
+	var cycles [][]string
+	for range 5 {
+		cycle := make([]string, len(items))
+		for i := range cycle {
+			cycle[i] = b.next()
+		}
+		if sorted := slices.Sorted(slices.Values(cycle)); !slices.Equal(sorted, slices.Sorted(slices.Values(items))) {
+			t.Fatalf("cycle %q doesn't deal every answer exactly once", cycle)
+		}
+		cycles = append(cycles, cycle)
+	}
+	reshuffled := false
+	for _, cycle := range cycles[1:] {
+		reshuffled = reshuffled || !slices.Equal(cycle, cycles[0])
+	}
+	if !reshuffled {
+		t.Error("every cycle dealt the answers in the same order")
+	}
+	if !slices.Equal(items, numbered("answer", 20)) {
+		t.Error("the bag shuffled the caller's slice")
+	}
+}
+
+func TestShuffleBagSingleItem(t *testing.T) {
+	b := newShuffleBag([]string{"Yes."}, rand.New(rand.NewSource(1)))
+	for range 3 {
+		if got := b.next(); got != "Yes." {
+			t.Fatalf("next = %q, want Yes.", got)
+		}
+	}
+}
+
+// TestShuffleBagConcurrent deals whole cycles from many goroutines; every
+// answer must still come out once per cycle. Run it with -race.
+func TestShuffleBagConcurrent(t *testing.T) {
+	items := numbered("answer", 10)
+	b := newShuffleBag(items, newLockedRand(1))
+	const workers, perWorker = 8, 25 // 20 full cycles
+
+	var mu sync.Mutex
+	counts := map[string]int{}
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				item := b.next()
+				mu.Lock()
+				counts[item]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, item := range items {
+		if counts[item] != workers*perWorker/len(items) {
+			t.Errorf("%s dealt %d times, want %d", item, counts[item], workers*perWorker/len(items))
+		}
+	}
+}
+
+func TestBagSelectionMode(t *testing.T) {
+	c := withConfig(t, map[string]string{"SELECTION_MODE": "bag"})
+	r := rand.New(rand.NewSource(1))
+	seen := map[string]int{}
+	for range len(c.Responses) {
+		resp, err := Answer("Will it rain?", c.Responses, true, r)
+		if err != nil {
+			t.Fatalf("Answer: %v", err)
+		}
+		seen[resp.Text]++
+	}
+	for _, text := range c.Responses {
+		if seen[text] != 1 {
+			t.Errorf("%q given %d times in one cycle, want once", text, seen[text])
+		}
+	}
+	if n := bags.purge(); n != 1 {
+		t.Errorf("%d bags in use, want one for the list", n)
+	}
+}
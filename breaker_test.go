@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerStates(t *testing.T) {
+	errFail := errors.New("slack is down")
+	now := time.Unix(1700000000, 0)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	fail := func() error { return errFail }
+	ok := func() error { return nil }
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		fn      func() error
+		wantErr error
+		want    breakerState
+	}{
+		{"first failure stays closed", 0, fail, errFail, breakerClosed},
+		{"threshold opens", 0, fail, errFail, breakerOpen},
+		{"open short-circuits", 30 * time.Second, ok, errBreakerOpen, breakerOpen},
+		{"failed probe re-opens", 30 * time.Second, fail, errFail, breakerOpen},
+		{"re-opened short-circuits", 59 * time.Second, ok, errBreakerOpen, breakerOpen},
+		{"successful probe closes", time.Second, ok, nil, breakerClosed},
+		{"closed again counts afresh", 0, fail, errFail, breakerClosed},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if err := b.do(step.fn); !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: do = %v, want %v", step.name, err, step.wantErr)
+		}
+		if got := b.current(); got != step.want {
+			t.Fatalf("%s: state = %s, want %s", step.name, got, step.want)
+		}
+	}
+}
+
+func TestBreakerHalfOpenAllowsOneProbe(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.do(func() error { return errors.New("down") })
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("allow after the cooldown = false, want a probe")
+	}
+	if b.current() != breakerHalfOpen {
+		t.Fatalf("state = %s, want half-open", b.current())
+	}
+	if b.allow() {
+		t.Error("allow while a probe is in flight = true, want false")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the answer cache; expired entries are swept when it
+// fills up.
+const maxCacheEntries = 10000
+
+// cachedAnswer is an answer remembered by a ttlCache.
+type cachedAnswer struct {
+	resp    Response
+	expires time.Time
+}
+
+// ttlCache remembers answers for a short time so a quickly repeated question
+// gets the same answer instead of a re-roll.
+type ttlCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedAnswer
+}
+
+func newTTLCache(ttl time.Duration) *ttlCache {
+	return &ttlCache{ttl: ttl, entries: make(map[string]cachedAnswer)}
+}
+
+// get returns the answer cached under key if it hasn't expired at now.
+func (c *ttlCache) get(key string, now time.Time) (Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return Response{}, false
+	}
+	return e.resp, true
+}
+
+// put caches an answer under key until now plus the TTL.
+func (c *ttlCache) put(key string, resp Response, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < maxCacheEntries {
+		c.entries[key] = cachedAnswer{resp: resp, expires: now.Add(c.ttl)}
+	}
+}
+
+// purge forgets every cached answer, returning how many there were.
+func (c *ttlCache) purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entries)
+	clear(c.entries)
+	return n
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTTLCache(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	c := newTTLCache(10 * time.Second)
+	c.put("k", Response{Text: "Yes."}, start)
+
+	tests := []struct {
+		name  string
+		key   string
+		after time.Duration
+		hit   bool
+	}{
+		{"immediately", "k", 0, true},
+		{"within the TTL", "k", 9 * time.Second, true},
+		{"at expiry", "k", 10 * time.Second, false},
+		{"after expiry", "k", time.Minute, false},
+		{"other key", "other", 0, false},
+	}
+	for _, tt := range tests {
+		resp, ok := c.get(tt.key, start.Add(tt.after))
+		if ok != tt.hit || (ok && resp.Text != "Yes.") {
+			t.Errorf("%s: get = %q, %v; want hit %v", tt.name, resp.Text, ok, tt.hit)
+		}
+	}
+}
+
+// withAnswerCache turns on ANSWER_CACHE_TTL=ttl, as main does, and
+// DEBUG_TRACE for the rest of the test.
+func withAnswerCache(t *testing.T, ttl time.Duration) {
+	t.Helper()
+	withConfig(t, map[string]string{"ANSWER_CACHE_TTL": ttl.String(), "DEBUG_TRACE": "true"})
+	answerCache = newTTLCache(ttl)
+	t.Cleanup(func() { answerCache = nil })
+}
+
+func TestAskAnswerCache(t *testing.T) {
+	withAnswerCache(t, 10*time.Second)
+	advance := fakeClock(t, time.Unix(1700000000, 0))
+
+	req := askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}
+	first := ask(req)
+	if !first.answered() {
+		t.Fatalf("ask = %q, want an answer", first.Text)
+	}
+
+	advance(5 * time.Second)
+	for _, question := range []string{"Will it rain?", "will  it RAIN???"} {
+		req.Question = question
+		if resp := ask(req); resp.Text != first.Text || !slices.Contains(resp.Trace, "answer cache hit") {
+			t.Errorf("ask(%q) within the TTL = %q (trace %v), want the cached %q", question, resp.Text, resp.Trace, first.Text)
+		}
+	}
+
+	other := req
+	other.UserID = "U2"
+	if resp := ask(other); slices.Contains(resp.Trace, "answer cache hit") {
+		t.Error("another user got the first user's cached answer")
+	}
+
+	advance(6 * time.Second)
+	if resp := ask(req); slices.Contains(resp.Trace, "answer cache hit") {
+		t.Error("ask after the TTL hit the cache")
+	}
+}
+
+func TestAskAnswerCacheOff(t *testing.T) {
+	withConfig(t, map[string]string{"DEBUG_TRACE": "true"})
+	req := askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}
+	for range 5 {
+		if resp := ask(req); slices.Contains(resp.Trace, "answer cache hit") {
+			t.Fatal("ask hit a cache with ANSWER_CACHE_TTL unset")
+		}
+	}
+}
+
+// withConsensus turns on CONSENSUS_WINDOW=window, as main does, and
+// DEBUG_TRACE for the rest of the test.
+func withConsensus(t *testing.T, window time.Duration) {
+	t.Helper()
+	withConfig(t, map[string]string{"CONSENSUS_WINDOW": window.String(), "DEBUG_TRACE": "true"})
+	consensusCache = newTTLCache(window)
+	t.Cleanup(func() { consensusCache = nil })
+}
+
+func TestAskConsensus(t *testing.T) {
+	withConsensus(t, time.Minute)
+	advance := fakeClock(t, time.Unix(1700000000, 0))
+
+	first := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Should we ship today?"})
+	if !first.answered() {
+		t.Fatalf("ask = %q, want an answer", first.Text)
+	}
+	advance(30 * time.Second)
+
+	tests := []struct {
+		name      string
+		req       askRequest
+		consensus bool
+	}{
+		{"second user", askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U2", Question: "Should we ship today?"}, true},
+		{"differently typed", askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U3", Question: "should we  SHIP today??"}, true},
+		{"other channel", askRequest{TeamID: "T1", ChannelID: "C2", UserID: "U2", Question: "Should we ship today?"}, false},
+		{"other question", askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U2", Question: "Should we ship tomorrow?"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ask(tt.req)
+			if hit := slices.Contains(resp.Trace, "consensus cache hit"); hit != tt.consensus {
+				t.Fatalf("consensus hit = %v, want %v (trace %v)", hit, tt.consensus, resp.Trace)
+			}
+			if tt.consensus && resp.Text != first.Text {
+				t.Errorf("ask = %q, want the channel's consensus %q", resp.Text, first.Text)
+			}
+		})
+	}
+
+	advance(time.Minute)
+	resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U2", Question: "Should we ship today?"})
+	if slices.Contains(resp.Trace, "consensus cache hit") {
+		t.Error("ask after the window still got the consensus")
+	}
+}
+
+func TestConsensusBeatsAnswerCache(t *testing.T) {
+	withConsensus(t, time.Minute)
+	answerCache = newTTLCache(time.Minute)
+	t.Cleanup(func() { answerCache = nil })
+	fakeClock(t, time.Unix(1700000000, 0))
+
+	question := "Should we ship today?"
+	answerCache.put(cacheKey("U2", question), Response{Text: "Own cached answer.", Index: 0}, clock())
+	first := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: question})
+	if resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U2", Question: question}); resp.Text != first.Text {
+		t.Errorf("ask = %q, want the consensus %q over the user's cached answer", resp.Text, first.Text)
+	}
+}
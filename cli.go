@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const cliUsage = `usage: 8ball ask "Will it work?"`
+
+// runCLI handles local subcommands, returning the process exit code: 0 for
+// an answer, 1 when the question was rejected and 2 for bad usage.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "ask" {
+		fmt.Fprintln(stderr, cliUsage)
+		return 2
+	}
+
+	question := strings.Join(args[1:], " ")
+	if strings.TrimSpace(question) == "" {
+		fmt.Fprintln(stderr, cliUsage)
+		return 2
+	}
+
+	resp, err := Answer(question, currentResponses().Responses, cfg.StrictQuestions, rng)
+	if err != nil {
+		fmt.Fprintln(stderr, errorReply(err))
+		return 1
+	}
+	fmt.Fprintln(stdout, resp.Text)
+	return 0
+}
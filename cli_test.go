@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	c := withConfig(t, nil)
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout bool
+		wantStderr string
+	}{
+		{"valid question", []string{"ask", "Will", "it", "work?"}, 0, true, ""},
+		{"quoted question", []string{"ask", "Will it work?"}, 0, true, ""},
+		{"rejected question", []string{"ask", "Why did it break?"}, 1, false, replyOpenEnded},
+		{"not a question", []string{"ask", "it works"}, 1, false, replyNoQuestion},
+		{"no question", []string{"ask"}, 2, false, cliUsage},
+		{"blank question", []string{"ask", " "}, 2, false, cliUsage},
+		{"no argument", nil, 2, false, cliUsage},
+		{"unknown subcommand", []string{"shake"}, 2, false, cliUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCLI(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			answer := strings.TrimSuffix(stdout.String(), "\n")
+			if tt.wantStdout != slices.Contains(c.Responses, answer) {
+				t.Errorf("stdout = %q, want an answer %v", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
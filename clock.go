@@ -0,0 +1,7 @@
+package main
+
+import "time"
+
+// clock returns the current time. Time-dependent features call it instead of
+// time.Now so their behaviour can be pinned to a fixed instant.
+var clock = time.Now
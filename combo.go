@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// comboPrefix starts a question asking for the full decision kit: an
+// answer, a coin flip and a die roll.
+const comboPrefix = "combo:"
+
+// comboDieSides is the die /ask8ball combo: rolls.
+const comboDieSides = 6
+
+// comboRequest reports whether text asks for a combo, returning the
+// question without the prefix.
+func comboRequest(text string) (question string, ok bool) {
+	trimmed := strings.TrimSpace(text)
+	if len(trimmed) < len(comboPrefix) || !strings.EqualFold(trimmed[:len(comboPrefix)], comboPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[len(comboPrefix):]), true
+}
+
+// rollDie rolls a die with sides faces, numbered from 1.
+func rollDie(sides int, r *rand.Rand) int {
+	return 1 + r.Intn(sides)
+}
+
+// comboCommand answers "/ask8ball combo: ..." with the 8-ball's answer, a
+// coin flip and a die roll. A rejected question gets its canned reply
+// alone.
+func comboCommand(cmd slack.SlashCommand, question, length string) *Reply {
+	resp := ask(askRequest{
+		EnterpriseID: cmd.EnterpriseID,
+		TeamID:       cmd.TeamID,
+		ChannelID:    cmd.ChannelID,
+		UserID:       cmd.UserID,
+		Question:     question,
+		Length:       length,
+	})
+	if !resp.answered() {
+		return &Reply{Text: resp.Text}
+	}
+	log.Printf("Combo for %s %s", logID(cmd.UserID), commandFields(cmd))
+	return &Reply{
+		Text:     fmt.Sprintf("🎱 %s\n🪙 %s.\n🎲 %d", replyText(question, resp), coinFlip(rng), rollDie(comboDieSides, rng)),
+		Category: resp.Category,
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math/rand"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestComboRequest(t *testing.T) {
+	tests := []struct {
+		text     string
+		question string
+		ok       bool
+	}{
+		{"combo: Should I ship?", "Should I ship?", true},
+		{"  COMBO:Should I ship?", "Should I ship?", true},
+		{"combo:", "", true},
+		{"combo Should I ship?", "", false},
+		{"Should I ship? combo:", "", false},
+		{"com", "", false},
+	}
+	for _, tt := range tests {
+		question, ok := comboRequest(tt.text)
+		if question != tt.question || ok != tt.ok {
+			t.Errorf("comboRequest(%q) = %q, %v; want %q, %v", tt.text, question, ok, tt.question, tt.ok)
+		}
+	}
+}
+
+func TestRollDie(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	for _, sides := range []int{1, 6, 20} {
+		seen := map[int]bool{}
+		for range 1000 {
+			n := rollDie(sides, r)
+			if n < 1 || n > sides {
+				t.Fatalf("rollDie(%d) = %d", sides, n)
+			}
+			seen[n] = true
+		}
+		if len(seen) != sides {
+			t.Errorf("1000 rolls of a d%d showed %d faces", sides, len(seen))
+		}
+	}
+}
+
+// comboPattern matches a combo reply, capturing the answer line.
+var comboPattern = regexp.MustCompile(`^🎱 (.+)\n🪙 (Heads|Tails)\.\n🎲 [1-6]$`)
+
+func TestAskCommandCombo(t *testing.T) {
+	c := withConfig(t, nil)
+	for range 20 {
+		reply := askCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", UserID: "U1", Text: "combo: should I ship?"})
+		m := comboPattern.FindStringSubmatch(reply.Text)
+		if m == nil {
+			t.Fatalf("combo reply = %q, want an answer, a coin flip and a die roll", reply.Text)
+		}
+		if !slices.Contains(c.Responses, m[1]) || reply.Category != categoryOf(m[1]) {
+			t.Fatalf("combo answer %q (category %q) isn't one of the responses", m[1], reply.Category)
+		}
+	}
+	// The prefix is stripped before the question is checked, so it neither
+	// makes a question of a statement nor hides an open-ended one.
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"combo: Why ship?", replyOpenEnded},
+		{"combo: ship it", replyNoQuestion},
+		{"combo:", replyNoQuestion},
+	}
+	for _, tt := range tests {
+		if reply := askCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", UserID: "U1", Text: tt.text}); reply.Text != tt.want {
+			t.Errorf("%q: reply = %q, want %q", tt.text, reply.Text, tt.want)
+		}
+	}
+}
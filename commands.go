@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Reply is what a command answers with, independent of how it is sent.
+type Reply struct {
+	Text string
+	// ResponseType is "" (ephemeral) or slack.ResponseTypeInChannel.
+	ResponseType string
+	Blocks       []slack.Block
+	// Category is the answer's category when the reply is an answer.
+	Category string
+}
+
+// webhookMessage converts r into the JSON Slack expects.
+func (r *Reply) webhookMessage() *slack.WebhookMessage {
+	msg := &slack.WebhookMessage{Text: r.Text, ResponseType: r.ResponseType}
+	if len(r.Blocks) > 0 {
+		msg.Blocks = &slack.Blocks{BlockSet: r.Blocks}
+	}
+	return msg
+}
+
+// commandFunc produces the reply to a verified slash command.
+type commandFunc func(cmd slack.SlashCommand) *Reply
+
+// command is a slash command the bot understands.
+type command struct {
+	run  commandFunc
+	help string
+}
+
+// commands maps slash command names to their implementations. It is filled
+// in by init because the help command lists the table itself.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"/ask8ball":       {askCommand, "ask the Magic 8-ball a yes or no question"},
+		"/coinflip":       {coinFlipCommand, "flip a coin"},
+		"/8ball-help":     {helpCommand, "show this help"},
+		"/8ball-stats":    {statsCommand, "show how often each answer was given"},
+		"/8ball-export":   {adminOnly("export stats", exportCommand), "export the answer counts as CSV, or per category with \"categories\" (admins only)"},
+		"/8ball-feedback": {feedbackCommand, "rate your last answer: up or down"},
+		"/8ball-theme":    {themeCommand, "show or set this channel's theme"},
+		"/8ball-themes":   {themesCommand, "list the themes with a sample answer from each"},
+		"/8ball-preview":  {previewCommand, "sample a few answers from a theme without switching to it"},
+		"/8ball-strict":   {adminOnly("set channel strictness", strictCommand), "show or set whether this channel only takes yes/no questions (admins only)"},
+		"/8ball-selftest": {adminOnly("run the self-test", selfTestCommand), "check every answer branch (admins only)"},
+		"/8ball-reset":    {adminOnly("reset users", resetUserCommand), "clear a user's stored state (admins only)"},
+		"/8ball-purge":    {adminOnly("purge state", purgeCommand), "wipe all per-user and per-channel state (admins only)"},
+		"/8ball-add":      {adminOnly("add responses", addResponseCommand), "add a response to the global list (admins only)"},
+		"/8ball-reload":   {adminOnly("reload the responses", reloadCommand), "reload the responses, theme, feature flags and log level (admins only)"},
+		"/8ball-config":   {adminOnly("view the config", configCommand), "show the effective configuration (admins only)"},
+	}
+}
+
+// replyChannelNotAllowed answers requests from outside ALLOWED_CHANNELS.
+const replyChannelNotAllowed = "🎱 isn't available in this channel."
+
+// channelAllowed reports whether the bot may answer in channelID. An empty
+// allow list allows every channel.
+func channelAllowed(channelID string, allow map[string]struct{}) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	_, ok := allow[channelID]
+	return ok
+}
+
+// errUnknownCommand is returned by dispatch for commands not in the table.
+var errUnknownCommand = errors.New("unknown command")
+
+// dispatch routes a slash command to its implementation by name, so several
+// commands can share one Request URL. A payload without a command name, as
+// from a JSON integration, is treated as /ask8ball.
+func dispatch(cmd slack.SlashCommand) (*Reply, error) {
+	c, ok := commands[resolveAlias(commandName(cmd))]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownCommand, cmd.Command)
+	}
+	if !channelAllowed(cmd.ChannelID, cfg.AllowedChannels) {
+		return &Reply{Text: replyChannelNotAllowed}, nil
+	}
+	return c.run(cmd), nil
+}
+
+// commandName is the name cmd is dispatched under.
+func commandName(cmd slack.SlashCommand) string {
+	if cmd.Command == "" {
+		return "/ask8ball"
+	}
+	return cmd.Command
+}
+
+// resolveAlias returns the command an ALIASES entry maps name to, or name
+// itself.
+func resolveAlias(name string) string {
+	if target, ok := cfg.Aliases[name]; ok {
+		return target
+	}
+	return name
+}
+
+// parseAliases parses ALIASES entries such as "/8ball=/ask8ball". Aliases
+// must target a known command and can't shadow one.
+func parseAliases(entries []string) (map[string]string, error) {
+	aliases := make(map[string]string, len(entries))
+	for _, e := range entries {
+		alias, target, ok := strings.Cut(e, "=")
+		alias, target = strings.ToLower(strings.TrimSpace(alias)), strings.ToLower(strings.TrimSpace(target))
+		if !ok || !strings.HasPrefix(alias, "/") || len(alias) < 2 {
+			return nil, fmt.Errorf("invalid ALIASES entry %q: want /alias=/command", e)
+		}
+		if _, ok := commands[target]; !ok {
+			return nil, fmt.Errorf("invalid ALIASES entry %q: unknown command %s", e, target)
+		}
+		if _, ok := commands[alias]; ok {
+			return nil, fmt.Errorf("invalid ALIASES entry %q: %s is already a command", e, alias)
+		}
+		if prev, ok := aliases[alias]; ok && prev != target {
+			return nil, fmt.Errorf("invalid ALIASES entry %q: %s is already an alias for %s", e, alias, prev)
+		}
+		aliases[alias] = target
+	}
+	return aliases, nil
+}
+
+// serveCommand verifies a slash command request and replies with fn's
+// message, or refuses it outside ALLOWED_CHANNELS as dispatch does.
+func serveCommand(w http.ResponseWriter, r *http.Request, signingSecret string, fn commandFunc) {
+	payload, ok := verifyCommand(w, r, signingSecret)
+	if !ok {
+		return
+	}
+	if !channelAllowed(payload.ChannelID, cfg.AllowedChannels) {
+		writeJSON(w, (&Reply{Text: replyChannelNotAllowed}).webhookMessage())
+		return
+	}
+	writeJSON(w, fn(payload).webhookMessage())
+}
+
+// replyShakeAgain answers the first of the two shakes SHAKE_TWICE_WINDOW
+// asks for.
+const replyShakeAgain = "🎱 Shake again to get your answer."
+
+// askCommand answers a question, lists the possible answers for
+// "/ask8ball list", sets a lucky number for "/ask8ball set-lucky", draws
+// three answers for "/ask8ball reading: ...", or adds a coin flip and a die
+// roll for "/ask8ball combo: ...". A question after "session:<id>" gets the
+// same answer for everyone in that session.
+// Answers are private unless --public is given; see responseTypeFor.
+func askCommand(cmd slack.SlashCommand) *Reply {
+	text, flags, err := parseFlags(cmd.Text)
+	if err != nil {
+		return &Reply{Text: flagHelp(err)}
+	}
+	cmd.Text = text
+	if page, ok := listRequest(cmd.Text); ok {
+		return listCommand(cmd, page)
+	}
+	if arg, ok := luckyRequest(cmd.Text); ok {
+		return luckyCommand(cmd, arg)
+	}
+	if modalRequest(cmd) {
+		return modalCommand(cmd)
+	}
+	if refusal := askRefusal(cmd.TeamID, cmd.ChannelID, cmd.UserID); refusal != "" {
+		return &Reply{Text: refusal}
+	}
+	if question, ok := readingRequest(cmd.Text); ok {
+		return readingCommand(cmd, question, responseTypeFor(flags))
+	}
+	if question, ok := comboRequest(cmd.Text); ok {
+		return comboCommand(cmd, question, lengthFlag(flags))
+	}
+	session, question, ok := sessionRequest(cmd.Text)
+	if ok {
+		cmd.Text = question
+	}
+	if needsAnotherShake(cmd.UserID, cmd.Text) {
+		return &Reply{Text: replyShakeAgain}
+	}
+	req := askRequest{
+		EnterpriseID: cmd.EnterpriseID,
+		TeamID:       cmd.TeamID,
+		ChannelID:    cmd.ChannelID,
+		UserID:       cmd.UserID,
+		Question:     cmd.Text,
+		Length:       lengthFlag(flags),
+		Session:      session,
+	}
+	resp := ask(req)
+	log.Printf("Slash Command Replying with: %s (index %d) %s", resp.Text, resp.Index, commandFields(cmd))
+	if resp.answered() && responseTypeFor(flags) == slack.ResponseTypeInChannel {
+		return sharedMessage(cmd.UserID, "", cmd.Text, resp)
+	}
+	msg := replyMessage(resp, req)
+	msg.ResponseType = slack.ResponseTypeEphemeral
+	return msg
+}
+
+// maxAnswerDelay caps ANSWER_DELAY so a delayed answer still arrives within
+// Slack's 3 second budget for a slash command reply.
+const maxAnswerDelay = 2 * time.Second
+
+// delayAnswer holds an answer back for d, for suspense. It waits on a timer
+// rather than the shared state, so other requests are unaffected, and stops
+// early when ctx ends or maxAnswerDelay passes.
+func delayAnswer(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxAnswerDelay)
+	defer cancel()
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// listPageSize is how many answers /ask8ball list shows per page.
+const listPageSize = 50
+
+// listRequest reports whether text is the "list" keyword, optionally with a
+// page number ("list 2"), and which page it asks for.
+func listRequest(text string) (page int, ok bool) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 || fields[0] != "list" || len(fields) > 2 {
+		return 0, false
+	}
+	if len(fields) == 1 {
+		return 1, true
+	}
+	page, err := strconv.Atoi(fields[1])
+	if err != nil || page < 1 {
+		return 0, false
+	}
+	return page, true
+}
+
+// listCommand shows one page of the answers the 8-ball could give here.
+func listCommand(cmd slack.SlashCommand, page int) *Reply {
+	all, err := responseProvider.Responses(context.Background(), cmd.EnterpriseID, cmd.TeamID, cmd.ChannelID)
+	if err != nil {
+		logError("Error getting responses: %v %s", err, commandFields(cmd))
+		return &Reply{Text: replyNoResponses}
+	}
+	var resps []string
+	seen := make(map[string]bool)
+	for _, text := range all {
+		if !seen[text] {
+			seen[text] = true
+			resps = append(resps, text)
+		}
+	}
+	if len(resps) == 0 {
+		return &Reply{Text: replyNoResponses}
+	}
+
+	pages := (len(resps) + listPageSize - 1) / listPageSize
+	switch {
+	case page > pages && pages == 1:
+		return &Reply{Text: "🎱 There is only 1 page of answers."}
+	case page > pages:
+		return &Reply{Text: fmt.Sprintf("🎱 There are only %d pages of answers.", pages)}
+	}
+	start := (page - 1) * listPageSize
+	end := min(start+listPageSize, len(resps))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🎱 The %d possible answers here:", len(resps))
+	for i := start; i < end; i++ {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, resps[i])
+	}
+	if page < pages {
+		fmt.Fprintf(&b, "\nPage %d of %d; see more with /ask8ball list %d", page, pages, page+1)
+	}
+	return &Reply{Text: b.String()}
+}
+
+// coinFlip returns "Heads" or "Tails".
+func coinFlip(r *rand.Rand) string {
+	if r.Intn(2) == 0 {
+		return "Heads"
+	}
+	return "Tails"
+}
+
+// coinFlipCommand flips a coin.
+func coinFlipCommand(cmd slack.SlashCommand) *Reply {
+	return &Reply{Text: "🪙 " + coinFlip(rng) + "."}
+}
+
+// helpText lists the commands the bot understands.
+func helpText() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	b.WriteString("Commands:")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n• %s - %s", name, commands[name].help)
+	}
+	return b.String()
+}
+
+// helpCommand lists the available commands.
+func helpCommand(cmd slack.SlashCommand) *Reply {
+	return &Reply{Text: helpText()}
+}
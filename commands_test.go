@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestDispatch(t *testing.T) {
+	c := withConfig(t, nil)
+	isAnswer := func(text string) bool {
+		return slices.ContainsFunc(c.Responses, func(answer string) bool { return strings.Contains(text, answer) })
+	}
+	tests := []struct {
+		name  string
+		cmd   slack.SlashCommand
+		check func(text string) bool
+	}{
+		{"ask", slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?"}, isAnswer},
+		{"no command name", slack.SlashCommand{Text: "Will it rain?"}, isAnswer},
+		{"coin flip", slack.SlashCommand{Command: "/coinflip"}, func(text string) bool {
+			return text == "🪙 Heads." || text == "🪙 Tails."
+		}},
+		{"help", slack.SlashCommand{Command: "/8ball-help"}, func(text string) bool {
+			return strings.HasPrefix(text, "Commands:") && strings.Contains(text, "• /coinflip - flip a coin")
+		}},
+		{"stats", slack.SlashCommand{Command: "/8ball-stats"}, func(text string) bool {
+			// The asks above have been counted.
+			return strings.Contains(text, " × ")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cmd.UserID, tt.cmd.ChannelID, tt.cmd.TeamID = "U1", "C1", "T1"
+			reply, err := dispatch(tt.cmd)
+			if err != nil {
+				t.Fatalf("dispatch: %v", err)
+			}
+			if !tt.check(reply.Text) {
+				t.Errorf("reply %q isn't what %s gives", reply.Text, commandName(tt.cmd))
+			}
+		})
+	}
+}
+
+func TestDispatchUnknownCommand(t *testing.T) {
+	withConfig(t, nil)
+	cmd := slack.SlashCommand{Command: "/tarot", UserID: "U1", ChannelID: "C1"}
+	if _, err := dispatch(cmd); !errors.Is(err, errUnknownCommand) {
+		t.Errorf("dispatch error = %v, want errUnknownCommand", err)
+	}
+	reply := commandReply(context.Background(), cmd)
+	if !strings.HasPrefix(reply.Text, "🎱 I don't know /tarot.") || !strings.Contains(reply.Text, "Commands:") {
+		t.Errorf("reply = %q, want the help", reply.Text)
+	}
+}
+
+func TestHandleSlashCommandRoutes(t *testing.T) {
+	withConfig(t, nil)
+	body := url.Values{"command": {"/coinflip"}, "user_id": {"U1"}, "channel_id": {"C1"}}.Encode()
+	w := httptest.NewRecorder()
+	handleSlashCommand(w, signedRequest("/ask8ball", formContentType, body), testSecret, nil)
+	var msg slack.WebhookMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("decoding reply %q: %v", w.Body, err)
+	}
+	if !strings.HasPrefix(msg.Text, "🪙 ") {
+		t.Errorf("/coinflip through /ask8ball replied %q", msg.Text)
+	}
+}
+
+func TestDelayAnswer(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		delay    time.Duration
+		min, max time.Duration
+	}{
+		{"no delay", context.Background(), 0, 0, 10 * time.Millisecond},
+		{"negative delay", context.Background(), -time.Second, 0, 10 * time.Millisecond},
+		{"small delay", context.Background(), 30 * time.Millisecond, 30 * time.Millisecond, time.Second},
+		{"context already done", cancelled, time.Second, 0, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			delayAnswer(tt.ctx, tt.delay)
+			if took := time.Since(start); took < tt.min || took > tt.max {
+				t.Errorf("delayAnswer(%v) took %v, want %v to %v", tt.delay, took, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestDelayAnswerStopsAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	delayAnswer(ctx, maxAnswerDelay)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("delayAnswer ran %v past a 30ms deadline", took)
+	}
+}
+
+func TestAnswerDelayConfig(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", 0},
+		{"500ms", 500 * time.Millisecond},
+		{"10s", maxAnswerDelay},
+	}
+	for _, tt := range tests {
+		c := withConfig(t, map[string]string{"ANSWER_DELAY": tt.env})
+		if c.AnswerDelay != tt.want {
+			t.Errorf("ANSWER_DELAY=%q loaded as %v, want %v", tt.env, c.AnswerDelay, tt.want)
+		}
+	}
+}
+
+func TestCommandReplyDelaysOnlyAnswers(t *testing.T) {
+	withConfig(t, map[string]string{"ANSWER_DELAY": "50ms"})
+	tests := []struct {
+		cmd     slack.SlashCommand
+		delayed bool
+	}{
+		{slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", TeamID: "T1", ChannelID: "C1", UserID: "U1"}, true},
+		{slack.SlashCommand{Command: "/ask8ball", Text: "help", TeamID: "T1", ChannelID: "C1", UserID: "U1"}, true},
+		{slack.SlashCommand{Command: "/8ball-stats", TeamID: "T1", ChannelID: "C1", UserID: "U1"}, false},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		commandReply(context.Background(), tt.cmd)
+		if delayed := time.Since(start) >= 50*time.Millisecond; delayed != tt.delayed {
+			t.Errorf("%s %q delayed = %v, want %v", tt.cmd.Command, tt.cmd.Text, delayed, tt.delayed)
+		}
+	}
+}
+
+func TestParseAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]string
+		wantErr string
+	}{
+		{"none", nil, map[string]string{}, ""},
+		{"several", []string{"/8ball=/ask8ball", " /Magic8 = /ASK8BALL ", "/flip=/coinflip"},
+			map[string]string{"/8ball": "/ask8ball", "/magic8": "/ask8ball", "/flip": "/coinflip"}, ""},
+		{"repeated", []string{"/8ball=/ask8ball", "/8ball=/ask8ball"}, map[string]string{"/8ball": "/ask8ball"}, ""},
+		{"no target", []string{"/8ball"}, nil, "want /alias=/command"},
+		{"no slash", []string{"8ball=/ask8ball"}, nil, "want /alias=/command"},
+		{"bare slash", []string{"/=/ask8ball"}, nil, "want /alias=/command"},
+		{"unknown target", []string{"/tarot=/8ball-tarot"}, nil, "unknown command /8ball-tarot"},
+		{"shadows a command", []string{"/coinflip=/ask8ball"}, nil, "/coinflip is already a command"},
+		{"two targets", []string{"/8ball=/ask8ball", "/8ball=/coinflip"}, nil, "/8ball is already an alias for /ask8ball"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAliases(tt.entries)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseAliases error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAliases: %v", err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseAliases = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAliasesDispatch(t *testing.T) {
+	c := withConfig(t, map[string]string{"ALIASES": "/8ball=/ask8ball,/magic8=/ask8ball"})
+	for _, name := range []string{"/ask8ball", "/8ball", "/magic8"} {
+		if got := resolveAlias(name); got != "/ask8ball" {
+			t.Errorf("resolveAlias(%s) = %s, want /ask8ball", name, got)
+		}
+		reply, err := dispatch(slack.SlashCommand{Command: name, Text: "Will it rain?", UserID: "U1", ChannelID: "C1", TeamID: "T1"})
+		if err != nil {
+			t.Fatalf("dispatch %s: %v", name, err)
+		}
+		if !slices.ContainsFunc(c.Responses, func(answer string) bool { return strings.Contains(reply.Text, answer) }) {
+			t.Errorf("%s replied %q, want an answer", name, reply.Text)
+		}
+	}
+	if got := resolveAlias("/tarot"); got != "/tarot" {
+		t.Errorf("resolveAlias(/tarot) = %s, want it unchanged", got)
+	}
+	if _, err := dispatch(slack.SlashCommand{Command: "/tarot", UserID: "U1", ChannelID: "C1"}); !errors.Is(err, errUnknownCommand) {
+		t.Errorf("dispatch /tarot error = %v, want errUnknownCommand", err)
+	}
+
+	t.Setenv("ALIASES", "/coinflip=/ask8ball")
+	if _, err := loadConfig(); err == nil {
+		t.Error("an alias shadowing /coinflip loaded")
+	}
+}
+
+func TestChannelAllowed(t *testing.T) {
+	allow := map[string]struct{}{"C1": {}, "C2": {}}
+	tests := []struct {
+		channel string
+		allow   map[string]struct{}
+		want    bool
+	}{
+		{"C1", allow, true},
+		{"C3", allow, false},
+		{"", allow, false},
+		{"C3", nil, true},
+		{"C3", map[string]struct{}{}, true},
+	}
+	for _, tt := range tests {
+		if got := channelAllowed(tt.channel, tt.allow); got != tt.want {
+			t.Errorf("channelAllowed(%q, %v) = %v, want %v", tt.channel, tt.allow, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedChannels(t *testing.T) {
+	withConfig(t, map[string]string{"ALLOWED_CHANNELS": "C1, C2"})
+	for _, tt := range []struct {
+		channel string
+		allowed bool
+	}{{"C1", true}, {"C2", true}, {"C3", false}} {
+		reply, err := dispatch(slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", UserID: "U1", ChannelID: tt.channel, TeamID: "T1"})
+		if err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+		if refused := reply.Text == replyChannelNotAllowed; refused == tt.allowed {
+			t.Errorf("%s: reply %q, want allowed %v", tt.channel, reply.Text, tt.allowed)
+		}
+		if !tt.allowed && reply.ResponseType != "" && reply.ResponseType != slack.ResponseTypeEphemeral {
+			t.Errorf("%s: refusal is %s, want ephemeral", tt.channel, reply.ResponseType)
+		}
+	}
+}
+
+// TestAllowedChannelsRoutes calls each command's own Request URL, which
+// doesn't go through dispatch, from inside and outside ALLOWED_CHANNELS.
+func TestAllowedChannelsRoutes(t *testing.T) {
+	withConfig(t, map[string]string{"ALLOWED_CHANNELS": "C1"})
+	for _, rt := range routes(testSecret, nil) {
+		if !strings.HasPrefix(rt.path, "/8ball-") {
+			continue
+		}
+		for _, channel := range []string{"C1", "C3"} {
+			body := url.Values{"command": {rt.path}, "team_id": {"T1"}, "channel_id": {channel}, "user_id": {"U1"}}.Encode()
+			w := httptest.NewRecorder()
+			rt.handler(w, signedRequest(rt.path, formContentType, body))
+			var msg slack.WebhookMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+				t.Fatalf("%s in %s: decoding reply %q: %v", rt.path, channel, w.Body, err)
+			}
+			if refused := msg.Text == replyChannelNotAllowed; refused != (channel == "C3") {
+				t.Errorf("%s in %s replied %q", rt.path, channel, msg.Text)
+			}
+		}
+	}
+}
+
+func TestAskCommandReply(t *testing.T) {
+	c := withConfig(t, nil)
+	tests := []struct {
+		name         string
+		text         string
+		wantText     string // "" for any answer
+		responseType string
+		wantBlocks   bool
+	}{
+		{"answer", "Will it rain?", "", slack.ResponseTypeEphemeral, true},
+		{"public answer", "Will it rain? --public", "", slack.ResponseTypeInChannel, false},
+		{"no question", "It will rain", replyNoQuestion, slack.ResponseTypeEphemeral, false},
+		{"open-ended", "Why is it raining?", replyOpenEnded, slack.ResponseTypeEphemeral, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: tt.text, UserID: "U1", ChannelID: "C1", TeamID: "T1"})
+			if reply.ResponseType != tt.responseType {
+				t.Errorf("ResponseType = %q, want %q", reply.ResponseType, tt.responseType)
+			}
+			if (len(reply.Blocks) > 0) != tt.wantBlocks {
+				t.Errorf("Blocks = %v, want blocks %v", reply.Blocks, tt.wantBlocks)
+			}
+			if tt.wantText != "" {
+				if !strings.Contains(reply.Text, tt.wantText) || reply.Category != "" {
+					t.Errorf("reply = %q in category %q, want %q and none", reply.Text, reply.Category, tt.wantText)
+				}
+				return
+			}
+			i := slices.IndexFunc(c.Responses, func(answer string) bool { return strings.Contains(reply.Text, answer) })
+			if i < 0 {
+				t.Fatalf("reply %q holds no answer", reply.Text)
+			}
+			if want := categoryOf(c.Responses[i]); reply.Category != want {
+				t.Errorf("Category = %q, want %q for %q", reply.Category, want, c.Responses[i])
+			}
+		})
+	}
+}
+
+func TestReplyWebhookMessage(t *testing.T) {
+	blocks := []slack.Block{slack.NewDividerBlock()}
+	msg := (&Reply{Text: "Yes.", ResponseType: slack.ResponseTypeInChannel, Blocks: blocks, Category: categoryYes}).webhookMessage()
+	if msg.Text != "Yes." || msg.ResponseType != slack.ResponseTypeInChannel || msg.Blocks == nil || len(msg.Blocks.BlockSet) != 1 {
+		t.Errorf("webhookMessage = %+v", msg)
+	}
+	if msg := (&Reply{Text: "No."}).webhookMessage(); msg.Blocks != nil {
+		t.Errorf("a reply without blocks sent %+v", msg.Blocks)
+	}
+}
+
+func TestListRequest(t *testing.T) {
+	tests := []struct {
+		text string
+		page int
+		ok   bool
+	}{
+		{"list", 1, true},
+		{" LIST ", 1, true},
+		{"list 3", 3, true},
+		{"list 0", 0, false},
+		{"list two", 0, false},
+		{"list 2 3", 0, false},
+		{"list the answers?", 0, false},
+		{"Will it rain?", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		if page, ok := listRequest(tt.text); page != tt.page || ok != tt.ok {
+			t.Errorf("listRequest(%q) = %d, %v; want %d, %v", tt.text, page, ok, tt.page, tt.ok)
+		}
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	items := make([]string, 120)
+	for i := range items {
+		items[i] = fmt.Sprintf("%q", fmt.Sprintf("Answer %d.", i+1))
+	}
+	overrides := writeFile(t, "overrides.json", `{"teams": {"TBIG": [`+strings.Join(items, ",")+`]}}`)
+	c := withConfig(t, map[string]string{"RESPONSE_OVERRIDES_FILE": overrides})
+
+	ask := func(team, text string) string {
+		return askCommand(slack.SlashCommand{Command: "/ask8ball", Text: text, UserID: "U1", ChannelID: "C1", TeamID: team}).Text
+	}
+
+	full := ask("T1", "list")
+	if !strings.HasPrefix(full, fmt.Sprintf("🎱 The %d possible answers here:", len(c.Responses))) || strings.Contains(full, "Page ") {
+		t.Errorf("list = %q", full)
+	}
+	for i, answer := range c.Responses {
+		if want := fmt.Sprintf("\n%d. %s", i+1, answer); !strings.Contains(full, want) {
+			t.Errorf("list lacks %q", want)
+		}
+	}
+
+	tests := []struct {
+		text     string
+		want     []string
+		dontWant []string
+	}{
+		{"list", []string{"The 120 possible answers here:", "\n1. Answer 1.", "\n50. Answer 50.", "Page 1 of 3; see more with /ask8ball list 2"}, []string{"51. "}},
+		{"list 3", []string{"\n101. Answer 101.", "\n120. Answer 120."}, []string{"\n100. ", "Page "}},
+		{"list 4", []string{"There are only 3 pages of answers."}, []string{"Answer"}},
+	}
+	for _, tt := range tests {
+		got := ask("TBIG", tt.text)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%q lacks %q", tt.text, want)
+			}
+		}
+		for _, bad := range tt.dontWant {
+			if strings.Contains(got, bad) {
+				t.Errorf("%q has %q", tt.text, bad)
+			}
+		}
+	}
+
+	if got := ask("T1", "Is the list ready?"); !slices.ContainsFunc(c.Responses, func(answer string) bool { return strings.Contains(got, answer) }) {
+		t.Errorf("a question mentioning list got %q, want an answer", got)
+	}
+}
+
+// TestHandleSlashCommandAnswers sends signed /ask8ball requests over HTTP,
+// as Slack does, and checks the reply body is commandReply's Reply.
+func TestHandleSlashCommandAnswers(t *testing.T) {
+	c := withConfig(t, nil)
+	isAnswer := func(text string) bool { return slices.Contains(c.Responses, text) }
+	tests := []struct {
+		name         string
+		text         string
+		responseType string
+		check        func(text string) bool
+	}{
+		{"question", "Will the deploy work?", slack.ResponseTypeEphemeral, isAnswer},
+		{"public", "--public Will the deploy work?", slack.ResponseTypeInChannel, func(text string) bool {
+			return slices.ContainsFunc(c.Responses, func(answer string) bool { return strings.Contains(text, answer) })
+		}},
+		{"open-ended", "Why is it raining?", slack.ResponseTypeEphemeral, func(text string) bool { return text == replyOpenEnded }},
+		{"combo", "combo: Should I ship?", "", func(text string) bool {
+			return strings.HasPrefix(text, "🎱 ") && strings.Contains(text, "\n🪙 ") && strings.Contains(text, "\n🎲 ")
+		}},
+		{"reading", "reading: Will it rain?", "", func(text string) bool { return strings.HasPrefix(text, "🎱 A reading for: Will it rain?") }},
+		{"list", "list", "", func(text string) bool { return strings.Contains(text, c.Responses[0]) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := url.Values{"command": {"/ask8ball"}, "text": {tt.text}, "team_id": {"T1"}, "channel_id": {"C1"}, "user_id": {"U1"}}.Encode()
+			w := httptest.NewRecorder()
+			handleSlashCommand(w, signedRequest("/ask8ball", formContentType, body), testSecret, nil)
+			if ct := w.Header().Get("Content-Type"); w.Code != 200 || ct != "application/json" {
+				t.Fatalf("status %d with Content-Type %q, want 200 JSON", w.Code, ct)
+			}
+			var msg slack.WebhookMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+				t.Fatalf("decoding reply %q: %v", w.Body, err)
+			}
+			if !tt.check(msg.Text) {
+				t.Errorf("/ask8ball %s replied %q", tt.text, msg.Text)
+			}
+			if tt.responseType != "" && msg.ResponseType != tt.responseType {
+				t.Errorf("/ask8ball %s replied %s, want %s", tt.text, msg.ResponseType, tt.responseType)
+			}
+		})
+	}
+}
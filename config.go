@@ -0,0 +1,763 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds settings read from the environment at startup.
+type Config struct {
+	BotToken      string `json:"bot_token"`
+	SigningSecret string `json:"signing_secret"`
+
+	// ValidateToken checks at startup that BotToken works and has the
+	// scopes the bot needs.
+	ValidateToken bool `json:"validate_token"`
+
+	// SocketMode serves Slack over a Socket Mode connection authenticated
+	// with AppToken instead of public HTTP endpoints.
+	SocketMode bool   `json:"socket_mode"`
+	AppToken   string `json:"app_token"`
+
+	// SignatureTolerance is how far a request's timestamp may be from our
+	// clock, capped at maxSignatureTolerance.
+	SignatureTolerance time.Duration `json:"signature_tolerance"`
+
+	// WebhookToken, when set, enables /webhook/ask for callers presenting
+	// it as a bearer token.
+	WebhookToken string `json:"webhook_token"`
+
+	// Addr is the listen address derived from PORT, e.g. ":8080".
+	Addr string `json:"addr"`
+
+	// PathPrefix is prepended to every route, e.g. "/bots/8ball" when
+	// running behind a gateway. OpsPrefixExempt keeps ops endpoints such as
+	// /healthz at the root regardless.
+	PathPrefix      string `json:"path_prefix"`
+	OpsPrefixExempt bool   `json:"ops_prefix_exempt"`
+
+	// Aliases maps alternate slash command names, e.g. "/magic8", to the
+	// commands they run.
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	// AllowedChannels, when not empty, are the only channel IDs the bot
+	// answers in.
+	AllowedChannels map[string]struct{} `json:"allowed_channels,omitempty"`
+
+	// AdminUsers holds the Slack user IDs allowed to run admin commands.
+	AdminUsers map[string]struct{} `json:"admin_users"`
+
+	// Themes are the built-in themes after filtering, and Theme is the one
+	// used unless a channel picks another.
+	Themes map[string][]string `json:"themes"`
+	Theme  string              `json:"theme"`
+
+	// ThemeBlend, when set, mixes several themes by weight into the global
+	// list instead of using Theme.
+	ThemeBlend map[string]int `json:"theme_blend,omitempty"`
+
+	// Responses is the active answer list: the chosen theme or blend after
+	// filtering.
+	Responses []string `json:"responses"`
+
+	// StrictResponses makes duplicate texts within a response list a
+	// startup error instead of a warning.
+	StrictResponses bool `json:"strict_responses"`
+
+	// Overrides replaces Responses for specific enterprises or teams.
+	Overrides responseOverrides `json:"overrides"`
+
+	// PersistAddedResponses writes responses added with /8ball-add to
+	// RESPONSES_FILE, when the list came from there.
+	PersistAddedResponses bool `json:"persist_added_responses"`
+
+	// StrictWrites makes an unwritable AUDIT_FILE or persisted RESPONSES_FILE
+	// a startup error rather than turning the feature off.
+	StrictWrites bool `json:"strict_writes"`
+
+	// Provenance says where Responses and Overrides were loaded from.
+	Provenance responsesProvenance `json:"provenance"`
+
+	// FilterProfanity drops responses containing words from PROFANITY_WORDS
+	// at startup, as a safeguard for custom lists loaded from files.
+	FilterProfanity bool `json:"filter_profanity"`
+	// ProfanityWords are the words FILTER_PROFANITY drops responses for,
+	// from PROFANITY_WORDS or defaultProfanityWords. Unset without it.
+	ProfanityWords []string `json:"profanity_words,omitempty"`
+
+	// DisabledResponses are the texts DISABLED_RESPONSES keeps out.
+	DisabledResponses []string `json:"disabled_responses,omitempty"`
+
+	// LengthResponses are the --short and --long lists, by length, after
+	// DISABLED_RESPONSES and FILTER_PROFANITY.
+	LengthResponses map[string][]string `json:"length_responses"`
+
+	// OpenQuestion matches open-ended questions, which the 8-ball refuses
+	// to answer. It is built from OPEN_QUESTION_PATTERN if set, otherwise
+	// from the interrogatives in OPEN_QUESTION_WORDS.
+	OpenQuestion *regexp.Regexp `json:"open_question"`
+
+	// TrivialWords are single-word "questions" like "Right?" that get a
+	// playful brush-off instead of an answer.
+	TrivialWords map[string]struct{} `json:"trivial_words"`
+
+	// StrictQuestions refuses open-ended questions. Channels can override it
+	// with /8ball-strict.
+	StrictQuestions bool `json:"strict_questions"`
+
+	// RequireYesNoForm only answers questions starting with a yes/no
+	// auxiliary such as "Should" or "Is", hinting at the phrasing otherwise.
+	RequireYesNoForm bool `json:"require_yesno_form"`
+
+	// Force fixes the answer's category for questions containing trigger
+	// words from FORCE_YES_WORDS and FORCE_NO_WORDS.
+	Force forceConfig `json:"force"`
+
+	// Boost temporarily favours one answer within a time window.
+	Boost boostConfig `json:"boost"`
+
+	// LogSampleRate, when above 1, keeps only one in that many routine
+	// per-request log lines. Errors and warnings are always logged.
+	LogSampleRate int `json:"log_sample_rate"`
+
+	// LogLevel is the least severe level logged. It defaults to debug when
+	// DEBUG_LOG_BODY or DEBUG_TRACE asks for debug lines, and info otherwise.
+	LogLevel slog.Level `json:"log_level"`
+
+	// AnonymizeLogs hashes team, channel and user IDs in log lines and
+	// leaves out channel names. It also hashes user IDs in the audit file.
+	AnonymizeLogs bool `json:"anonymize_logs"`
+
+	// DebugTrace logs which rules decided each reply, and returns them from
+	// /webhook/ask.
+	DebugTrace bool `json:"debug_trace"`
+
+	// StrictContentType refuses slash commands that aren't form-encoded or
+	// JSON with 415. When off, other bodies are decoded as forms.
+	StrictContentType bool `json:"strict_content_type"`
+
+	// DebugLogBody logs every parsed slash command field, for diagnosing
+	// parsing problems. ANONYMIZE_LOGS still applies.
+	DebugLogBody bool `json:"debug_log_body"`
+
+	// EmojiPrefix replaces the 🎱 before a shared answer with an emoji
+	// suiting its category.
+	EmojiPrefix bool `json:"emoji_prefix"`
+
+	// WindowArt draws answers in a text-art 8-ball window.
+	WindowArt bool `json:"window_art"`
+
+	// MirrorCase shouts answers back at questions asked in capitals, and
+	// lowercases answers to all-lowercase questions.
+	MirrorCase bool `json:"mirror_case"`
+
+	// MrkdwnEmphasis shows yes answers in bold and no answers in italics.
+	// WINDOW_ART's code block would show the markers, so it takes precedence.
+	MrkdwnEmphasis bool `json:"mrkdwn_emphasis"`
+
+	// EchoMaxLen caps, in runes, the question echoed back with answers
+	// posted in channels. 0 means no limit.
+	EchoMaxLen int `json:"echo_max_len"`
+
+	// ImageReplies attaches one of CategoryImages, picked by the answer's
+	// category, to answers. Categories without images get text only.
+	ImageReplies   bool                `json:"image_replies"`
+	CategoryImages map[string][]string `json:"category_images,omitempty"`
+
+	// CategorySuffix is appended to answers by category, e.g. "🎉" for yes.
+	CategorySuffix map[string]string `json:"category_suffix,omitempty"`
+
+	// AuditFile, when set, is a JSONL file every answer given is appended to.
+	AuditFile string `json:"audit_file,omitempty"`
+
+	// ShowFooter adds a footer with the time to answers shared in-channel.
+	ShowFooter bool `json:"show_footer"`
+	// FooterTZ is the time zone footers use when Slack doesn't say the
+	// user's. It defaults to UTC.
+	FooterTZ string `json:"footer_tz"`
+
+	// Greeting answers an app mention that has no question in it.
+	Greeting string `json:"greeting"`
+
+	// AnswerCacheTTL, when positive, repeats a user's answer to the same
+	// question asked again within this window.
+	AnswerCacheTTL time.Duration `json:"answer_cache_ttl"`
+
+	// ConsensusWindow, when positive, gives everyone asking the same
+	// question in a channel within this window the same answer.
+	ConsensusWindow time.Duration `json:"consensus_window"`
+
+	// ChannelRecentAnswers, when positive, is how many of a channel's latest
+	// answers the picker avoids repeating.
+	ChannelRecentAnswers int `json:"channel_recent_answers"`
+
+	// ReaskRate is the probability (0-1) of answering with a "maybe"
+	// response regardless of the question, like a murky 8-ball window.
+	ReaskRate float64 `json:"reask_rate"`
+
+	// DeflectThirdParty answers questions about someone else, such as
+	// "Does Sam like pizza?", with "Ask Sam yourself." instead.
+	DeflectThirdParty bool `json:"deflect_third_party"`
+
+	// ShowReason follows each answer with a mock rationale suiting its
+	// category, such as "— the stars are unusually aligned."
+	ShowReason bool `json:"show_reason"`
+
+	// LuckyNumber adds a random lucky number from 1 to 99 under each
+	// answer, for fun. It has nothing to do with /ask8ball set-lucky.
+	LuckyNumber bool `json:"lucky_number"`
+
+	// Mood biases answers by the time of day: more noes in the morning,
+	// more yeses in the afternoon, by the server's local time.
+	Mood bool `json:"mood"`
+
+	// RandomSeed, when set, seeds the answer picker for a reproducible
+	// sequence of answers, e.g. for demos.
+	RandomSeed *int64 `json:"random_seed,omitempty"`
+
+	// SelectionMode is selectionRandom, selectionBag to cycle through every
+	// answer before repeating one, or selectionDecay to favour answers not
+	// given recently, with usage halving every DecayHalfLife.
+	SelectionMode string        `json:"selection_mode"`
+	DecayHalfLife time.Duration `json:"decay_half_life"`
+
+	// Modals opens a modal asking for the question when /ask8ball is used
+	// without one.
+	Modals bool `json:"modals"`
+
+	// BusinessHours, when set, is the weekly schedule outside which the bot
+	// declines to answer.
+	BusinessHours *businessHours `json:"business_hours,omitempty"`
+
+	// ChannelDailyLimit, when positive, caps how many questions are answered
+	// in each channel per UTC day.
+	ChannelDailyLimit int `json:"channel_daily_limit"`
+
+	// UserDailyLimit, when positive, caps how many questions each user gets
+	// answered per UTC day.
+	UserDailyLimit int `json:"user_daily_limit"`
+
+	// TeamRateLimit, when positive, caps how many questions are answered
+	// for each team per UTC day, however many of its users ask.
+	TeamRateLimit int `json:"team_rate_limit"`
+
+	// ShakeTwiceWindow, when positive, makes users ask the same question
+	// twice within the window before /ask8ball answers, as a gag.
+	ShakeTwiceWindow time.Duration `json:"shake_twice_window"`
+
+	// Milestones are ask counts, overall or per channel depending on
+	// MilestoneScope, whose answer carries a celebratory note.
+	Milestones     []int  `json:"milestones"`
+	MilestoneScope string `json:"milestone_scope"`
+
+	// AnswerDelay holds back slash command answers for suspense. It is
+	// capped at maxAnswerDelay.
+	AnswerDelay time.Duration `json:"answer_delay"`
+
+	// SelfDestructTTL, when positive, deletes answers posted in channels
+	// via the Web API (replies to mentions) after this long.
+	SelfDestructTTL time.Duration `json:"self_destruct_ttl"`
+
+	// ReadinessDelay keeps /ready failing for this long after startup.
+	ReadinessDelay time.Duration `json:"readiness_delay"`
+
+	// ShutdownTimeout bounds how long shutdown waits for in-flight requests
+	// before closing their connections.
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+
+	// UserStateTTL is how long per-user state is kept after a user was last
+	// seen; the store is pruned every UserPruneInterval. Lucky numbers are
+	// kept regardless. 0 keeps it all forever.
+	UserStateTTL      time.Duration `json:"user_state_ttl"`
+	UserPruneInterval time.Duration `json:"user_prune_interval"`
+}
+
+// cfg is the active configuration, set once by main before serving.
+var cfg Config
+
+// loadConfig reads and validates the configuration from the environment.
+// Slack credentials are checked separately by requireSlack, since local
+// subcommands don't need them.
+func loadConfig() (Config, error) {
+	c := Config{
+		BotToken:      os.Getenv("SLACK_BOT_TOKEN"),
+		SigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+		WebhookToken:  os.Getenv("WEBHOOK_TOKEN"),
+		AppToken:      os.Getenv("SLACK_APP_TOKEN"),
+	}
+	var err error
+	if c.ValidateToken, err = envBool("VALIDATE_TOKEN", false); err != nil {
+		return c, err
+	}
+	if c.SocketMode, err = envBool("SOCKET_MODE", false); err != nil {
+		return c, err
+	}
+	if c.SignatureTolerance, err = envDuration("SIGNATURE_TOLERANCE", 5*time.Minute); err != nil {
+		return c, err
+	}
+	if c.SignatureTolerance == 0 {
+		return c, errors.New("SIGNATURE_TOLERANCE must be positive")
+	}
+	if c.SignatureTolerance > maxSignatureTolerance {
+		logWarn("SIGNATURE_TOLERANCE %v exceeds %v, capping it", c.SignatureTolerance, maxSignatureTolerance)
+		c.SignatureTolerance = maxSignatureTolerance
+	}
+
+	if c.Aliases, err = parseAliases(envList("ALIASES")); err != nil {
+		return c, err
+	}
+	c.AllowedChannels = make(map[string]struct{})
+	for _, id := range envList("ALLOWED_CHANNELS") {
+		c.AllowedChannels[id] = struct{}{}
+	}
+	if c.AdminUsers, err = parseAdminUsers(os.Getenv("ADMIN_USERS")); err != nil {
+		return c, err
+	}
+
+	if c.LogSampleRate, err = envInt("LOG_SAMPLE_RATE", 1); err != nil {
+		return c, err
+	}
+	if c.LogSampleRate < 1 {
+		return c, fmt.Errorf("LOG_SAMPLE_RATE must be at least 1, got %d", c.LogSampleRate)
+	}
+	if c.AnonymizeLogs, err = envBool("ANONYMIZE_LOGS", false); err != nil {
+		return c, err
+	}
+	if c.DebugLogBody, err = envBool("DEBUG_LOG_BODY", false); err != nil {
+		return c, err
+	}
+	if c.DebugTrace, err = envBool("DEBUG_TRACE", false); err != nil {
+		return c, err
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if c.LogLevel, err = parseLogLevel(v); err != nil {
+			return c, err
+		}
+	} else if c.DebugLogBody || c.DebugTrace {
+		c.LogLevel = slog.LevelDebug
+	}
+	if c.StrictContentType, err = envBool("STRICT_CONTENT_TYPE", true); err != nil {
+		return c, err
+	}
+	if c.EmojiPrefix, err = envBool("EMOJI_PREFIX", false); err != nil {
+		return c, err
+	}
+	if c.WindowArt, err = envBool("WINDOW_ART", false); err != nil {
+		return c, err
+	}
+	if c.MirrorCase, err = envBool("MIRROR_CASE", false); err != nil {
+		return c, err
+	}
+	if c.MrkdwnEmphasis, err = envBool("MRKDWN_EMPHASIS", false); err != nil {
+		return c, err
+	}
+	if c.EchoMaxLen, err = envInt("ECHO_MAX_LEN", 140); err != nil {
+		return c, err
+	}
+	if c.EchoMaxLen < 0 || c.EchoMaxLen == 1 {
+		return c, fmt.Errorf("ECHO_MAX_LEN must be 0 for no limit or at least 2, got %d", c.EchoMaxLen)
+	}
+	if c.ImageReplies, err = envBool("IMAGE_REPLIES", false); err != nil {
+		return c, err
+	}
+	if c.CategoryImages, err = parseCategoryImages(envList("IMAGE_URLS")); err != nil {
+		return c, err
+	}
+	if c.CategorySuffix, err = parseCategorySuffix(envList("CATEGORY_SUFFIX")); err != nil {
+		return c, err
+	}
+	if c.ShowFooter, err = envBool("SHOW_FOOTER", false); err != nil {
+		return c, err
+	}
+	if c.FooterTZ = os.Getenv("FOOTER_TZ"); c.FooterTZ != "" {
+		if _, err := time.LoadLocation(c.FooterTZ); err != nil {
+			return c, fmt.Errorf("invalid FOOTER_TZ %q: %w", c.FooterTZ, err)
+		}
+	}
+	c.AuditFile = os.Getenv("AUDIT_FILE")
+	c.Greeting = os.Getenv("GREETING")
+	if c.Greeting == "" {
+		c.Greeting = defaultGreeting
+	}
+
+	// DISABLED_RESPONSES is comma-separated, so texts that themselves
+	// contain a comma can't be disabled this way.
+	disabled := envList("DISABLED_RESPONSES")
+	c.DisabledResponses = disabled
+	if c.Themes, err = filterThemes(builtinThemes, disabled); err != nil {
+		return c, err
+	}
+	c.Theme = strings.ToLower(os.Getenv("THEME"))
+	if c.Theme == "" {
+		c.Theme = defaultTheme
+	}
+	var ok bool
+	if c.Responses, ok = c.Themes[c.Theme]; !ok {
+		return c, fmt.Errorf("unknown THEME %q", c.Theme)
+	}
+	c.Provenance = responsesProvenance{Source: provenanceBuiltin, Theme: c.Theme}
+	if blend := envList("THEME_BLEND"); len(blend) > 0 {
+		if c.ThemeBlend, err = parseBlend(blend); err != nil {
+			return c, err
+		}
+		if c.Responses, err = buildBlendedPool(c.Themes, c.ThemeBlend); err != nil {
+			return c, err
+		}
+		c.Provenance = responsesProvenance{Source: provenanceBlend}
+	}
+	if path := os.Getenv("RESPONSE_OVERRIDES_FILE"); path != "" {
+		if c.Overrides, err = loadOverrides(path); err != nil {
+			return c, err
+		}
+		c.Provenance.OverridesPath = path
+	}
+	if path := os.Getenv("RESPONSES_FILE"); path != "" {
+		fallback, err := envBool("RESPONSES_EMPTY_FALLBACK", false)
+		if err != nil {
+			return c, err
+		}
+		entries, err := loadResponsesFile(path)
+		switch {
+		case err != nil && fallback:
+			logWarn("%v; using the built-in responses instead", err)
+		case err != nil:
+			return c, err
+		default:
+			if c.Overrides.Meta == nil {
+				c.Overrides.Meta = make(map[string]responseEntry)
+			}
+			if c.Responses, err = applyBlacklist(entryTexts(entries, c.Overrides.Meta), disabled); err != nil {
+				return c, err
+			}
+			c.Provenance.Source, c.Provenance.Theme, c.Provenance.Path = provenanceFile, "", path
+		}
+	}
+	if c.PersistAddedResponses, err = envBool("PERSIST_ADDED_RESPONSES", false); err != nil {
+		return c, err
+	}
+	if c.StrictWrites, err = envBool("STRICT_WRITES", false); err != nil {
+		return c, err
+	}
+	if c.FilterProfanity, err = envBool("FILTER_PROFANITY", false); err != nil {
+		return c, err
+	}
+	var profanity []string
+	if c.FilterProfanity {
+		profanity = envList("PROFANITY_WORDS")
+		if len(profanity) == 0 {
+			profanity = defaultProfanityWords
+		}
+		if err = filterOverridesProfanity(c.Overrides, profanity); err != nil {
+			return c, err
+		}
+		if c.Responses = filterProfanity(c.Responses, profanity); len(c.Responses) == 0 {
+			return c, errors.New("FILTER_PROFANITY would leave no responses")
+		}
+		c.ProfanityWords = profanity
+	}
+	c.LengthResponses = map[string][]string{
+		lengthShort: filterLengthList(shortResponses, disabled, profanity),
+		lengthLong:  filterLengthList(longResponses, disabled, profanity),
+	}
+	c.Provenance.Count = len(c.Responses)
+	c.Provenance.OverrideEnterprises = len(c.Overrides.Enterprises)
+	c.Provenance.OverrideTeams = len(c.Overrides.Teams)
+	if c.StrictResponses, err = envBool("STRICT_RESPONSES", false); err != nil {
+		return c, err
+	}
+	if err = checkDuplicates(c); err != nil {
+		return c, err
+	}
+	if c.OpenQuestion, err = compileOpenQuestion(os.Getenv("OPEN_QUESTION_PATTERN"), envList("OPEN_QUESTION_WORDS")); err != nil {
+		return c, err
+	}
+	trivial := envList("TRIVIAL_WORDS")
+	if len(trivial) == 0 {
+		trivial = defaultTrivialWords
+	}
+	c.TrivialWords = make(map[string]struct{}, len(trivial))
+	for _, w := range trivial {
+		c.TrivialWords[strings.ToLower(w)] = struct{}{}
+	}
+	if c.StrictQuestions, err = envBool("STRICT_QUESTIONS", true); err != nil {
+		return c, err
+	}
+	if c.RequireYesNoForm, err = envBool("REQUIRE_YESNO_FORM", false); err != nil {
+		return c, err
+	}
+	c.Force = forceConfig{
+		YesWords: envList("FORCE_YES_WORDS"),
+		NoWords:  envList("FORCE_NO_WORDS"),
+	}
+	if c.PathPrefix, err = normalizePathPrefix(os.Getenv("PATH_PREFIX")); err != nil {
+		return c, err
+	}
+	if c.OpsPrefixExempt, err = envBool("PATH_PREFIX_EXEMPT_OPS", false); err != nil {
+		return c, err
+	}
+	if c.Boost, err = loadBoost(); err != nil {
+		return c, err
+	}
+	if c.AnswerCacheTTL, err = envDuration("ANSWER_CACHE_TTL", 0); err != nil {
+		return c, err
+	}
+	if c.ConsensusWindow, err = envDuration("CONSENSUS_WINDOW", 0); err != nil {
+		return c, err
+	}
+	if c.ChannelRecentAnswers, err = envInt("CHANNEL_RECENT_ANSWERS", 0); err != nil {
+		return c, err
+	}
+	if c.Addr, err = resolvePort(os.Getenv("PORT")); err != nil {
+		return c, err
+	}
+	if c.ReaskRate, err = envFloat("REASK_RATE", 0); err != nil {
+		return c, err
+	}
+	if c.ReaskRate < 0 || c.ReaskRate > 1 {
+		return c, fmt.Errorf("REASK_RATE must be between 0 and 1, got %v", c.ReaskRate)
+	}
+	if c.DeflectThirdParty, err = envBool("DEFLECT_THIRD_PARTY", false); err != nil {
+		return c, err
+	}
+	if c.ShowReason, err = envBool("SHOW_REASON", false); err != nil {
+		return c, err
+	}
+	if c.LuckyNumber, err = envBool("LUCKY_NUMBER", false); err != nil {
+		return c, err
+	}
+	if c.Mood, err = envBool("MOOD", false); err != nil {
+		return c, err
+	}
+	if v := os.Getenv("RANDOM_SEED"); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c, fmt.Errorf("invalid RANDOM_SEED %q: %w", v, err)
+		}
+		c.RandomSeed = &seed
+	}
+	switch c.SelectionMode = strings.ToLower(os.Getenv("SELECTION_MODE")); c.SelectionMode {
+	case "":
+		c.SelectionMode = selectionRandom
+	case selectionRandom, selectionBag, selectionDecay:
+	default:
+		return c, fmt.Errorf("invalid SELECTION_MODE %q: must be %s, %s or %s", c.SelectionMode, selectionRandom, selectionBag, selectionDecay)
+	}
+	if c.Modals, err = envBool("MODALS", false); err != nil {
+		return c, err
+	}
+	if v := os.Getenv("BUSINESS_HOURS"); v != "" {
+		if c.BusinessHours, err = parseBusinessHours(v); err != nil {
+			return c, err
+		}
+	}
+	if c.ChannelDailyLimit, err = envInt("CHANNEL_DAILY_LIMIT", 0); err != nil {
+		return c, err
+	}
+	if c.UserDailyLimit, err = envInt("USER_DAILY_LIMIT", 0); err != nil {
+		return c, err
+	}
+	if c.TeamRateLimit, err = envInt("TEAM_RATE_LIMIT", 0); err != nil {
+		return c, err
+	}
+	if c.ShakeTwiceWindow, err = envDuration("SHAKE_TWICE_WINDOW", 0); err != nil {
+		return c, err
+	}
+	if c.Milestones, err = parseMilestones(envList("MILESTONES")); err != nil {
+		return c, err
+	}
+	switch c.MilestoneScope = strings.ToLower(os.Getenv("MILESTONE_SCOPE")); c.MilestoneScope {
+	case "":
+		c.MilestoneScope = milestoneGlobal
+	case milestoneGlobal, milestoneChannel:
+	default:
+		return c, fmt.Errorf("invalid MILESTONE_SCOPE %q: must be %s or %s", c.MilestoneScope, milestoneGlobal, milestoneChannel)
+	}
+	if c.DecayHalfLife, err = envDuration("DECAY_HALF_LIFE", time.Hour); err != nil {
+		return c, err
+	}
+	if c.DecayHalfLife == 0 {
+		return c, errors.New("DECAY_HALF_LIFE must be positive")
+	}
+	if c.AnswerDelay, err = envDuration("ANSWER_DELAY", 0); err != nil {
+		return c, err
+	}
+	if c.AnswerDelay > maxAnswerDelay {
+		logWarn("ANSWER_DELAY %v exceeds %v, capping it", c.AnswerDelay, maxAnswerDelay)
+		c.AnswerDelay = maxAnswerDelay
+	}
+	if c.SelfDestructTTL, err = envDuration("SELF_DESTRUCT_TTL", 0); err != nil {
+		return c, err
+	}
+	if c.ReadinessDelay, err = envDuration("READINESS_DELAY", 0); err != nil {
+		return c, err
+	}
+	if c.ShutdownTimeout, err = envDuration("SHUTDOWN_TIMEOUT", 10*time.Second); err != nil {
+		return c, err
+	}
+	if c.UserStateTTL, err = envDuration("USER_STATE_TTL", 24*time.Hour); err != nil {
+		return c, err
+	}
+	if c.UserPruneInterval, err = envDuration("USER_PRUNE_INTERVAL", 10*time.Minute); err != nil {
+		return c, err
+	}
+	if c.UserPruneInterval == 0 {
+		return c, errors.New("USER_PRUNE_INTERVAL must be positive")
+	}
+
+	return c, nil
+}
+
+// redacted replaces secrets in output such as /8ball-config.
+const redacted = "[redacted]"
+
+// Redacted returns a copy of c with its secrets masked, safe to show to
+// admins or write to logs.
+func (c Config) Redacted() Config {
+	if c.BotToken != "" {
+		c.BotToken = redacted
+	}
+	if c.SigningSecret != "" {
+		c.SigningSecret = redacted
+	}
+	if c.WebhookToken != "" {
+		c.WebhookToken = redacted
+	}
+	if c.AppToken != "" {
+		c.AppToken = redacted
+	}
+	return c
+}
+
+// requireSlack checks the settings needed to serve Slack requests.
+func (c Config) requireSlack() error {
+	if c.SocketMode {
+		if c.BotToken == "" || !strings.HasPrefix(c.AppToken, "xapp-") {
+			return errors.New("SOCKET_MODE needs SLACK_BOT_TOKEN and an app-level SLACK_APP_TOKEN (xapp-...)")
+		}
+		return nil
+	}
+	if c.BotToken == "" || c.SigningSecret == "" {
+		return errors.New("SLACK_BOT_TOKEN and SLACK_SIGNING_SECRET must be set")
+	}
+	return nil
+}
+
+// envList splits a comma-separated environment variable, dropping blanks.
+func envList(name string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// envBool parses a boolean environment variable, returning def when unset.
+func envBool(name string, def bool) (bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+	return b, nil
+}
+
+// envDuration parses a duration environment variable such as "10s",
+// returning def when unset.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must not be negative", name, v)
+	}
+	return d, nil
+}
+
+// envInt parses a non-negative integer environment variable, returning def
+// when unset.
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must not be negative", name, v)
+	}
+	return n, nil
+}
+
+// envFloat parses a float environment variable, returning def when unset.
+func envFloat(name string, def float64) (float64, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+	return f, nil
+}
+
+// resolvePort turns the PORT setting into a listen address, defaulting to
+// 8080. A leading colon (PORT=:9090) is tolerated rather than producing an
+// address like "::9090".
+func resolvePort(raw string) (string, error) {
+	port := strings.TrimPrefix(strings.TrimSpace(raw), ":")
+	if port == "" {
+		port = "8080"
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid PORT %q: must be a number between 1 and 65535", raw)
+	}
+	return ":" + strconv.Itoa(n), nil
+}
+
+// userIDPattern matches Slack user IDs, which start with U, or W on
+// Enterprise Grid.
+var userIDPattern = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+// parseAdminUsers parses the comma-separated ADMIN_USERS list into a set,
+// trimming whitespace and upper-casing IDs. A malformed entry is an error
+// rather than an admin who silently can't run anything.
+func parseAdminUsers(s string) (map[string]struct{}, error) {
+	admins := make(map[string]struct{})
+	for _, id := range strings.Split(s, ",") {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		if !userIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid ADMIN_USERS entry %q: want a Slack user ID like U012ABC", id)
+		}
+		admins[id] = struct{}{}
+	}
+	return admins, nil
+}
+
+// isAdmin reports whether userID may run admin commands.
+func (c Config) isAdmin(userID string) bool {
+	_, ok := c.AdminUsers[userID]
+	return ok
+}
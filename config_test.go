@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestResolvePort(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{":9090", ":9090"},
+		{"9090", ":9090"},
+		{"", ":8080"},
+		{" 9090 ", ":9090"},
+		{":", ":8080"},
+		{"09090", ":9090"},
+	}
+	for _, tt := range tests {
+		got, err := resolvePort(tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("resolvePort(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestResolvePortRejects(t *testing.T) {
+	for _, raw := range []string{"::9090", "0", "65536", "-1", "http", "localhost:9090"} {
+		if got, err := resolvePort(raw); err == nil {
+			t.Errorf("resolvePort(%q) = %q, want an error", raw, got)
+		}
+	}
+}
+
+func TestParseAdminUsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"valid list", "U012ABC,W034DEF", []string{"U012ABC", "W034DEF"}, false},
+		{"whitespace and blanks", " U012ABC , ,\tW034DEF ,", []string{"U012ABC", "W034DEF"}, false},
+		{"lower case", "u012abc", []string{"U012ABC"}, false},
+		{"repeated", "U012ABC,U012ABC", []string{"U012ABC"}, false},
+		{"channel ID", "U012ABC,C012ABC", nil, true},
+		{"name", "@sam", nil, true},
+		{"bare prefix", "U", nil, true},
+		{"inner space", "U012 ABC", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAdminUsers(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAdminUsers(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("parseAdminUsers(%q) = %v, want %q", tt.raw, got, tt.want)
+			}
+			for _, id := range tt.want {
+				if _, ok := got[id]; !ok {
+					t.Errorf("parseAdminUsers(%q) lacks %s", tt.raw, id)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminUsersGateAdminCommands(t *testing.T) {
+	c := withConfig(t, map[string]string{"ADMIN_USERS": " uadmin1 "})
+	if !c.isAdmin("UADMIN1") || c.isAdmin("U2") {
+		t.Errorf("AdminUsers = %v, want only UADMIN1", c.AdminUsers)
+	}
+	if reply := commands["/8ball-config"].run(slack.SlashCommand{UserID: "U2"}); !strings.Contains(reply.Text, "only admins") {
+		t.Errorf("non-admin got %q", reply.Text)
+	}
+
+	t.Setenv("ADMIN_USERS", "UADMIN1,sam")
+	if _, err := loadConfig(); err == nil {
+		t.Error("a malformed ADMIN_USERS entry loaded")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// selectionDecay favours answers that haven't been given much lately.
+const selectionDecay = "decay"
+
+// decayedCount is a usage count and when it was last brought up to date.
+type decayedCount struct {
+	value float64
+	at    time.Time
+}
+
+// decayCounter tracks how often each answer has been given across the whole
+// workspace, with counts halving every halfLife so old usage stops counting.
+type decayCounter struct {
+	halfLife time.Duration
+
+	mu     sync.Mutex
+	counts map[string]decayedCount
+}
+
+func newDecayCounter(halfLife time.Duration) *decayCounter {
+	return &decayCounter{halfLife: halfLife, counts: make(map[string]decayedCount)}
+}
+
+// answerUsage is set at startup when SELECTION_MODE=decay.
+var answerUsage *decayCounter
+
+// value returns c decayed to now. Callers must hold d.mu.
+func (d *decayCounter) value(c decayedCount, now time.Time) float64 {
+	elapsed := now.Sub(c.at)
+	if elapsed <= 0 {
+		return c.value
+	}
+	return c.value * math.Exp2(-float64(elapsed)/float64(d.halfLife))
+}
+
+// weights returns a selection weight for each of resps, 1/(1+usage), so an
+// answer that has just been given a lot becomes correspondingly less likely.
+func (d *decayCounter) weights(resps []string, now time.Time) []float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w := make([]float64, len(resps))
+	for i, text := range resps {
+		w[i] = 1 / (1 + d.value(d.counts[text], now))
+	}
+	return w
+}
+
+// use records that text was given at now.
+func (d *decayCounter) use(text string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.counts[text] = decayedCount{value: d.value(d.counts[text], now) + 1, at: now}
+}
+
+// purge forgets all usage, returning how many answers had some.
+func (c *decayCounter) purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.counts)
+	clear(c.counts)
+	return n
+}
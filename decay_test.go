@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDecayCounterWeights(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	d := newDecayCounter(time.Hour)
+	resps := []string{"Yes.", "No."}
+	for range 9 {
+		d.use("Yes.", start)
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  float64 // weight of "Yes."
+	}{
+		{"just used", 0, 1.0 / 10},
+		{"one half-life", time.Hour, 1 / (1 + 4.5)},
+		{"two half-lives", 2 * time.Hour, 1 / (1 + 2.25)},
+		{"long forgotten", 48 * time.Hour, 1},
+	}
+	for _, tt := range tests {
+		w := d.weights(resps, start.Add(tt.after))
+		if math.Abs(w[0]-tt.want) > 1e-6 {
+			t.Errorf("%s: weight = %v, want %v", tt.name, w[0], tt.want)
+		}
+		if w[1] != 1 {
+			t.Errorf("%s: unused answer's weight = %v, want 1", tt.name, w[1])
+		}
+	}
+
+	// A use after some decay adds to what's left.
+	d.use("Yes.", start.Add(time.Hour))
+	if w := d.weights(resps, start.Add(time.Hour)); math.Abs(w[0]-1/(1+5.5)) > 1e-6 {
+		t.Errorf("weight after a decayed use = %v, want %v", w[0], 1/(1+5.5))
+	}
+	if n := d.purge(); n != 1 {
+		t.Errorf("purge = %d, want 1", n)
+	}
+}
+
+// TestDecayDisfavoursOverusedAnswer checks, statistically with a fixed
+// seed, that an answer given a lot lately is picked well below uniform.
+func TestDecayDisfavoursOverusedAnswer(t *testing.T) {
+	c := withConfig(t, map[string]string{"SELECTION_MODE": "decay"})
+	setClock(t, time.Unix(1700000000, 0))
+	answerUsage = newDecayCounter(c.DecayHalfLife)
+	t.Cleanup(func() { answerUsage = nil })
+
+	overused := c.Responses[0]
+	for range 50 {
+		answerUsage.use(overused, clock())
+	}
+	weights := answerUsage.weights(c.Responses, clock())
+
+	r := rand.New(rand.NewSource(1))
+	const draws = 20000
+	picked := 0
+	for range draws {
+		if pickWeighted(r, len(c.Responses), weights) == 0 {
+			picked++
+		}
+	}
+	uniform := 1 / float64(len(c.Responses))
+	if share := float64(picked) / draws; share > uniform/4 {
+		t.Errorf("overused answer picked %.4f of the time, want well below uniform %.4f", share, uniform)
+	}
+
+	// Through Answer, where every pick counts as use, it stays rarer
+	// than the rest until the others catch up.
+	got := 0
+	for range 100 {
+		resp, err := Answer("Will it rain?", c.Responses, true, r)
+		if err != nil {
+			t.Fatalf("Answer: %v", err)
+		}
+		if resp.Text == overused {
+			got++
+		}
+	}
+	if want := 100 * uniform; float64(got) >= want {
+		t.Errorf("overused answer given %d times in 100, want fewer than uniform %v", got, want)
+	}
+}
+
+// TestDecayCounterConcurrent runs with -race.
+func TestDecayCounterConcurrent(t *testing.T) {
+	d := newDecayCounter(time.Hour)
+	resps := []string{"Yes.", "No.", "Maybe."}
+	now := time.Unix(1700000000, 0)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				d.use(resps[i%len(resps)], now)
+				d.weights(resps, now)
+			}
+		}()
+	}
+	wg.Wait()
+	var total float64
+	for _, w := range d.weights(resps, now) {
+		total += 1/w - 1
+	}
+	if math.Abs(total-800) > 1e-6 {
+		t.Errorf("counted %v uses, want 800", total)
+	}
+}
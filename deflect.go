@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// thirdPartyAuxiliaries are the yes/no forms that put the subject right
+// after them, as in "Does Sam like pizza?".
+var thirdPartyAuxiliaries = map[string]struct{}{
+	"can": {}, "could": {}, "did": {}, "does": {}, "has": {}, "is": {},
+	"may": {}, "might": {}, "must": {}, "should": {}, "was": {}, "will": {},
+	"would": {},
+}
+
+// notThirdParties are capitalised words that are rarely a person's name,
+// so "Will It rain?" or "Is Monday a holiday?" still get an answer.
+var notThirdParties = map[string]struct{}{
+	"i": {}, "you": {}, "we": {}, "they": {}, "he": {}, "she": {}, "it": {},
+	"this": {}, "that": {}, "these": {}, "those": {}, "there": {}, "my": {},
+	"your": {}, "our": {}, "their": {}, "his": {}, "her": {}, "its": {},
+	"the": {}, "a": {}, "an": {}, "any": {}, "anyone": {}, "everyone": {},
+	"someone": {}, "today": {}, "tomorrow": {}, "tonight": {},
+	"monday": {}, "tuesday": {}, "wednesday": {}, "thursday": {},
+	"friday": {}, "saturday": {}, "sunday": {},
+	"january": {}, "february": {}, "march": {}, "april": {}, "june": {},
+	"july": {}, "august": {}, "september": {}, "october": {},
+	"november": {}, "december": {},
+}
+
+// thirdPartySubject returns who text asks about when its subject is
+// someone else: a mention or a capitalised name straight after a yes/no
+// form. It is deliberately conservative, passing on anything that might be
+// the asker or a thing, such as possessives and acronyms.
+func thirdPartySubject(text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		return "", false
+	}
+	if _, ok := thirdPartyAuxiliaries[strings.ToLower(fields[0])]; !ok {
+		return "", false
+	}
+	subject := fields[1]
+	if mentionPattern.FindString(subject) == subject {
+		return subject, true
+	}
+	if _, ok := notThirdParties[strings.ToLower(subject)]; ok {
+		return "", false
+	}
+	first, size := utf8.DecodeRuneInString(subject)
+	if !unicode.IsUpper(first) || size == len(subject) {
+		return "", false
+	}
+	for _, r := range subject[size:] {
+		if !unicode.IsLower(r) {
+			return "", false
+		}
+	}
+	return subject, true
+}
+
+// deflection is the DEFLECT_THIRD_PARTY reply to a question about name.
+func deflection(name string) string {
+	return fmt.Sprintf("🎱 Ask %s yourself.", name)
+}
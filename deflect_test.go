@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestThirdPartySubject(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // "" when the question isn't about someone else
+	}{
+		{"Does Sam like pizza?", "Sam"},
+		{"will Zoë win the raffle?", "Zoë"},
+		{"Does <@U123> like pizza?", "<@U123>"},
+		{"Is <@W42|sam> coming today?", "<@W42|sam>"},
+		{"Should I order pizza?", ""},
+		{"Will you marry me?", ""},
+		{"Will It rain?", ""},
+		{"Is Monday a holiday?", ""},
+		{"Is NASA launching today?", ""},
+		{"Does Sam's team win?", ""},
+		{"Does sam like pizza?", ""},
+		{"Sam likes pizza, right?", ""},
+		{"Is Sam?", ""},
+		{"Why does Sam like pizza?", ""},
+	}
+	for _, tt := range tests {
+		got, ok := thirdPartySubject(tt.text)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("thirdPartySubject(%q) = %q, %v; want %q", tt.text, got, ok, tt.want)
+		}
+	}
+}
+
+func TestAskDeflectsThirdParty(t *testing.T) {
+	tests := []struct {
+		name     string
+		deflect  string
+		question string
+		want     string // "" for an answer
+	}{
+		{"third party", "true", "Does Sam like pizza?", "🎱 Ask Sam yourself."},
+		{"self", "true", "Should I eat pizza?", ""},
+		{"mention", "true", "Does <@U123> like pizza?", "🎱 Ask <@U123> yourself."},
+		{"disabled", "false", "Does Sam like pizza?", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, map[string]string{"DEFLECT_THIRD_PARTY": tt.deflect})
+			resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: tt.question})
+			if tt.want == "" {
+				if !resp.answered() {
+					t.Errorf("ask %q = %q, want an answer", tt.question, resp.Text)
+				}
+				return
+			}
+			if resp.answered() || resp.Text != tt.want {
+				t.Errorf("ask %q = %q (index %d), want the unanswered %q", tt.question, resp.Text, resp.Index, tt.want)
+			}
+		})
+	}
+}
+
+func TestAskCommandDeflectsMention(t *testing.T) {
+	withConfig(t, map[string]string{"DEFLECT_THIRD_PARTY": "true"})
+	reply := askCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", UserID: "U1", Text: "Does <@U123|sam> like pizza?"})
+	if !strings.Contains(reply.Text, "Ask <@U123|sam> yourself.") {
+		t.Errorf("reply = %q, want the mention deflected", reply.Text)
+	}
+}
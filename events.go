@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// defaultGreeting answers a mention that doesn't contain a question.
+const defaultGreeting = "🎱 Hi! Ask me a yes-or-no question, like \"@8ball Will it rain tomorrow?\""
+
+// greetingWords are bare salutations that shouldn't get a random answer.
+var greetingWords = map[string]struct{}{
+	"hi": {}, "hello": {}, "hey": {}, "hiya": {}, "howdy": {}, "yo": {},
+	"sup": {}, "morning": {}, "hola": {},
+}
+
+// isGreetingOnly reports whether a mention's text, with mentions removed, is
+// empty or just a greeting such as "hi" or "hello!".
+func isGreetingOnly(text string) bool {
+	text = normalizeText(stripMentions(text))
+	if text == "" {
+		return true
+	}
+	if strings.HasSuffix(text, "?") {
+		return false
+	}
+	word := strings.ToLower(strings.TrimRight(text, "!.,~ "))
+	_, ok := greetingWords[word]
+	return ok
+}
+
+// handleEvents processes Events API callbacks, answering app mentions in
+// thread. Compressed payloads are inflated by verifyRequest, so the challenge
+// and events are read from the same bytes the signature covered.
+func handleEvents(w http.ResponseWriter, r *http.Request, signingSecret string, client *slack.Client) {
+	body, ok := verifyRequest(w, r, signingSecret)
+	if !ok {
+		return
+	}
+
+	event, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		logError("Error parsing event: %v", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case slackevents.URLVerification:
+		challenge, ok := event.Data.(*slackevents.EventsAPIURLVerificationEvent)
+		if !ok {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		// Slack expects the challenge echoed back byte for byte.
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(challenge.Challenge))
+		return
+	case slackevents.CallbackEvent:
+		if mention, ok := event.InnerEvent.Data.(*slackevents.AppMentionEvent); ok && mention.BotID == "" {
+			// Reply after acknowledging so Slack doesn't retry the event.
+			go replyToMention(client, event.TeamID, mention)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// replyToMention answers an app mention in its thread.
+func replyToMention(client *slack.Client, teamID string, mention *slackevents.AppMentionEvent) {
+	threadTS := mention.ThreadTimeStamp
+	if threadTS == "" {
+		threadTS = mention.TimeStamp
+	}
+
+	if !channelAllowed(mention.Channel, cfg.AllowedChannels) {
+		if err := postEphemeral(client, mention.Channel, mention.User, slack.MsgOptionText(replyChannelNotAllowed, false)); err != nil {
+			logError("Error replying to mention: %v", err)
+		}
+		return
+	}
+
+	if refusal := askRefusal(teamID, mention.Channel, mention.User); refusal != "" {
+		if err := postEphemeral(client, mention.Channel, mention.User, slack.MsgOptionText(refusal, false)); err != nil {
+			logError("Error replying to mention: %v", err)
+		}
+		return
+	}
+
+	raw, flags, err := parseFlags(mention.Text)
+	broadcast := flags[flagBroadcast]
+	var text string
+	switch {
+	case err != nil:
+		text = flagHelp(err)
+	case isGreetingOnly(raw):
+		text = cfg.Greeting
+	default:
+		question := normalizeText(stripMentions(raw))
+		session, q, ok := sessionRequest(question)
+		if ok {
+			question = q
+		}
+		resp := ask(askRequest{
+			TeamID:    teamID,
+			ChannelID: mention.Channel,
+			UserID:    mention.User,
+			Question:  question,
+			Length:    lengthFlag(flags),
+			Session:   session,
+		})
+		log.Printf("Mention Replying with: %s (index %d)", resp.Text, resp.Index)
+		text = threadReplyText(raw, windowText(question, resp))
+	}
+
+	if flags[flagPrivate] {
+		// Only the asker sees the answer, so there is nothing to self-destruct.
+		if err := postEphemeral(client, mention.Channel, mention.User, slack.MsgOptionText(text, false), slack.MsgOptionTS(threadTS)); err != nil {
+			logError("Error replying to mention: %v", err)
+		}
+		return
+	}
+	ts, err := postMessage(client, mention.Channel, threadReplyOptions(text, threadTS, broadcast)...)
+	if err != nil {
+		logError("Error replying to mention: %v", err)
+		return
+	}
+	if selfDestructs != nil {
+		selfDestructs.schedule(client, mention.Channel, ts)
+	}
+}
+
+// threadReplyOptions builds the message options for a reply in threadTS,
+// also broadcasting it to the channel when asked.
+func threadReplyOptions(text, threadTS string, broadcast bool) []slack.MsgOption {
+	options := []slack.MsgOption{slack.MsgOptionText(text, false), slack.MsgOptionTS(threadTS)}
+	if broadcast {
+		options = append(options, slack.MsgOptionBroadcast())
+	}
+	return options
+}
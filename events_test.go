@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestIsGreetingOnly(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"<@U0BOT>", true},
+		{"  <@U0BOT>  ", true},
+		{"<@U0BOT> hi", true},
+		{"<@U0BOT> Hello!", true},
+		{"hey <@U0BOT>", true},
+		{"<@U0BOT> Will it rain?", false},
+		{"<@U0BOT> hi?", false},
+		{"<@U0BOT> hi there", false},
+		{"<@U0BOT> deploy now", false},
+	}
+	for _, tt := range tests {
+		if got := isGreetingOnly(tt.text); got != tt.want {
+			t.Errorf("isGreetingOnly(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+// postedText returns the text of a chat.postMessage or chat.postEphemeral
+// call captured by fakeSlack.
+func postedText(t *testing.T, call outboundRequest) string {
+	t.Helper()
+	form, err := url.ParseQuery(call.Body)
+	if err != nil {
+		t.Fatalf("parsing %s call: %v", call.URL, err)
+	}
+	return form.Get("text")
+}
+
+func TestReplyToMentionGreeting(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		text    string
+		want    string
+		notWant string
+	}{
+		{"bare mention", nil, "<@U0BOT>", defaultGreeting, ""},
+		{"greeting", map[string]string{"GREETING": "Ask away!"}, "<@U0BOT> hi", "Ask away!", ""},
+		{"question", map[string]string{"GREETING": "Ask away!"}, "<@U0BOT> Will it rain?", "> Will it rain?", "Ask away!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			client, calls := fakeSlack(t)
+			replyToMention(client, "T1", &slackevents.AppMentionEvent{User: "U1", Channel: "C1", Text: tt.text, TimeStamp: "1700000000.000100"})
+
+			call := nextOutbound(t, calls)
+			if call.URL != "chat.postMessage" {
+				t.Fatalf("called %s, want chat.postMessage", call.URL)
+			}
+			text := postedText(t, call)
+			if !strings.Contains(text, tt.want) || (tt.notWant != "" && strings.Contains(text, tt.notWant)) {
+				t.Errorf("replied %q, want %q", text, tt.want)
+			}
+		})
+	}
+}
+
+// gzipped compresses s.
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// eventRequest is a signed Events API request carrying payload, compressed
+// with encoding if it is "gzip". Slack signs the uncompressed payload.
+func eventRequest(t *testing.T, payload, encoding string) *http.Request {
+	t.Helper()
+	r := signedRequest("/events", "application/json", payload)
+	switch encoding {
+	case "":
+	case "gzip":
+		r.Body = io.NopCloser(bytes.NewReader(gzipped(t, payload)))
+		r.Header.Set("Content-Encoding", "gzip")
+	default:
+		r.Header.Set("Content-Encoding", encoding)
+	}
+	return r
+}
+
+func TestHandleEventsChallenge(t *testing.T) {
+	withConfig(t, nil)
+	const challenge = `3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P`
+	payload := `{"token":"x","challenge":"` + challenge + `","type":"url_verification"}`
+	tests := []struct {
+		name       string
+		encoding   string
+		wantStatus int
+		wantBody   string
+	}{
+		{"plain", "", http.StatusOK, challenge},
+		{"gzip", "gzip", http.StatusOK, challenge},
+		{"unsupported encoding", "br", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleEvents(w, eventRequest(t, payload, tt.encoding), testSecret, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want the challenge echoed exactly", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleEventsCorruptGzip(t *testing.T) {
+	withConfig(t, nil)
+	r := signedRequest("/events", "application/json", "not gzip")
+	r.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handleEvents(w, r, testSecret, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestHandleEventsMention(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  string
+		botID     string
+		wantReply bool
+	}{
+		{"plain", "", "", true},
+		{"gzip", "gzip", "", true},
+		{"from a bot", "", "B1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, nil)
+			client, calls := fakeSlack(t)
+			payload := `{"type":"event_callback","team_id":"T1","event":{"type":"app_mention","user":"U1","channel":"C1","ts":"1700000000.000100","bot_id":"` + tt.botID + `","text":"<@U0BOT> Will it rain?"}}`
+			w := httptest.NewRecorder()
+			handleEvents(w, eventRequest(t, payload, tt.encoding), testSecret, client)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", w.Code)
+			}
+			if !tt.wantReply {
+				select {
+				case call := <-calls:
+					t.Errorf("answered a bot's mention with %s", call.URL)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			call := nextOutbound(t, calls)
+			if text := postedText(t, call); call.URL != "chat.postMessage" || !strings.Contains(text, "> Will it rain?") {
+				t.Errorf("%s posted %q", call.URL, text)
+			}
+		})
+	}
+}
+
+func TestReplyToMentionBroadcast(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"<@U0BOT> Will it rain? --broadcast", true},
+		{"<@U0BOT> --broadcast Will it rain?", true},
+		{"<@U0BOT> Will it rain?", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			withConfig(t, nil)
+			client, calls := fakeSlack(t)
+			replyToMention(client, "T1", &slackevents.AppMentionEvent{User: "U1", Channel: "C1", Text: tt.text, TimeStamp: "1700000000.000100"})
+
+			call := nextOutbound(t, calls)
+			if call.URL != "chat.postMessage" {
+				t.Fatalf("called %s, want chat.postMessage", call.URL)
+			}
+			form, _ := url.ParseQuery(call.Body)
+			if got := form.Get("reply_broadcast") == "true"; got != tt.want {
+				t.Errorf("reply_broadcast = %q, want broadcast %v", form.Get("reply_broadcast"), tt.want)
+			}
+			if form.Get("thread_ts") != "1700000000.000100" {
+				t.Errorf("thread_ts = %q, want the mention's", form.Get("thread_ts"))
+			}
+			if text := form.Get("text"); strings.Contains(text, "broadcast") || !strings.Contains(text, "> Will it rain?") {
+				t.Errorf("posted %q, want the question without the flag", text)
+			}
+		})
+	}
+}
+
+func TestReplyToMentionDisallowedChannel(t *testing.T) {
+	withConfig(t, map[string]string{"ALLOWED_CHANNELS": "C1"})
+	client, calls := fakeSlack(t)
+	replyToMention(client, "T1", &slackevents.AppMentionEvent{User: "U1", Channel: "C9", Text: "<@U0BOT> Will it rain?", TimeStamp: "1700000000.000100"})
+
+	call := nextOutbound(t, calls)
+	if call.URL != "chat.postEphemeral" || postedText(t, call) != replyChannelNotAllowed {
+		t.Errorf("called %s with %q, want the refusal as an ephemeral", call.URL, postedText(t, call))
+	}
+}
+
+func TestReplyToMentionTruncatesEcho(t *testing.T) {
+	c := withConfig(t, map[string]string{"ECHO_MAX_LEN": "40", "FORCE_YES_WORDS": "pizza"})
+	client, calls := fakeSlack(t)
+	// Only the end of the question, cut from the echo, forces the answer.
+	question := "Should we " + strings.Repeat("finally ", 10) + "order pizza?"
+	replyToMention(client, "T1", &slackevents.AppMentionEvent{User: "U1", Channel: "C1", Text: "<@U0BOT> " + question, TimeStamp: "1700000000.000001"})
+
+	text := postedText(t, nextOutbound(t, calls))
+	if !strings.Contains(text, truncateRunes(question, 40)) || strings.Contains(text, "pizza") {
+		t.Errorf("posted %q, want the question cut to 40 runes", text)
+	}
+	yes := false
+	for _, r := range c.Responses {
+		yes = yes || (categoryOf(r) == categoryYes && strings.Contains(text, r))
+	}
+	if !yes {
+		t.Errorf("posted %q, want the yes the whole question forces", text)
+	}
+}
+
+func TestReplyToMentionPrivate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"<@U0BOT> Will it rain?", "chat.postMessage"},
+		{"<@U0BOT> Will it rain? --private", "chat.postEphemeral"},
+		{"<@U0BOT> --public Will it rain? --private", "chat.postEphemeral"},
+	}
+	for _, tt := range tests {
+		withConfig(t, nil)
+		client, calls := fakeSlack(t)
+		replyToMention(client, "T1", &slackevents.AppMentionEvent{User: "U1", Channel: "C1", Text: tt.text, TimeStamp: "1700000000.000001"})
+		call := nextOutbound(t, calls)
+		if call.URL != tt.want {
+			t.Errorf("%q: called %s, want %s", tt.text, call.URL, tt.want)
+		}
+		form, _ := url.ParseQuery(call.Body)
+		if tt.want == "chat.postEphemeral" && form.Get("user") != "U1" {
+			t.Errorf("%q: ephemeral for %q, want the asker", tt.text, form.Get("user"))
+		}
+	}
+}
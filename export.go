@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// exportCategoriesArg asks /8ball-export for per-category totals rather
+// than per-answer counts.
+const exportCategoriesArg = "categories"
+
+// statsCSV renders answer counts as CSV with a header row, in sortedStats
+// order. With byCategory it totals the counts per category instead, ordered
+// the same way; answers without a category are totalled as "unknown".
+func statsCSV(counts map[string]int, byCategory bool) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if byCategory {
+		totals := make(map[string]int)
+		for answer, n := range counts {
+			totals[cmp.Or(categoryOf(answer), "unknown")] += n
+		}
+		w.Write([]string{"category", "count"})
+		for _, e := range sortedStats(totals) {
+			w.Write([]string{e.Answer, strconv.Itoa(e.Count)})
+		}
+	} else {
+		w.Write([]string{"answer", "category", "count"})
+		for _, e := range sortedStats(counts) {
+			w.Write([]string{e.Answer, categoryOf(e.Answer), strconv.Itoa(e.Count)})
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// exportRequest reports whether text asks for per-category totals.
+func exportRequest(text string) (byCategory, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "":
+		return false, true
+	case exportCategoriesArg:
+		return true, true
+	}
+	return false, false
+}
+
+// replyExportUsage explains /8ball-export's argument.
+const replyExportUsage = "Usage: /8ball-export [categories]"
+
+// exportCommand shows the stats as CSV in a code block, for when
+// /8ball-export is dispatched through a shared Request URL that can only
+// answer with a Slack message.
+func exportCommand(cmd slack.SlashCommand) *Reply {
+	byCategory, ok := exportRequest(cmd.Text)
+	if !ok {
+		return &Reply{Text: replyExportUsage}
+	}
+	data, err := statsCSV(stats.snapshot(), byCategory)
+	if err != nil {
+		logError("Error exporting stats: %v", err)
+		return &Reply{Text: "🎱 Couldn't export the stats."}
+	}
+	return &Reply{Text: "```\n" + string(data) + "```"}
+}
+
+// handleExport processes the /8ball-export slash command. On its own
+// Request URL it answers admins with the CSV itself, as text/csv, so it can
+// be fetched straight into a spreadsheet.
+func handleExport(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	cmd, ok := verifyCommand(w, r, signingSecret)
+	if !ok {
+		return
+	}
+	if !channelAllowed(cmd.ChannelID, cfg.AllowedChannels) {
+		writeJSON(w, (&Reply{Text: replyChannelNotAllowed}).webhookMessage())
+		return
+	}
+	byCategory, ok := exportRequest(cmd.Text)
+	if !cfg.isAdmin(cmd.UserID) || !ok {
+		writeJSON(w, adminOnly("export stats", exportCommand)(cmd).webhookMessage())
+		return
+	}
+	data, err := statsCSV(stats.snapshot(), byCategory)
+	if err != nil {
+		logError("Error exporting stats: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="8ball-stats.csv"`)
+	w.Write(data)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// exportCounts are answer counts with ties and an answer of no category.
+var exportCounts = map[string]int{
+	"It is certain.":    3,
+	"Ask again later.":  3,
+	"Very doubtful.":    5,
+	"As I see it, yes.": 1,
+	"Who knows?":        2,
+}
+
+func TestStatsCSV(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		name       string
+		byCategory bool
+		want       [][]string
+	}{
+		{"answers", false, [][]string{
+			{"answer", "category", "count"},
+			{"Very doubtful.", "no", "5"},
+			{"Ask again later.", "maybe", "3"},
+			{"It is certain.", "yes", "3"},
+			{"Who knows?", "", "2"},
+			{"As I see it, yes.", "yes", "1"},
+		}},
+		{"categories", true, [][]string{
+			{"category", "count"},
+			{"no", "5"},
+			{"yes", "4"},
+			{"maybe", "3"},
+			{"unknown", "2"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map order varies between runs; the rows mustn't.
+			for range 10 {
+				data, err := statsCSV(exportCounts, tt.byCategory)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+				if err != nil {
+					t.Fatalf("%q isn't CSV: %v", data, err)
+				}
+				if !slices.EqualFunc(rows, tt.want, slices.Equal) {
+					t.Fatalf("rows = %q, want %q", rows, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// exportViaHTTP sends /8ball-export text as userID.
+func exportViaHTTP(userID, text string) *httptest.ResponseRecorder {
+	body := url.Values{"command": {"/8ball-export"}, "text": {text}, "user_id": {userID}, "team_id": {"T1"}, "channel_id": {"C1"}}.Encode()
+	w := httptest.NewRecorder()
+	handleExport(w, signedRequest("/8ball-export", formContentType, body), testSecret)
+	return w
+}
+
+func TestHandleExport(t *testing.T) {
+	withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN"})
+	for answer, n := range exportCounts {
+		for range n {
+			stats.record(answer)
+		}
+	}
+
+	w := exportViaHTTP("UADMIN", "")
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want an attachment", cd)
+	}
+	want, _ := statsCSV(exportCounts, false)
+	if w.Body.String() != string(want) {
+		t.Errorf("body = %q, want %q", w.Body, want)
+	}
+	if w = exportViaHTTP("UADMIN", " Categories "); !strings.HasPrefix(w.Body.String(), "category,count\nno,5\n") {
+		t.Errorf("categories body = %q", w.Body)
+	}
+
+	// Anyone else, or a bad argument, gets a Slack message instead.
+	tests := []struct {
+		user, text, want string
+	}{
+		{"U1", "", "Sorry, only admins can export stats."},
+		{"UADMIN", "everything", replyExportUsage},
+	}
+	for _, tt := range tests {
+		w := exportViaHTTP(tt.user, tt.text)
+		var msg slack.WebhookMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+			t.Fatalf("%s %q: reply %q isn't JSON: %v", tt.user, tt.text, w.Body, err)
+		}
+		if !strings.Contains(msg.Text, tt.want) {
+			t.Errorf("%s %q: reply %q, want %q", tt.user, tt.text, msg.Text, tt.want)
+		}
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	withConfig(t, nil)
+	stats.record("It is certain.")
+	reply := exportCommand(slack.SlashCommand{Text: "categories"})
+	if reply.Text != "```\ncategory,count\nyes,1\n```" {
+		t.Errorf("reply = %q, want the CSV in a code block", reply.Text)
+	}
+}
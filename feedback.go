@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/slack-go/slack"
+)
+
+// Votes for /8ball-feedback.
+const (
+	voteUp   = "up"
+	voteDown = "down"
+)
+
+// feedbackTotal counts feedback votes by vote and the category of the
+// answer voted on.
+var feedbackTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ask8ball_feedback_total",
+	Help: "Feedback votes on answers, by vote and answer category.",
+}, []string{"vote", "category"})
+
+// feedbackTally is the votes one answer has received.
+type feedbackTally struct {
+	Up, Down int
+}
+
+// feedbackStore tallies votes per answer text since startup.
+type feedbackStore struct {
+	mu      sync.Mutex
+	tallies map[string]feedbackTally
+}
+
+// feedback is shared by all requests.
+var feedback = &feedbackStore{tallies: make(map[string]feedbackTally)}
+
+// record counts vote against answer.
+func (s *feedbackStore) record(answer, vote string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tallies[answer]
+	if vote == voteUp {
+		t.Up++
+	} else {
+		t.Down++
+	}
+	s.tallies[answer] = t
+}
+
+// tally returns the votes answer has received.
+func (s *feedbackStore) tally(answer string) feedbackTally {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tallies[answer]
+}
+
+// parseVote reads a vote from command text: "up", "down", 👍 or 👎.
+func parseVote(text string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "up", "+", "+1", "👍", ":+1:", ":thumbsup:":
+		return voteUp, true
+	case "down", "-", "-1", "👎", ":-1:", ":thumbsdown:":
+		return voteDown, true
+	}
+	return "", false
+}
+
+// feedbackCommand records a vote on the last answer given to the caller.
+func feedbackCommand(cmd slack.SlashCommand) *Reply {
+	vote, ok := parseVote(cmd.Text)
+	if !ok {
+		return &Reply{Text: "Usage: /8ball-feedback up, or /8ball-feedback down"}
+	}
+	answer, ok := users.lastAnswer(cmd.UserID)
+	if !ok {
+		return &Reply{Text: "🎱 I haven't answered you yet, so there's nothing to rate."}
+	}
+	feedback.record(answer, vote)
+	feedbackTotal.WithLabelValues(vote, metricCategory(Response{Text: answer, Category: categoryOf(answer)})).Inc()
+	if vote == voteUp {
+		return &Reply{Text: "🎱 Thanks! Glad \"" + answer + "\" hit the spot."}
+	}
+	return &Reply{Text: "🎱 Thanks, noted. The 8-ball will try to do better than \"" + answer + "\"."}
+}
+
+// handleFeedback processes the /8ball-feedback slash command
+func handleFeedback(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, feedbackCommand)
+}
+
+// purge forgets all votes, returning how many answers had some.
+func (s *feedbackStore) purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.tallies)
+	clear(s.tallies)
+	return n
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/slack-go/slack"
+)
+
+func TestParseVote(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{"up", voteUp, true},
+		{" UP ", voteUp, true},
+		{"👍", voteUp, true},
+		{":thumbsup:", voteUp, true},
+		{"+1", voteUp, true},
+		{"down", voteDown, true},
+		{"👎", voteDown, true},
+		{":-1:", voteDown, true},
+		{"", "", false},
+		{"sideways", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseVote(tt.text); got != tt.want || ok != tt.ok {
+			t.Errorf("parseVote(%q) = %q, %v; want %q, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFeedbackCommand(t *testing.T) {
+	withConfig(t, nil)
+	answer := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}).Text
+	category := metricCategory(Response{Text: answer, Category: categoryOf(answer)})
+	upBefore := testutil.ToFloat64(feedbackTotal.WithLabelValues(voteUp, category))
+	downBefore := testutil.ToFloat64(feedbackTotal.WithLabelValues(voteDown, category))
+
+	steps := []struct {
+		text  string
+		want  string
+		tally feedbackTally
+	}{
+		{"up", "Thanks! Glad \"" + answer + "\" hit the spot.", feedbackTally{Up: 1}},
+		{"👍", "Thanks! Glad", feedbackTally{Up: 2}},
+		{"down", "try to do better than \"" + answer + "\"", feedbackTally{Up: 2, Down: 1}},
+		{"meh", "Usage: /8ball-feedback up", feedbackTally{Up: 2, Down: 1}},
+	}
+	for _, step := range steps {
+		reply := feedbackCommand(slack.SlashCommand{Command: "/8ball-feedback", Text: step.text, UserID: "U1"})
+		if !strings.Contains(reply.Text, step.want) {
+			t.Errorf("/8ball-feedback %s: reply %q lacks %q", step.text, reply.Text, step.want)
+		}
+		if got := feedback.tally(answer); got != step.tally {
+			t.Errorf("after %s: tally = %+v, want %+v", step.text, got, step.tally)
+		}
+	}
+	if got := testutil.ToFloat64(feedbackTotal.WithLabelValues(voteUp, category)) - upBefore; got != 2 {
+		t.Errorf("up votes counted = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(feedbackTotal.WithLabelValues(voteDown, category)) - downBefore; got != 1 {
+		t.Errorf("down votes counted = %v, want 1", got)
+	}
+}
+
+func TestFeedbackWithoutAnswer(t *testing.T) {
+	withConfig(t, nil)
+	ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"})
+
+	body := url.Values{"command": {"/8ball-feedback"}, "text": {"up"}, "user_id": {"U2"}, "team_id": {"T1"}, "channel_id": {"C1"}}.Encode()
+	w := httptest.NewRecorder()
+	handleFeedback(w, signedRequest("/8ball-feedback", formContentType, body), testSecret)
+	var msg slack.WebhookMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("decoding reply %q: %v", w.Body, err)
+	}
+	if !strings.Contains(msg.Text, "nothing to rate") {
+		t.Errorf("feedback without an answer replied %q", msg.Text)
+	}
+	if n := feedback.purge(); n != 0 {
+		t.Errorf("%d answers got votes, want none", n)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// Flags recognized in questions, without their leading dashes.
+const (
+	flagBroadcast = "broadcast"
+	flagPublic    = "public"
+	flagPrivate   = "private"
+	flagShort     = "short"
+	flagLong      = "long"
+)
+
+// knownFlags lists every flag parseFlags accepts.
+var knownFlags = []string{flagBroadcast, flagPublic, flagPrivate, flagShort, flagLong}
+
+// flagName returns the name of the flag word w, such as "broadcast" for
+// "--broadcast", or "" if w isn't a flag. The em dash form covers clients
+// that "smarten" a typed "--".
+func flagName(w string) string {
+	for _, prefix := range []string{"--", "—"} {
+		if name, ok := strings.CutPrefix(w, prefix); ok && name != "" && !strings.HasPrefix(name, "-") {
+			return strings.ToLower(name)
+		}
+	}
+	return ""
+}
+
+// parseFlags extracts every --flag word from text, wherever it appears, and
+// returns the question without them. Text without flags is returned
+// unchanged. An unrecognized flag is an error naming the ones that exist.
+func parseFlags(text string) (clean string, flags map[string]bool, err error) {
+	words := strings.Fields(text)
+	kept := words[:0]
+	flags = make(map[string]bool)
+	for _, w := range words {
+		name := flagName(w)
+		if name == "" {
+			kept = append(kept, w)
+			continue
+		}
+		if !slices.Contains(knownFlags, name) {
+			return "", nil, fmt.Errorf("unknown flag %s", w)
+		}
+		flags[name] = true
+	}
+	if len(flags) == 0 {
+		return text, flags, nil
+	}
+	return strings.Join(kept, " "), flags, nil
+}
+
+// responseTypeFor is the response type flags ask a slash command answer
+// for: ephemeral by default, in the channel with --public. --private wins
+// over --public, so adding it always keeps an answer private.
+func responseTypeFor(flags map[string]bool) string {
+	if flags[flagPublic] && !flags[flagPrivate] {
+		return slack.ResponseTypeInChannel
+	}
+	return slack.ResponseTypeEphemeral
+}
+
+// flagHelp is the reply to a question with an unknown flag.
+func flagHelp(err error) string {
+	names := make([]string, len(knownFlags))
+	for i, name := range knownFlags {
+		names[i] = "--" + name
+	}
+	return fmt.Sprintf("🎱 I don't understand that: %v. Flags I know: %s.", err, strings.Join(names, ", "))
+}
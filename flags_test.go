@@ -0,0 +1,106 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantClean string
+		wantFlags []string
+		wantErr   string
+	}{
+		{"none", "Will  it rain? ", "Will  it rain? ", nil, ""},
+		{"trailing", "Will it rain? --public", "Will it rain?", []string{flagPublic}, ""},
+		{"multiple", "--broadcast Will it rain? --public --short", "Will it rain?", []string{flagBroadcast, flagPublic, flagShort}, ""},
+		{"mid-sentence", "Will it --long rain?", "Will it rain?", []string{flagLong}, ""},
+		{"repeated", "Will it rain? --public --PUBLIC", "Will it rain?", []string{flagPublic}, ""},
+		{"em dash", "Will it rain? —public", "Will it rain?", []string{flagPublic}, ""},
+		{"only flags", "--public", "", []string{flagPublic}, ""},
+		{"dashes alone", "Will it rain -- or not?", "Will it rain -- or not?", nil, ""},
+		{"triple dash", "Will it rain ---public?", "Will it rain ---public?", nil, ""},
+		{"unknown", "Will it rain? --odds", "", nil, "unknown flag --odds"},
+		{"unknown among known", "--public Will it rain? --loud", "", nil, "unknown flag --loud"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clean, flags, err := parseFlags(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseFlags(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFlags(%q): %v", tt.text, err)
+			}
+			want := map[string]bool{}
+			for _, f := range tt.wantFlags {
+				want[f] = true
+			}
+			if clean != tt.wantClean || !maps.Equal(flags, want) {
+				t.Errorf("parseFlags(%q) = %q, %v; want %q, %v", tt.text, clean, flags, tt.wantClean, want)
+			}
+		})
+	}
+}
+
+func TestResponseTypeFor(t *testing.T) {
+	tests := []struct {
+		flags map[string]bool
+		want  string
+	}{
+		{nil, slack.ResponseTypeEphemeral},
+		{map[string]bool{flagPublic: true}, slack.ResponseTypeInChannel},
+		{map[string]bool{flagPrivate: true}, slack.ResponseTypeEphemeral},
+		{map[string]bool{flagPublic: true, flagPrivate: true}, slack.ResponseTypeEphemeral},
+	}
+	for _, tt := range tests {
+		if got := responseTypeFor(tt.flags); got != tt.want {
+			t.Errorf("responseTypeFor(%v) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestAskCommandUnknownFlag(t *testing.T) {
+	withConfig(t, nil)
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain? --odds", TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+	for _, want := range []string{"unknown flag --odds", "--broadcast", "--public", "--long"} {
+		if !strings.Contains(reply.Text, want) {
+			t.Errorf("reply %q lacks %s", reply.Text, want)
+		}
+	}
+	reply = askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "Will it --public rain?", TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+	if reply.ResponseType != slack.ResponseTypeInChannel || strings.Contains(reply.Text, "--public") {
+		t.Errorf("--public mid-question: %q %q", reply.ResponseType, reply.Text)
+	}
+}
+
+func TestAskCommandVisibilityFlags(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Will it rain?", slack.ResponseTypeEphemeral},
+		{"Will it rain? --public", slack.ResponseTypeInChannel},
+		{"Will it rain? --private", slack.ResponseTypeEphemeral},
+		{"--public Will it rain? --private", slack.ResponseTypeEphemeral},
+		{"--private Will it rain? --public", slack.ResponseTypeEphemeral},
+	}
+	for _, tt := range tests {
+		withConfig(t, nil)
+		reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: tt.text, TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+		if reply.ResponseType != tt.want {
+			t.Errorf("%q: response_type = %q, want %q", tt.text, reply.ResponseType, tt.want)
+		}
+		if strings.Contains(reply.Text, "--") {
+			t.Errorf("%q: reply %q shows the flags", tt.text, reply.Text)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "strings"
+
+// forceConfig lists trigger substrings that fix an answer's sentiment, e.g.
+// any question mentioning "pizza" is always answered yes.
+type forceConfig struct {
+	YesWords []string `json:"yes_words"`
+	NoWords  []string `json:"no_words"`
+}
+
+// forcedCategory returns the category forced by a trigger word in text,
+// matched case-insensitively as a substring. When text contains both kinds
+// of trigger, the no-words win: it's the safer verdict for an inside joke.
+func forcedCategory(text string, fc forceConfig) (string, bool) {
+	if len(fc.YesWords) == 0 && len(fc.NoWords) == 0 {
+		return "", false
+	}
+	lower := strings.ToLower(text)
+	for _, w := range fc.NoWords {
+		if strings.Contains(lower, strings.ToLower(w)) {
+			return categoryNo, true
+		}
+	}
+	for _, w := range fc.YesWords {
+		if strings.Contains(lower, strings.ToLower(w)) {
+			return categoryYes, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestForcedCategory(t *testing.T) {
+	fc := forceConfig{YesWords: []string{"pizza", "Friday"}, NoWords: []string{"monday"}}
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		forced bool
+	}{
+		{"yes word", "Should we get pizza?", categoryYes, true},
+		{"yes word any case", "Is it FRIDAY yet?", categoryYes, true},
+		{"yes word inside another", "Pizzas tonight?", categoryYes, true},
+		{"no word", "Do I have to work Monday?", categoryNo, true},
+		{"conflict goes to no", "Pizza on Monday?", categoryNo, true},
+		{"no match", "Will it rain?", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := forcedCategory(tt.text, fc)
+			if got != tt.want || ok != tt.forced {
+				t.Errorf("forcedCategory(%q) = %q, %v; want %q, %v", tt.text, got, ok, tt.want, tt.forced)
+			}
+		})
+	}
+	if _, ok := forcedCategory("Pizza?", forceConfig{}); ok {
+		t.Error("forcedCategory with no words forced a category")
+	}
+}
+
+func TestForcedAnswers(t *testing.T) {
+	c := withConfig(t, map[string]string{"FORCE_YES_WORDS": "pizza", "FORCE_NO_WORDS": "monday"})
+	r := rand.New(rand.NewSource(5))
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Should we get pizza?", categoryYes},
+		{"Is Monday fun?", categoryNo},
+		{"Pizza on Monday?", categoryNo},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			resp, err := Answer(tt.text, c.Responses, true, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Category != tt.want || resp.Source != sourceForced {
+				t.Fatalf("Answer(%q) = %q (%s from %s), want a forced %s", tt.text, resp.Text, resp.Category, resp.Source, tt.want)
+			}
+		}
+	}
+}
+
+func TestForcedWordWithoutMatchingAnswer(t *testing.T) {
+	// With no yes answer to force, a normal answer is picked instead.
+	c := withConfig(t, map[string]string{"FORCE_YES_WORDS": "pizza"})
+	var noYes []string
+	for _, text := range c.Responses {
+		if categoryOf(text) != categoryYes {
+			noYes = append(noYes, text)
+		}
+	}
+	resp, err := Answer("Pizza?", noYes, true, rand.New(rand.NewSource(5)))
+	if err != nil || resp.Source == sourceForced {
+		t.Errorf("Answer = %q from %s, %v; want an unforced answer", resp.Text, resp.Source, err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestMain(m *testing.M) {
+	// Handlers log every request; keep test output to the failures.
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// withConfig loads the configuration with env set on top of the process
+// environment and makes it live, as main does, for the rest of the test.
+// Shared state is wiped before and after so tests don't see each other's
+// counts and caches.
+func withConfig(t testing.TB, env map[string]string) Config {
+	t.Helper()
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	setConfig(t, c)
+	return c
+}
+
+// setConfig makes c the live configuration for the rest of the test.
+func setConfig(t testing.TB, c Config) {
+	t.Helper()
+	oldCfg, oldLive := cfg, liveResponses.Load()
+	cfg = c
+	liveResponses.Store(newResponseSet(c))
+	purgeAll()
+	t.Cleanup(func() {
+		purgeAll()
+		cfg = oldCfg
+		liveResponses.Store(oldLive)
+	})
+}
+
+// testSecret is the signing secret signedRequest signs with.
+const testSecret = "test-signing-secret"
+
+// signedRequest builds a POST to path carrying body, signed with testSecret
+// as Slack would sign it now.
+func signedRequest(path, contentType, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	ts := strconv.FormatInt(clock().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+// outboundRequest is a request the bot sent while captureOutbound was in
+// effect, with its body already read.
+type outboundRequest struct {
+	URL  string
+	Body string
+}
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// captureOutbound answers every request made through httpClient with an
+// empty 200 for the rest of the test, and sends each one on the returned
+// channel instead of over the network.
+func captureOutbound(t *testing.T) <-chan outboundRequest {
+	t.Helper()
+	return stubOutbound(t, http.StatusOK)
+}
+
+// stubOutbound is captureOutbound answering with status instead.
+func stubOutbound(t *testing.T, status int) <-chan outboundRequest {
+	t.Helper()
+	sent := make(chan outboundRequest, 16)
+	old := httpClient
+	httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		sent <- outboundRequest{URL: r.URL.String(), Body: string(body)}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewReader(nil)), Header: http.Header{}}, nil
+	})}
+	t.Cleanup(func() { httpClient = old })
+	return sent
+}
+
+// nextOutbound waits for the next request captureOutbound sees.
+func nextOutbound(t *testing.T, sent <-chan outboundRequest) outboundRequest {
+	t.Helper()
+	select {
+	case req := <-sent:
+		return req
+	case <-time.After(2 * time.Second):
+		t.Fatal("no outbound request was made")
+		return outboundRequest{}
+	}
+}
+
+// captureLog collects everything logged for the rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(io.Discard) })
+	return &buf
+}
+
+// captureDebugLog collects everything logged through slog, down to debug
+// level, for the rest of the test.
+func captureDebugLog(t *testing.T) *lockedBuffer {
+	t.Helper()
+	var buf lockedBuffer
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(old) })
+	return &buf
+}
+
+// setClock pins clock to now for the rest of the test.
+func setClock(t *testing.T, now time.Time) {
+	t.Helper()
+	old := clock
+	clock = func() time.Time { return now }
+	t.Cleanup(func() { clock = old })
+}
+
+// fakeSlack serves the Slack Web API for the rest of the test, answering
+// every method with ok. It returns a client using it and a channel carrying
+// each call, with the method as URL and the form as body.
+func fakeSlack(t *testing.T) (*slack.Client, <-chan outboundRequest) {
+	t.Helper()
+	calls := make(chan outboundRequest, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls <- outboundRequest{URL: strings.TrimPrefix(r.URL.Path, "/"), Body: string(body)}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":true,"channel":"C1","ts":"1700000000.000100","message_ts":"1700000000.000100"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return slack.New("xoxb-test", slack.OptionAPIURL(srv.URL+"/")), calls
+}
+
+// fakeClock pins clock to start for the rest of the test and returns a
+// function moving it on by d. It is safe to advance while background
+// goroutines read the clock.
+func fakeClock(t *testing.T, start time.Time) (advance func(d time.Duration)) {
+	t.Helper()
+	var mu sync.Mutex
+	now := start
+	old := clock
+	clock = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	t.Cleanup(func() { clock = old })
+	return func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+// seedRNG reseeds the shared rng for the rest of the test, as RANDOM_SEED
+// does at startup.
+func seedRNG(t testing.TB, seed int64) {
+	t.Helper()
+	old := rng
+	rng = newLockedRand(seed)
+	t.Cleanup(func() { rng = old })
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// replyOffHours tells users the bot is outside BUSINESS_HOURS.
+const replyOffHours = "🎱 is off the clock. Ask during business hours."
+
+// businessHours is a weekly schedule such as "Mon-Fri 09:00-17:00
+// America/New_York": a run of weekdays and a daily time range in a zone.
+type businessHours struct {
+	firstDay, lastDay time.Weekday
+	// start and end are minutes after midnight; end is exclusive.
+	start, end int
+	loc        *time.Location
+	raw        string
+}
+
+// weekdays maps the three-letter day names BUSINESS_HOURS uses.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseBusinessHours parses BUSINESS_HOURS. The zone defaults to UTC, and
+// a day run may wrap around the week, as in "Sat-Sun".
+func parseBusinessHours(s string) (*businessHours, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 || len(fields) > 3 {
+		return nil, fmt.Errorf("invalid BUSINESS_HOURS %q: want e.g. \"Mon-Fri 09:00-17:00 America/New_York\"", s)
+	}
+	h := &businessHours{loc: time.UTC, raw: s}
+
+	first, last, _ := strings.Cut(strings.ToLower(fields[0]), "-")
+	if last == "" {
+		last = first
+	}
+	var ok1, ok2 bool
+	h.firstDay, ok1 = weekdays[first]
+	h.lastDay, ok2 = weekdays[last]
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("invalid BUSINESS_HOURS %q: days must be like Mon or Mon-Fri", s)
+	}
+
+	from, to, ok := strings.Cut(fields[1], "-")
+	var err error
+	if h.start, err = parseClock(from); err == nil && ok {
+		h.end, err = parseClock(to)
+	}
+	if !ok || err != nil || h.end <= h.start {
+		return nil, fmt.Errorf("invalid BUSINESS_HOURS %q: hours must be like 09:00-17:00", s)
+	}
+
+	if len(fields) == 3 {
+		if h.loc, err = time.LoadLocation(fields[2]); err != nil {
+			return nil, fmt.Errorf("invalid BUSINESS_HOURS %q: %w", s, err)
+		}
+	}
+	return h, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight. "24:00" is
+// allowed as the end of a day.
+func parseClock(s string) (int, error) {
+	var hh, mm int
+	if _, err := fmt.Sscanf(s, "%d:%d", &hh, &mm); err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	if hh < 0 || mm < 0 || mm > 59 || hh > 24 || (hh == 24 && mm != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hh*60 + mm, nil
+}
+
+// open reports whether t falls within the schedule.
+func (h *businessHours) open(t time.Time) bool {
+	t = t.In(h.loc)
+	day := (t.Weekday() - h.firstDay + 7) % 7
+	if day > (h.lastDay-h.firstDay+7)%7 {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	return minute >= h.start && minute < h.end
+}
+
+// MarshalText shows the schedule as configured, e.g. in /8ball-config.
+func (h *businessHours) MarshalText() ([]byte, error) {
+	return []byte(h.raw), nil
+}
+
+// offHours reports whether BUSINESS_HOURS is set and clock() is outside it.
+func offHours() bool {
+	return cfg.BusinessHours != nil && !cfg.BusinessHours.open(clock())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestBusinessHoursOpen(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		name     string
+		schedule string
+		at       time.Time
+		want     bool
+	}{
+		// 1 May 2024 was a Wednesday.
+		{"in hours", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 1, 14, 0, 0, 0, ny), true},
+		{"in hours, from UTC", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC), true},
+		{"at opening", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 1, 9, 0, 0, 0, ny), true},
+		{"before opening", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 1, 8, 59, 0, 0, ny), false},
+		{"at closing", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 1, 17, 0, 0, 0, ny), false},
+		{"evening in New York, next day in UTC", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 2, 1, 0, 0, 0, time.UTC), false},
+		{"weekend", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 4, 12, 0, 0, 0, ny), false},
+		{"Friday", "Mon-Fri 09:00-17:00 America/New_York", time.Date(2024, 5, 3, 12, 0, 0, 0, ny), true},
+		{"UTC by default", "Mon-Fri 09:00-17:00", time.Date(2024, 5, 1, 16, 59, 0, 0, time.UTC), true},
+		{"weekend run wraps", "Sat-Sun 10:00-14:00", time.Date(2024, 5, 5, 11, 0, 0, 0, time.UTC), true},
+		{"weekend run skips weekdays", "Sat-Sun 10:00-14:00", time.Date(2024, 5, 6, 11, 0, 0, 0, time.UTC), false},
+		{"single day", "wed 00:00-24:00", time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		h, err := parseBusinessHours(tt.schedule)
+		if err != nil {
+			t.Fatalf("%s: parseBusinessHours(%q): %v", tt.name, tt.schedule, err)
+		}
+		if got := h.open(tt.at); got != tt.want {
+			t.Errorf("%s: %q open at %v = %v, want %v", tt.name, tt.schedule, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestParseBusinessHoursRejects(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"Mon-Fri",
+		"Mon-Fry 09:00-17:00",
+		"Mon-Fri 9:00-17:00",
+		"Mon-Fri 09:00",
+		"Mon-Fri 17:00-09:00",
+		"Mon-Fri 09:00-24:30",
+		"Mon-Fri 09:60-17:00",
+		"Mon-Fri 09:00-17:00 Mars/Olympus_Mons",
+		"Mon-Fri 09:00-17:00 UTC extra",
+	} {
+		if _, err := parseBusinessHours(bad); err == nil {
+			t.Errorf("parseBusinessHours(%q) succeeded", bad)
+		}
+	}
+	t.Setenv("BUSINESS_HOURS", "whenever")
+	if _, err := loadConfig(); err == nil {
+		t.Error("BUSINESS_HOURS=whenever loaded")
+	}
+}
+
+func TestOffHoursReply(t *testing.T) {
+	withConfig(t, map[string]string{"BUSINESS_HOURS": "Mon-Fri 09:00-17:00", "WEBHOOK_TOKEN": "ci-token"})
+	cmd := slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", TeamID: "T1", ChannelID: "C1", UserID: "U1"}
+	tests := []struct {
+		name string
+		at   time.Time
+		off  bool
+	}{
+		{"in hours", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"off hours", time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC), true},
+		{"weekend", time.Date(2024, 5, 4, 10, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		setClock(t, tt.at)
+		reply := askCommand(cmd)
+		if (reply.Text == replyOffHours) != tt.off {
+			t.Errorf("%s: reply = %q, want off hours %v", tt.name, reply.Text, tt.off)
+		}
+		if tt.off && reply.ResponseType == slack.ResponseTypeInChannel {
+			t.Errorf("%s: off-hours reply is public", tt.name)
+		}
+
+		// Mentions are turned away privately too.
+		client, calls := fakeSlack(t)
+		replyToMention(client, "T1", &slackevents.AppMentionEvent{User: "U1", Channel: "C1", Text: "<@U0BOT> Will it rain?", TimeStamp: "1700000000.000001"})
+		call := nextOutbound(t, calls)
+		if got := postedText(t, call); (got == replyOffHours) != tt.off || (call.URL == "chat.postEphemeral") != tt.off {
+			t.Errorf("%s: mention got %s %q, want off hours %v", tt.name, call.URL, got, tt.off)
+		}
+
+		// So are other tools' webhook questions.
+		r := httptest.NewRequest("POST", "/webhook/ask", strings.NewReader(`{"question":"Will it rain?"}`))
+		r.Header.Set("Authorization", "Bearer ci-token")
+		w := httptest.NewRecorder()
+		handleWebhookAsk(w, r)
+		var out webhookAnswer
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("%s: decoding %q: %v", tt.name, w.Body, err)
+		}
+		if (out.Answer == replyOffHours) != tt.off || tt.off && out.Confidence != nil {
+			t.Errorf("%s: webhook answered %+v, want off hours %v", tt.name, out, tt.off)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// parseCategoryImages parses IMAGE_URLS entries such as
+// "yes:https://example.com/yes.gif", collecting the images for each category.
+func parseCategoryImages(entries []string) (map[string][]string, error) {
+	images := make(map[string][]string)
+	for _, e := range entries {
+		category, u, ok := strings.Cut(e, ":")
+		category, u = strings.ToLower(strings.TrimSpace(category)), strings.TrimSpace(u)
+		if !ok || u == "" {
+			return nil, fmt.Errorf("invalid IMAGE_URLS entry %q: want category:url", e)
+		}
+		if !knownCategory(category) {
+			return nil, fmt.Errorf("invalid IMAGE_URLS entry %q: unknown category %q", e, category)
+		}
+		if err := validateImageURL(u); err != nil {
+			return nil, fmt.Errorf("invalid IMAGE_URLS entry %q: %w", e, err)
+		}
+		images[category] = append(images[category], u)
+	}
+	return images, nil
+}
+
+// validateImageURL checks that u is an absolute https URL, which is all
+// Slack will unfurl in an image block without mixed-content warnings.
+func validateImageURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("%q is not an https URL", u)
+	}
+	return nil
+}
+
+// imageForCategory picks one of the images configured for category, or
+// reports false when IMAGE_REPLIES is off or the category has none.
+func imageForCategory(category string, r *rand.Rand) (string, bool) {
+	if !cfg.ImageReplies {
+		return "", false
+	}
+	set := cfg.CategoryImages[category]
+	if len(set) == 0 {
+		return "", false
+	}
+	return set[r.Intn(len(set))], true
+}
+
+// withImage adds an image suiting msg's category after its text, leaving the
+// message as it is when there is none.
+func withImage(msg *Reply) *Reply {
+	u, ok := imageForCategory(msg.Category, rng)
+	if !ok {
+		return msg
+	}
+	if len(msg.Blocks) == 0 {
+		msg.Blocks = []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, msg.Text, false, false), nil, nil),
+		}
+	}
+	// Keep the image under the text, above any buttons or footer.
+	image := slack.NewImageBlock(u, msg.Category+" answer", "", nil)
+	msg.Blocks = slices.Insert(msg.Blocks, 1, slack.Block(image))
+	return msg
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestValidateImageURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://example.com/yes.gif", true},
+		{"https://cdn.example.com/a/b.png?size=2", true},
+		{"http://example.com/yes.gif", false},
+		{"ftp://example.com/yes.gif", false},
+		{"//example.com/yes.gif", false},
+		{"https:///yes.gif", false},
+		{"yes.gif", false},
+		{"https://exa mple.com/%zz", false},
+	}
+	for _, tt := range tests {
+		if err := validateImageURL(tt.url); (err == nil) != tt.ok {
+			t.Errorf("validateImageURL(%q) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func TestParseCategoryImages(t *testing.T) {
+	got, err := parseCategoryImages([]string{"yes:https://example.com/y1.gif", " YES : https://example.com/y2.gif", "no:https://example.com/n.gif"})
+	want := map[string][]string{
+		categoryYes: {"https://example.com/y1.gif", "https://example.com/y2.gif"},
+		categoryNo:  {"https://example.com/n.gif"},
+	}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCategoryImages = %v, %v; want %v", got, err, want)
+	}
+	for _, bad := range []string{"yes", "yes:", "sometimes:https://example.com/s.gif", "yes:http://example.com/y.gif"} {
+		if _, err := parseCategoryImages([]string{bad}); err == nil {
+			t.Errorf("parseCategoryImages(%q) succeeded", bad)
+		}
+	}
+	t.Setenv("IMAGE_URLS", "yes:http://example.com/y.gif")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "not an https URL") {
+		t.Errorf("loadConfig with an http image = %v, want it rejected", err)
+	}
+}
+
+func TestImageForCategory(t *testing.T) {
+	images := "yes:https://example.com/y1.gif,yes:https://example.com/y2.gif"
+	tests := []struct {
+		name     string
+		env      map[string]string
+		category string
+		want     []string // the images it may pick; none means no image
+	}{
+		{"with images", map[string]string{"IMAGE_REPLIES": "true", "IMAGE_URLS": images}, categoryYes, []string{"https://example.com/y1.gif", "https://example.com/y2.gif"}},
+		{"without images", map[string]string{"IMAGE_REPLIES": "true", "IMAGE_URLS": images}, categoryNo, nil},
+		{"off", map[string]string{"IMAGE_REPLIES": "false", "IMAGE_URLS": images}, categoryYes, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			r := rand.New(rand.NewSource(1))
+			seen := map[string]bool{}
+			for range 50 {
+				u, ok := imageForCategory(tt.category, r)
+				if ok != (len(tt.want) > 0) {
+					t.Fatalf("imageForCategory(%s) = %q, %v", tt.category, u, ok)
+				}
+				if ok && !slices.Contains(tt.want, u) {
+					t.Fatalf("imageForCategory(%s) = %q, want one of %v", tt.category, u, tt.want)
+				}
+				seen[u] = ok
+			}
+			if len(tt.want) > 0 && len(seen) != len(tt.want) {
+				t.Errorf("50 picks only used %v of %v", seen, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithImage(t *testing.T) {
+	withConfig(t, map[string]string{"IMAGE_REPLIES": "true", "IMAGE_URLS": "yes:https://example.com/y.gif"})
+
+	msg := withImage(&Reply{Text: "🎱 Yes.", Category: categoryYes})
+	if len(msg.Blocks) != 2 {
+		t.Fatalf("reply has %d blocks, want the text and image", len(msg.Blocks))
+	}
+	if image, ok := msg.Blocks[1].(*slack.ImageBlock); !ok || image.ImageURL != "https://example.com/y.gif" {
+		t.Errorf("second block = %#v, want the image", msg.Blocks[1])
+	}
+
+	// The image goes above the buttons.
+	buttons := slack.NewActionBlock("", slack.NewButtonBlockElement("a", "b", slack.NewTextBlockObject(slack.PlainTextType, "c", false, false)))
+	msg = withImage(&Reply{Text: "🎱 Yes.", Category: categoryYes, Blocks: []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "🎱 Yes.", false, false), nil, nil), buttons,
+	}})
+	if len(msg.Blocks) != 3 || msg.Blocks[1].BlockType() != slack.MBTImage || msg.Blocks[2] != slack.Block(buttons) {
+		t.Errorf("blocks = %v, want text, image, buttons", msg.Blocks)
+	}
+
+	// Without an image for the category, the reply stays text.
+	if msg := withImage(&Reply{Text: "🎱 No.", Category: categoryNo}); len(msg.Blocks) != 0 {
+		t.Errorf("reply without an image has blocks %v", msg.Blocks)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Action IDs of the buttons attached to answers.
+const (
+	shakeAgainActionID = "shake_again"
+	shareActionID      = "share_to_channel"
+)
+
+// maxButtonValue is Slack's limit on a button's value field.
+const maxButtonValue = 2000
+
+// sharedAnswer is carried in the "Share to channel" button so the previewed
+// answer can be reposted exactly, with its reason and note.
+type sharedAnswer struct {
+	Question string `json:"q"`
+	Answer   string `json:"a"`
+	Reason   string `json:"r,omitempty"`
+	Note     string `json:"n,omitempty"`
+}
+
+// response is the answer shared carries.
+func (shared sharedAnswer) response() Response {
+	return Response{Text: shared.Answer, Category: categoryOf(shared.Answer), Reason: shared.Reason, Note: shared.Note}
+}
+
+// shakeAgainRequest is carried in the "Shake again" button so the question
+// is asked again the way it was first asked.
+type shakeAgainRequest struct {
+	Question string `json:"q"`
+	Length   string `json:"length,omitempty"`
+}
+
+// parseShakeAgain decodes a "Shake again" button value. Buttons on answers
+// given before the value was JSON carry the bare question.
+func parseShakeAgain(value string) shakeAgainRequest {
+	var again shakeAgainRequest
+	if err := json.Unmarshal([]byte(value), &again); err != nil {
+		return shakeAgainRequest{Question: value}
+	}
+	return again
+}
+
+// replyMessage builds the ephemeral message carrying the answer to req. When
+// the question was actually answered, it offers a "Shake again" button
+// carrying the question, to re-roll without retyping, and a "Share to
+// channel" button carrying the answer, to post the preview publicly. A
+// session's answer is the same however often it is asked, so it gets no
+// "Shake again".
+func replyMessage(resp Response, req askRequest) *Reply {
+	question := req.Question
+	reply := windowText(question, resp)
+	msg := &Reply{Text: reply, Category: resp.Category}
+	if !resp.answered() {
+		return msg
+	}
+	again := shakeAgainRequest{Question: question}
+	if req.Length != lengthNormal {
+		again.Length = req.Length
+	}
+	value, err := json.Marshal(again)
+	if err != nil || len(value) > maxButtonValue {
+		return withImage(msg)
+	}
+
+	var buttons []slack.BlockElement
+	if req.Session == "" {
+		buttons = append(buttons, slack.NewButtonBlockElement(shakeAgainActionID, string(value),
+			slack.NewTextBlockObject(slack.PlainTextType, "🎱 Shake again", true, false)))
+	}
+	if share, err := json.Marshal(sharedAnswer{Question: question, Answer: resp.Text, Reason: resp.Reason, Note: resp.Note}); err == nil && len(share) <= maxButtonValue {
+		buttons = append(buttons, slack.NewButtonBlockElement(shareActionID, string(share),
+			slack.NewTextBlockObject(slack.PlainTextType, "Share to channel", false, false)))
+	}
+	if len(buttons) == 0 {
+		return withImage(msg)
+	}
+
+	msg.Blocks = []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, reply, false, false), nil, nil),
+		slack.NewActionBlock("", buttons...),
+	}
+	return withImage(msg)
+}
+
+// sharedMessage builds the public message posting resp, the answer to
+// question. With SHOW_FOOTER it carries a muted footer saying when it was
+// asked, in userTZ if Slack sent the user's time zone or FOOTER_TZ otherwise.
+func sharedMessage(userID, userTZ, question string, resp Response) *Reply {
+	msg := &Reply{
+		ResponseType: slack.ResponseTypeInChannel,
+		Text:         fmt.Sprintf("<@%s> asked: %s\n%s %s", userID, echoText(question), answerPrefix(resp.Text), windowText(question, resp)),
+		Category:     resp.Category,
+	}
+	if liveFlags().ShowFooter {
+		msg.Blocks = []slack.Block{
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, msg.Text, false, false), nil, nil),
+			slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, footerText(clock(), resolveUserTZ(userTZ, cfg.FooterTZ)), false, false)),
+		}
+	}
+	return withImage(msg)
+}
+
+// footerText is the footer of an in-channel answer given at now, shown in
+// loc.
+func footerText(now time.Time, loc *time.Location) string {
+	return "asked via 🎱 at " + now.In(loc).Format("15:04 MST")
+}
+
+// resolveUserTZ is the time zone to show a user times in: payloadTZ, an
+// IANA name from the Slack payload, if valid, else defaultTZ, else UTC.
+func resolveUserTZ(payloadTZ, defaultTZ string) *time.Location {
+	for _, name := range []string{payloadTZ, defaultTZ} {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// handleInteraction processes Block Kit interaction payloads posted to /interactions
+func handleInteraction(w http.ResponseWriter, r *http.Request, signingSecret string, client *slack.Client) {
+	body, ok := verifyRequest(w, r, signingSecret)
+	if !ok {
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		logError("Error parsing interaction form: %v", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		logError("Error decoding interaction payload: %v", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	if isAskModalSubmission(callback) {
+		// The answer replaces the modal, so it goes in the response body.
+		writeJSON(w, askModalSubmission(&callback))
+		return
+	}
+	if callback.Type == slack.InteractionTypeBlockActions {
+		// Slack only needs an acknowledgement within 3 seconds; replies go
+		// via response_url, which may involve retries.
+		go handleBlockActions(client, &callback)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleBlockActions runs the buttons pressed in a block_actions payload.
+func handleBlockActions(client *slack.Client, callback *slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case shakeAgainActionID:
+			shakeAgain(client, callback, action)
+		case shareActionID:
+			shareToChannel(client, callback, action)
+		}
+	}
+}
+
+// shakeAgain replaces an answer with a fresh one for the same question. It
+// is refused the same way as asking again would be, and a refusal leaves the
+// answer in place.
+func shakeAgain(client *slack.Client, callback *slack.InteractionCallback, action *slack.BlockAction) {
+	again := parseShakeAgain(action.Value)
+	question := again.Question
+	refusal := askRefusal(callback.Team.ID, callback.Channel.ID, callback.User.ID)
+	if refusal == "" && needsAnotherShake(callback.User.ID, question) {
+		refusal = replyShakeAgain
+	}
+	if refusal != "" {
+		msg := slack.WebhookMessage{Text: refusal, ResponseType: slack.ResponseTypeEphemeral}
+		if err := deliverWithFallback(client, callback.ResponseURL, callback.Channel.ID, callback.User.ID, &msg); err != nil {
+			logError("Error delivering refusal: %v", err)
+		}
+		return
+	}
+
+	// Shaking again must re-roll, so skip the answer cache.
+	req := askRequest{
+		EnterpriseID: callback.Enterprise.ID,
+		TeamID:       callback.Team.ID,
+		ChannelID:    callback.Channel.ID,
+		UserID:       callback.User.ID,
+		Question:     question,
+		Length:       again.Length,
+		Reroll:       true,
+	}
+	resp := ask(req)
+	log.Printf("Shake again Replying with: %s (index %d)", resp.Text, resp.Index)
+
+	msg := replyMessage(resp, req).webhookMessage()
+	msg.ReplaceOriginal = resp.answered()
+	if err := deliverWithFallback(client, callback.ResponseURL, callback.Channel.ID, callback.User.ID, msg); err != nil {
+		logError("Error delivering answer: %v", err)
+	}
+}
+
+// shareToChannel reposts a previewed answer publicly. Without a
+// response_url it posts to the channel through the Web API instead.
+func shareToChannel(client *slack.Client, callback *slack.InteractionCallback, action *slack.BlockAction) {
+	var shared sharedAnswer
+	if err := json.Unmarshal([]byte(action.Value), &shared); err != nil {
+		logError("Error decoding shared answer: %v", err)
+		return
+	}
+	log.Printf("Sharing answer to channel %s: %s", logID(callback.Channel.ID), shared.Answer)
+
+	msg := sharedMessage(callback.User.ID, callback.User.TZ, shared.Question, shared.response())
+	if callback.ResponseURL == "" {
+		log.Printf("No response_url to share to; posting to channel %s directly", logID(callback.Channel.ID))
+		options := []slack.MsgOption{slack.MsgOptionText(msg.Text, false)}
+		if len(msg.Blocks) > 0 {
+			options = append(options, slack.MsgOptionBlocks(msg.Blocks...))
+		}
+		if _, err := postMessage(client, callback.Channel.ID, options...); err != nil {
+			logError("Error posting shared answer: %v", err)
+		}
+		return
+	}
+	if err := postResponseURL(callback.ResponseURL, msg.webhookMessage()); err != nil {
+		logError("Error posting to response_url: %v", err)
+	}
+}
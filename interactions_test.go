@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// replyButtons returns the buttons of msg's action block by action ID.
+func replyButtons(t *testing.T, msg *Reply) map[string]*slack.ButtonBlockElement {
+	t.Helper()
+	buttons := map[string]*slack.ButtonBlockElement{}
+	for _, block := range msg.Blocks {
+		action, ok := block.(*slack.ActionBlock)
+		if !ok {
+			continue
+		}
+		for _, element := range action.Elements.ElementSet {
+			if button, ok := element.(*slack.ButtonBlockElement); ok {
+				buttons[button.ActionID] = button
+			}
+		}
+	}
+	return buttons
+}
+
+func TestReplyMessageButtons(t *testing.T) {
+	withConfig(t, nil)
+	answered := Response{Text: "It is certain", Index: 0, Category: categoryOf("It is certain")}
+
+	tests := []struct {
+		name      string
+		resp      Response
+		req       askRequest
+		wantAgain *shakeAgainRequest
+		wantShare bool
+	}{
+		{"answer", answered, askRequest{Question: "Will it rain?"}, &shakeAgainRequest{Question: "Will it rain?"}, true},
+		{"answer with length", answered, askRequest{Question: "Will it rain?", Length: lengthShort}, &shakeAgainRequest{Question: "Will it rain?", Length: lengthShort}, true},
+		{"session answer", answered, askRequest{Question: "Will it rain?", Session: "standup"}, nil, true},
+		{"refusal", Response{Text: replyNoQuestion, Index: -1}, askRequest{Question: "Will it rain"}, nil, false},
+		{"question too long", answered, askRequest{Question: strings.Repeat("a", maxButtonValue) + "?"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := replyMessage(tt.resp, tt.req)
+			buttons := replyButtons(t, msg)
+
+			again, ok := buttons[shakeAgainActionID]
+			if (tt.wantAgain != nil) != ok {
+				t.Fatalf("Shake again button present = %v, want %v", ok, tt.wantAgain != nil)
+			}
+			if ok {
+				if got := parseShakeAgain(again.Value); got != *tt.wantAgain {
+					t.Errorf("Shake again value = %+v, want %+v", got, *tt.wantAgain)
+				}
+			}
+			if _, ok := buttons[shareActionID]; ok != tt.wantShare {
+				t.Errorf("Share button present = %v, want %v", ok, tt.wantShare)
+			}
+			if len(buttons) == 0 && len(msg.Blocks) > 0 {
+				t.Errorf("reply without buttons has blocks %v", msg.Blocks)
+			}
+		})
+	}
+}
+
+func TestParseShakeAgain(t *testing.T) {
+	tests := []struct {
+		value string
+		want  shakeAgainRequest
+	}{
+		{`{"q":"Will it rain?"}`, shakeAgainRequest{Question: "Will it rain?"}},
+		{`{"q":"Will it rain?","length":"short"}`, shakeAgainRequest{Question: "Will it rain?", Length: "short"}},
+		{"Will it rain?", shakeAgainRequest{Question: "Will it rain?"}},
+	}
+	for _, tt := range tests {
+		if got := parseShakeAgain(tt.value); got != tt.want {
+			t.Errorf("parseShakeAgain(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// interactionBody is the form body Slack posts for a press of button
+// actionID carrying value.
+func interactionBody(t *testing.T, actionID, value string) string {
+	t.Helper()
+	payload, err := json.Marshal(map[string]any{
+		"type":         slack.InteractionTypeBlockActions,
+		"response_url": "https://hooks.slack.com/actions/T1/1/abc",
+		"team":         map[string]string{"id": "T1"},
+		"channel":      map[string]string{"id": "C1"},
+		"user":         map[string]string{"id": "U1"},
+		"actions":      []map[string]string{{"block_id": "b1", "action_id": actionID, "value": value}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return url.Values{"payload": {string(payload)}}.Encode()
+}
+
+func TestHandleInteractionShakeAgain(t *testing.T) {
+	c := withConfig(t, nil)
+	sent := captureOutbound(t)
+
+	body := interactionBody(t, shakeAgainActionID, `{"q":"Will it rain?"}`)
+	w := httptest.NewRecorder()
+	handleInteraction(w, signedRequest("/interactions", formContentType, body), testSecret, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	out := nextOutbound(t, sent)
+	if out.URL != "https://hooks.slack.com/actions/T1/1/abc" {
+		t.Errorf("posted to %s, want the response_url", out.URL)
+	}
+	var msg slack.WebhookMessage
+	if err := json.Unmarshal([]byte(out.Body), &msg); err != nil {
+		t.Fatalf("decoding posted message: %v", err)
+	}
+	if !msg.ReplaceOriginal {
+		t.Error("fresh answer doesn't replace the original")
+	}
+	if !slices.ContainsFunc(c.Responses, func(answer string) bool { return strings.Contains(msg.Text, answer) }) {
+		t.Errorf("posted %q, which holds no answer", msg.Text)
+	}
+}
+
+func TestHandleInteractionRejectsBadSignature(t *testing.T) {
+	withConfig(t, nil)
+	sent := captureOutbound(t)
+
+	r := signedRequest("/interactions", formContentType, interactionBody(t, shakeAgainActionID, "Will it rain?"))
+	r.Header.Set("X-Slack-Signature", "v0=00")
+	w := httptest.NewRecorder()
+	handleInteraction(w, r, testSecret, nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", w.Code)
+	}
+	select {
+	case out := <-sent:
+		t.Errorf("rejected interaction posted %s", out.Body)
+	default:
+	}
+}
+
+func TestSharePreviewPayload(t *testing.T) {
+	withConfig(t, nil)
+	resp := Response{Text: "It is certain.", Index: 0, Category: categoryYes, Reason: "The stars agree.", Note: "🎉 That's the 100th question!"}
+	msg := replyMessage(resp, askRequest{Question: "Will it <rain>?"})
+	if msg.ResponseType != "" {
+		t.Errorf("preview response type = %q, want ephemeral", msg.ResponseType)
+	}
+	button, ok := replyButtons(t, msg)[shareActionID]
+	if !ok {
+		t.Fatal("preview has no Share to channel button")
+	}
+	var shared sharedAnswer
+	if err := json.Unmarshal([]byte(button.Value), &shared); err != nil {
+		t.Fatalf("decoding the button value: %v", err)
+	}
+	want := sharedAnswer{Question: "Will it <rain>?", Answer: resp.Text, Reason: resp.Reason, Note: resp.Note}
+	if shared != want {
+		t.Errorf("button carries %+v, want %+v", shared, want)
+	}
+}
+
+func TestHandleInteractionShare(t *testing.T) {
+	withConfig(t, nil)
+	sent := captureOutbound(t)
+
+	value := `{"q":"Will it <rain>?","a":"It is certain.","r":"The stars agree."}`
+	w := httptest.NewRecorder()
+	handleInteraction(w, signedRequest("/interactions", formContentType, interactionBody(t, shareActionID, value)), testSecret, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	var msg slack.WebhookMessage
+	if err := json.Unmarshal([]byte(nextOutbound(t, sent).Body), &msg); err != nil {
+		t.Fatalf("decoding posted message: %v", err)
+	}
+	if msg.ResponseType != slack.ResponseTypeInChannel {
+		t.Errorf("response type = %q, want in_channel", msg.ResponseType)
+	}
+	for _, want := range []string{"<@U1> asked: Will it &lt;rain&gt;?", "It is certain.", "The stars agree."} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("shared %q, which lacks %q", msg.Text, want)
+		}
+	}
+}
+
+func TestShareToChannelWithoutResponseURL(t *testing.T) {
+	withConfig(t, nil)
+	client, calls := fakeSlack(t)
+	callback := &slack.InteractionCallback{
+		Channel: slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C1"}}},
+		User:    slack.User{ID: "U1"},
+	}
+	shareToChannel(client, callback, &slack.BlockAction{ActionID: shareActionID, Value: `{"q":"Will it rain?","a":"It is certain."}`})
+
+	call := nextOutbound(t, calls)
+	if call.URL != "chat.postMessage" {
+		t.Fatalf("called %s, want chat.postMessage", call.URL)
+	}
+	form, _ := url.ParseQuery(call.Body)
+	if form.Get("channel") != "C1" || !strings.Contains(form.Get("text"), "It is certain.") {
+		t.Errorf("posted %v", form)
+	}
+}
+
+func TestShareToChannelBadValue(t *testing.T) {
+	withConfig(t, nil)
+	sent := captureOutbound(t)
+	callback := &slack.InteractionCallback{ResponseURL: "https://hooks.slack.com/actions/1"}
+	shareToChannel(nil, callback, &slack.BlockAction{ActionID: shareActionID, Value: "not json"})
+	select {
+	case out := <-sent:
+		t.Errorf("a bad share value posted %s", out.Body)
+	default:
+	}
+}
+
+func TestFooterText(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 32, 59, 0, time.UTC)
+	if got, want := footerText(now, time.UTC), "asked via 🎱 at 14:32 UTC"; got != want {
+		t.Errorf("footerText = %q, want %q", got, want)
+	}
+	tokyo := time.FixedZone("JST", 9*60*60)
+	if got, want := footerText(now, tokyo), "asked via 🎱 at 23:32 JST"; got != want {
+		t.Errorf("footerText in Tokyo = %q, want %q", got, want)
+	}
+}
+
+// footerBlock returns the text of msg's context block, if it has one.
+func footerBlock(msg *Reply) (string, bool) {
+	for _, block := range msg.Blocks {
+		if context, ok := block.(*slack.ContextBlock); ok {
+			return context.ContextElements.Elements[0].(*slack.TextBlockObject).Text, true
+		}
+	}
+	return "", false
+}
+
+func TestSharedMessageFooter(t *testing.T) {
+	resp := Response{Text: "It is certain.", Category: categoryYes}
+	for _, show := range []bool{true, false} {
+		withConfig(t, map[string]string{"SHOW_FOOTER": strconv.FormatBool(show), "FOOTER_TZ": ""})
+		setClock(t, time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC))
+		msg := sharedMessage("U1", "", "Will it rain?", resp)
+		footer, ok := footerBlock(msg)
+		if ok != show {
+			t.Fatalf("SHOW_FOOTER=%v: footer present = %v", show, ok)
+		}
+		if show && footer != "asked via 🎱 at 14:32 UTC" {
+			t.Errorf("footer = %q, want the clock's time", footer)
+		}
+		if !strings.Contains(msg.Text, "It is certain.") || strings.Contains(msg.Text, "asked via") {
+			t.Errorf("SHOW_FOOTER=%v: text = %q", show, msg.Text)
+		}
+	}
+}
+
+func TestResolveUserTZ(t *testing.T) {
+	tests := []struct {
+		name      string
+		payloadTZ string
+		defaultTZ string
+		want      string
+	}{
+		{"provided", "Asia/Tokyo", "Europe/Berlin", "Asia/Tokyo"},
+		{"invalid falls back", "Mars/Olympus_Mons", "Europe/Berlin", "Europe/Berlin"},
+		{"missing falls back", "", "Europe/Berlin", "Europe/Berlin"},
+		{"default", "", "", "UTC"},
+		{"both invalid", "Nowhere/Here", "Nowhere/There", "UTC"},
+	}
+	for _, tt := range tests {
+		if got := resolveUserTZ(tt.payloadTZ, tt.defaultTZ); got.String() != tt.want {
+			t.Errorf("%s: resolveUserTZ(%q, %q) = %s, want %s", tt.name, tt.payloadTZ, tt.defaultTZ, got, tt.want)
+		}
+	}
+}
+
+func TestSharedMessageFooterTZ(t *testing.T) {
+	resp := Response{Text: "It is certain.", Category: categoryYes}
+	tests := []struct {
+		name     string
+		footerTZ string
+		userTZ   string
+		want     string
+	}{
+		{"user's zone", "Europe/Berlin", "Asia/Tokyo", "asked via 🎱 at 23:32 JST"},
+		{"invalid user zone", "Europe/Berlin", "Mars/Olympus_Mons", "asked via 🎱 at 16:32 CEST"},
+		{"FOOTER_TZ", "Europe/Berlin", "", "asked via 🎱 at 16:32 CEST"},
+		{"UTC", "", "", "asked via 🎱 at 14:32 UTC"},
+	}
+	for _, tt := range tests {
+		withConfig(t, map[string]string{"SHOW_FOOTER": "true", "FOOTER_TZ": tt.footerTZ})
+		setClock(t, time.Date(2024, 5, 1, 14, 32, 0, 0, time.UTC))
+		if footer, _ := footerBlock(sharedMessage("U1", tt.userTZ, "Will it rain?", resp)); footer != tt.want {
+			t.Errorf("%s: footer = %q, want %q", tt.name, footer, tt.want)
+		}
+	}
+	t.Setenv("FOOTER_TZ", "Mars/Olympus_Mons")
+	if _, err := loadConfig(); err == nil {
+		t.Error("FOOTER_TZ=Mars/Olympus_Mons loaded")
+	}
+}
+
+func TestBlockActionsWithoutResponseURL(t *testing.T) {
+	share := `{"q":"Will it rain?","a":"It is certain."}`
+	tests := []struct {
+		name     string
+		actionID string
+		value    string
+		wantCall string
+		wantLog  string
+	}{
+		{"shake again", shakeAgainActionID, `{"q":"Will it rain?"}`, "chat.postEphemeral", "No response_url to deliver to"},
+		{"share", shareActionID, share, "chat.postMessage", "No response_url to share to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withConfig(t, nil)
+			quickRetries(t)
+			logs := captureLog(t)
+			sent := captureOutbound(t)
+			client, calls := fakeSlack(t)
+
+			var callback slack.InteractionCallback
+			callback.Type = slack.InteractionTypeBlockActions
+			callback.Team.ID, callback.Channel.ID, callback.User.ID = "T1", "C1", "U1"
+			callback.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: tt.actionID, Value: tt.value}}
+			handleBlockActions(client, &callback)
+
+			call := nextOutbound(t, calls)
+			if call.URL != tt.wantCall {
+				t.Fatalf("called %s, want %s", call.URL, tt.wantCall)
+			}
+			form, _ := url.ParseQuery(call.Body)
+			if form.Get("channel") != "C1" {
+				t.Errorf("delivered to channel %q, want C1", form.Get("channel"))
+			}
+			text := form.Get("text")
+			if !slices.ContainsFunc(c.Responses, func(r string) bool { return strings.Contains(text, r) }) {
+				t.Errorf("delivered %q, want an answer", text)
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("log %q doesn't mention the fallback", logs)
+			}
+			select {
+			case req := <-sent:
+				t.Errorf("posted to %s without a response_url", req.URL)
+			default:
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import "slices"
+
+// Answer lengths for the --short and --long flags.
+const (
+	lengthNormal = "normal"
+	lengthShort  = "short"
+	lengthLong   = "long"
+)
+
+// shortResponses are terse answers for --short.
+var shortResponses = []string{
+	"Yes.",
+	"Definitely.",
+	"Sure.",
+	"Likely.",
+	"Unclear.",
+	"Later.",
+	"No.",
+	"Nope.",
+	"Doubtful.",
+}
+
+// longResponses are elaborate answers for --long.
+var longResponses = []string{
+	"The spirits have conferred at length, and their verdict is a resounding yes.",
+	"Every sign I can read points the same way: go ahead, it will work out.",
+	"All the omens are aligned in your favour; you may proceed with confidence.",
+	"The mists are swirling too thickly for a clear answer. Take a breath and ask me again.",
+	"I see many possible futures, and not one of them settles it. Ask again later.",
+	"The signs are unmistakable, I'm afraid: this is not the path to take.",
+	"I have looked long and hard into the murk, and the answer that comes back is no.",
+}
+
+// lengthFlag is the answer length flags ask for. --short wins if both it
+// and --long are given.
+func lengthFlag(flags map[string]bool) string {
+	switch {
+	case flags[flagShort]:
+		return lengthShort
+	case flags[flagLong]:
+		return lengthLong
+	}
+	return lengthNormal
+}
+
+// filterLengthList applies DISABLED_RESPONSES and, when FILTER_PROFANITY is
+// on, its words to a length list. Unlike the main list it may be left empty,
+// in which case that length gets the normal answers.
+func filterLengthList(resps, disabled, profanity []string) []string {
+	out := slices.DeleteFunc(slices.Clone(resps), func(text string) bool { return slices.Contains(disabled, text) })
+	if len(profanity) > 0 {
+		out = filterProfanity(out, profanity)
+	}
+	return out
+}
+
+// lengthResponses is the list answers of req.Length are picked from: the
+// filtered --short or --long list, or normal for lengthNormal. Overrides and
+// channel themes have no short or long variants, so where one applies normal
+// is kept as it is.
+func lengthResponses(req askRequest, normal []string) []string {
+	if req.Length != lengthShort && req.Length != lengthLong {
+		return normal
+	}
+	if overrideScope(req.EnterpriseID, req.TeamID) != "" {
+		return normal
+	}
+	if _, ok := channelThemes.get(req.ChannelID); ok {
+		return normal
+	}
+	if resps := currentResponses().Lengths[req.Length]; len(resps) > 0 {
+		return resps
+	}
+	return normal
+}
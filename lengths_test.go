@@ -0,0 +1,77 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLengthFlag(t *testing.T) {
+	tests := []struct {
+		flags map[string]bool
+		want  string
+	}{
+		{nil, lengthNormal},
+		{map[string]bool{flagPublic: true}, lengthNormal},
+		{map[string]bool{flagShort: true}, lengthShort},
+		{map[string]bool{flagLong: true}, lengthLong},
+		{map[string]bool{flagShort: true, flagLong: true}, lengthShort},
+	}
+	for _, tt := range tests {
+		if got := lengthFlag(tt.flags); got != tt.want {
+			t.Errorf("lengthFlag(%v) = %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestAskByLength(t *testing.T) {
+	overrides := writeFile(t, "overrides.json", `{"teams": {"TOVER": ["Team yes."]}}`)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		team    string
+		theme   string // set for the channel first, if any
+		length  string
+		want    func(c Config) []string
+		notWant []string
+	}{
+		{"short", nil, "T1", "", lengthShort, func(Config) []string { return shortResponses }, nil},
+		{"long", nil, "T1", "", lengthLong, func(Config) []string { return longResponses }, nil},
+		{"default", nil, "T1", "", lengthNormal, func(c Config) []string { return c.Responses }, nil},
+		{"unset", nil, "T1", "", "", func(c Config) []string { return c.Responses }, nil},
+		{"short with an override", map[string]string{"RESPONSE_OVERRIDES_FILE": overrides}, "TOVER", "", lengthShort, func(Config) []string { return []string{"Team yes."} }, nil},
+		{"long with a channel theme", nil, "T1", "pirate", lengthLong, func(c Config) []string { return c.Themes["pirate"] }, nil},
+		{"short without disabled", map[string]string{"DISABLED_RESPONSES": "Yes.,Sure."}, "T1", "", lengthShort, func(Config) []string { return shortResponses }, []string{"Yes.", "Sure."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withConfig(t, tt.env)
+			if tt.theme != "" {
+				if err := channelThemes.set("C1", tt.theme); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want := tt.want(c)
+			for range 100 {
+				resp := ask(askRequest{TeamID: tt.team, ChannelID: "C1", UserID: "U1", Question: "Will it rain?", Length: tt.length})
+				if !slices.Contains(want, resp.Text) || slices.Contains(tt.notWant, resp.Text) {
+					t.Fatalf("answered %q, want one of %v without %v", resp.Text, want, tt.notWant)
+				}
+				if resp.Category != categoryOf(resp.Text) {
+					t.Fatalf("answer %q has category %q", resp.Text, resp.Category)
+				}
+			}
+		})
+	}
+}
+
+func TestLengthListsFallBackWhenEmpty(t *testing.T) {
+	c := withConfig(t, map[string]string{"DISABLED_RESPONSES": strings.Join(shortResponses, ",")})
+	if got := currentResponses().Lengths[lengthShort]; len(got) != 0 {
+		t.Fatalf("short list = %v, want it emptied", got)
+	}
+	resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?", Length: lengthShort})
+	if !slices.Contains(c.Responses, resp.Text) {
+		t.Errorf("--short with no short answers = %q, want a normal one", resp.Text)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import "sync"
+
+// Replies once CHANNEL_DAILY_LIMIT, USER_DAILY_LIMIT or TEAM_RATE_LIMIT has
+// been reached.
+const (
+	replyDailyLimit     = "🎱 has answered enough here today."
+	replyUserDailyLimit = "🎱 You've consulted me enough today — back tomorrow."
+	replyTeamLimit      = "🎱 Your workspace has used up today's answers — back tomorrow."
+)
+
+// dailyCounter counts answers per channel, user or team for the current UTC
+// day.
+// Counts from earlier days are dropped as soon as a new day is seen.
+type dailyCounter struct {
+	mu     sync.Mutex
+	day    string
+	counts map[string]int
+}
+
+// channelAnswers, userAnswers and teamAnswers are shared by all requests.
+var (
+	channelAnswers = &dailyCounter{counts: make(map[string]int)}
+	userAnswers    = &dailyCounter{counts: make(map[string]int)}
+	teamAnswers    = &dailyCounter{counts: make(map[string]int)}
+)
+
+// roll starts a fresh count if day is not the day being counted. Callers
+// must hold c.mu.
+func (c *dailyCounter) roll(day string) {
+	if c.day != day {
+		c.day = day
+		clear(c.counts)
+	}
+}
+
+// add counts an answer for id on day.
+func (c *dailyCounter) add(id, day string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(day)
+	c.counts[id]++
+}
+
+// reserve counts an answer for id on day unless id already has limit,
+// reporting whether it did. Checking and counting under one lock means
+// concurrent requests can't both take the last answer.
+func (c *dailyCounter) reserve(id, day string, limit int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(day)
+	if c.counts[id] >= limit {
+		return false
+	}
+	c.counts[id]++
+	return true
+}
+
+// release takes back an answer reserved for id on day that wasn't given.
+func (c *dailyCounter) release(id, day string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.day == day && c.counts[id] > 0 {
+		c.counts[id]--
+	}
+}
+
+// forget drops id's count, reporting whether it had one.
+func (c *dailyCounter) forget(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.counts[id]
+	delete(c.counts, id)
+	return ok
+}
+
+// count returns how many answers id has had on day.
+func (c *dailyCounter) count(id, day string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roll(day)
+	return c.counts[id]
+}
+
+// utcDay is the UTC date of clock(), the day the daily limits count by.
+func utcDay() string {
+	return clock().UTC().Format("2006-01-02")
+}
+
+// channelLimitReached reports whether channelID has had the
+// CHANNEL_DAILY_LIMIT answers for today. Requests outside a channel, like
+// the webhook's, are never limited.
+func channelLimitReached(channelID string) bool {
+	if cfg.ChannelDailyLimit <= 0 || channelID == "" {
+		return false
+	}
+	return channelAnswers.count(channelID, utcDay()) >= cfg.ChannelDailyLimit
+}
+
+// userLimitReached reports whether userID has had the USER_DAILY_LIMIT
+// answers for today.
+func userLimitReached(userID string) bool {
+	if cfg.UserDailyLimit <= 0 || userID == "" {
+		return false
+	}
+	return userAnswers.count(userID, utcDay()) >= cfg.UserDailyLimit
+}
+
+// teamLimitReached reports whether teamID has had the TEAM_RATE_LIMIT
+// answers for today, across all of its users.
+func teamLimitReached(teamID string) bool {
+	if cfg.TeamRateLimit <= 0 || teamID == "" {
+		return false
+	}
+	return teamAnswers.count(teamID, utcDay()) >= cfg.TeamRateLimit
+}
+
+// answerSlots are the answers reserved towards today's limits for one ask.
+type answerSlots struct {
+	day                       string
+	channelID, userID, teamID string
+}
+
+// reserveAnswer reserves an answer in channelID, for userID and in teamID
+// towards today's CHANNEL_DAILY_LIMIT, USER_DAILY_LIMIT and TEAM_RATE_LIMIT,
+// returning the refusal if any of them has been reached, in which case
+// nothing is reserved. Requests outside a channel, like the webhook's, have
+// no channel or user to limit.
+func reserveAnswer(teamID, channelID, userID string) (answerSlots, string) {
+	slots := answerSlots{day: utcDay()}
+	if cfg.ChannelDailyLimit > 0 && channelID != "" {
+		if !channelAnswers.reserve(channelID, slots.day, cfg.ChannelDailyLimit) {
+			return answerSlots{}, replyDailyLimit
+		}
+		slots.channelID = channelID
+	}
+	if cfg.UserDailyLimit > 0 && userID != "" {
+		if !userAnswers.reserve(userID, slots.day, cfg.UserDailyLimit) {
+			slots.release()
+			return answerSlots{}, replyUserDailyLimit
+		}
+		slots.userID = userID
+	}
+	if cfg.TeamRateLimit > 0 && teamID != "" {
+		if !teamAnswers.reserve(teamID, slots.day, cfg.TeamRateLimit) {
+			slots.release()
+			return answerSlots{}, replyTeamLimit
+		}
+		slots.teamID = teamID
+	}
+	return slots, ""
+}
+
+// release gives back the reserved answers, for an ask that wasn't answered.
+func (s answerSlots) release() {
+	if s.channelID != "" {
+		channelAnswers.release(s.channelID, s.day)
+	}
+	if s.userID != "" {
+		userAnswers.release(s.userID, s.day)
+	}
+	if s.teamID != "" {
+		teamAnswers.release(s.teamID, s.day)
+	}
+}
+
+// askRefusal is the reply to userID asking in channelID of teamID when the
+// bot won't answer right now, or "" if it will. It lets each way of asking
+// refuse in its own way before doing any work; ask still reserves the answer
+// itself, so a limit can't be overrun between the two.
+func askRefusal(teamID, channelID, userID string) string {
+	switch {
+	case offHours():
+		return replyOffHours
+	case channelLimitReached(channelID):
+		return replyDailyLimit
+	case userLimitReached(userID):
+		return replyUserDailyLimit
+	case teamLimitReached(teamID):
+		return replyTeamLimit
+	}
+	return ""
+}
+
+// purge resets today's counts, returning how many were kept.
+func (c *dailyCounter) purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.counts)
+	clear(c.counts)
+	return n
+}
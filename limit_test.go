@@ -0,0 +1,234 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestChannelDailyLimit(t *testing.T) {
+	withConfig(t, map[string]string{"CHANNEL_DAILY_LIMIT": "2"})
+	// Just before midnight UTC, when the counts reset.
+	advance := fakeClock(t, time.Date(2024, 5, 1, 23, 58, 0, 0, time.UTC))
+	ask := func(channel string) string {
+		return ask(askRequest{TeamID: "T1", ChannelID: channel, UserID: "U1", Question: "Will it rain?"}).Text
+	}
+
+	for i := range 2 {
+		if got := ask("C1"); got == replyDailyLimit {
+			t.Fatalf("ask %d refused before the limit", i+1)
+		}
+	}
+	if got := ask("C1"); got != replyDailyLimit {
+		t.Errorf("third ask in C1 = %q, want %q", got, replyDailyLimit)
+	}
+	if got := ask("C2"); got == replyDailyLimit {
+		t.Error("C2 refused by C1's limit")
+	}
+	if got := ask(""); got == replyDailyLimit {
+		t.Error("ask outside a channel refused")
+	}
+
+	// The slash command refuses privately, before answering.
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+	if reply.Text != replyDailyLimit || reply.ResponseType == slack.ResponseTypeInChannel {
+		t.Errorf("/ask8ball over the limit = %q (%s), want the ephemeral refusal", reply.Text, reply.ResponseType)
+	}
+
+	advance(time.Minute)
+	if got := ask("C1"); got != replyDailyLimit {
+		t.Errorf("ask at 23:59 = %q, want the limit still", got)
+	}
+	advance(time.Minute)
+	if got := ask("C1"); got == replyDailyLimit {
+		t.Error("ask after midnight UTC refused")
+	}
+}
+
+func TestChannelDailyLimitSkipsRejected(t *testing.T) {
+	withConfig(t, map[string]string{"CHANNEL_DAILY_LIMIT": "1"})
+	// A rejected question isn't an answer, so it doesn't use up the day's.
+	if resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Why is it raining?"}); resp.answered() {
+		t.Fatalf("open question answered %q", resp.Text)
+	}
+	if got := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}); !got.answered() {
+		t.Errorf("first real question = %q, want an answer", got.Text)
+	}
+}
+
+// TestChannelDailyLimitConcurrent asks from many goroutines at once; exactly
+// the limit must be answered.
+func TestChannelDailyLimitConcurrent(t *testing.T) {
+	withConfig(t, map[string]string{"CHANNEL_DAILY_LIMIT": "5"})
+	var answered atomic.Int32
+	var wg sync.WaitGroup
+	for range 40 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}).answered() {
+				answered.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := answered.Load(); n != 5 {
+		t.Errorf("%d answered, want the limit of 5", n)
+	}
+}
+
+func TestChannelDailyLimitConfig(t *testing.T) {
+	t.Setenv("CHANNEL_DAILY_LIMIT", "plenty")
+	if _, err := loadConfig(); err == nil {
+		t.Error("CHANNEL_DAILY_LIMIT=plenty loaded")
+	}
+}
+
+func TestUserDailyLimit(t *testing.T) {
+	withConfig(t, map[string]string{"USER_DAILY_LIMIT": "2"})
+	advance := fakeClock(t, time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC))
+	ask := func(user, channel string) string {
+		return ask(askRequest{TeamID: "T1", ChannelID: channel, UserID: user, Question: "Will it rain?"}).Text
+	}
+
+	// The cap follows the user across channels.
+	for i, channel := range []string{"C1", "C2"} {
+		if got := ask("U1", channel); got == replyUserDailyLimit {
+			t.Fatalf("ask %d refused before the limit", i+1)
+		}
+	}
+	if got := ask("U1", "C3"); got != replyUserDailyLimit {
+		t.Errorf("third ask = %q, want %q", got, replyUserDailyLimit)
+	}
+	// Other users have their own count.
+	for i := range 2 {
+		if got := ask("U2", "C1"); got == replyUserDailyLimit {
+			t.Errorf("U2's ask %d refused by U1's limit", i+1)
+		}
+	}
+
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+	if reply.Text != replyUserDailyLimit || reply.ResponseType == slack.ResponseTypeInChannel {
+		t.Errorf("/ask8ball over the limit = %q (%s), want the ephemeral refusal", reply.Text, reply.ResponseType)
+	}
+
+	advance(30 * time.Minute)
+	if got := ask("U1", "C1"); got == replyUserDailyLimit {
+		t.Error("ask after midnight UTC refused")
+	}
+}
+
+func TestDailyLimitsCombine(t *testing.T) {
+	withConfig(t, map[string]string{"USER_DAILY_LIMIT": "1", "CHANNEL_DAILY_LIMIT": "1"})
+	ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"})
+	// U1 hit the channel's limit first, so U2 in C1 gets the channel's reply
+	// and U1 elsewhere gets their own.
+	if got := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U2", Question: "Will it rain?"}).Text; got != replyDailyLimit {
+		t.Errorf("U2 in C1 = %q, want %q", got, replyDailyLimit)
+	}
+	if got := ask(askRequest{TeamID: "T1", ChannelID: "C2", UserID: "U1", Question: "Will it rain?"}).Text; got != replyUserDailyLimit {
+		t.Errorf("U1 in C2 = %q, want %q", got, replyUserDailyLimit)
+	}
+	// A refusal by one limit doesn't use up the others.
+	if got := ask(askRequest{TeamID: "T1", ChannelID: "C2", UserID: "U2", Question: "Will it rain?"}); !got.answered() {
+		t.Errorf("U2 in C2 = %q, want an answer", got.Text)
+	}
+}
+
+// TestTeamRateLimitConcurrent asks from many users of two teams at once:
+// the first team's cap is shared by its users, and the second team's users
+// are held only by their own.
+func TestTeamRateLimitConcurrent(t *testing.T) {
+	withConfig(t, map[string]string{"TEAM_RATE_LIMIT": "10", "USER_DAILY_LIMIT": "2"})
+	var mu sync.Mutex
+	replies := map[string]map[string]int{"T1": {}, "T2": {}}
+	var wg sync.WaitGroup
+	for _, team := range []string{"T1", "T2"} {
+		users := 20
+		if team == "T2" {
+			users = 4
+		}
+		for i := range users {
+			for range 3 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp := ask(askRequest{TeamID: team, ChannelID: "C" + team, UserID: team + "U" + strconv.Itoa(i), Question: "Will it rain?"})
+					reply := resp.Text
+					if resp.answered() {
+						reply = "answered"
+					}
+					mu.Lock()
+					replies[team][reply]++
+					mu.Unlock()
+				}()
+			}
+		}
+	}
+	wg.Wait()
+	if got := replies["T1"]; got["answered"] != 10 || got[replyTeamLimit]+got[replyUserDailyLimit] != 50 || got[replyTeamLimit] == 0 {
+		t.Errorf("T1 replies = %v, want the team's 10 answers and the rest refused", got)
+	}
+	// Each of T2's 4 users gets their 2 answers.
+	if got := replies["T2"]; got["answered"] != 8 || got[replyUserDailyLimit] != 4 || got[replyTeamLimit] != 0 {
+		t.Errorf("T2 replies = %v, want 8 answers and only user refusals", got)
+	}
+
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", TeamID: "T1", ChannelID: "CT1", UserID: "T1U99"})
+	if reply.Text != replyTeamLimit || reply.ResponseType == slack.ResponseTypeInChannel {
+		t.Errorf("/ask8ball over the team limit = %q (%s), want the ephemeral refusal", reply.Text, reply.ResponseType)
+	}
+}
+
+// TestTeamRateLimitReserveConcurrent reserves answers for many users of two
+// teams at once: exactly the first team's cap is granted across its users,
+// and the second team, asking just as hard, isn't held by it.
+func TestTeamRateLimitReserveConcurrent(t *testing.T) {
+	withConfig(t, map[string]string{"TEAM_RATE_LIMIT": "10"})
+	var mu sync.Mutex
+	reserved := map[string]int{}
+	refused := map[string]map[string]int{"T1": {}, "T2": {}}
+	var wg sync.WaitGroup
+	// T2 asks for exactly its cap, so it should never be refused.
+	for team, users := range map[string]int{"T1": 30, "T2": 10} {
+		for i := range users {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				user := team + "U" + strconv.Itoa(i)
+				_, refusal := reserveAnswer(team, "C"+user, user)
+				mu.Lock()
+				defer mu.Unlock()
+				if refusal != "" {
+					refused[team][refusal]++
+					return
+				}
+				reserved[team]++
+			}()
+		}
+	}
+	wg.Wait()
+	if reserved["T1"] != 10 || refused["T1"][replyTeamLimit] != 20 {
+		t.Errorf("T1 reserved %d and was refused %v, want 10 and 20 team refusals", reserved["T1"], refused["T1"])
+	}
+	if reserved["T2"] != 10 || len(refused["T2"]) != 0 {
+		t.Errorf("T2 reserved %d and was refused %v, want 10 and none", reserved["T2"], refused["T2"])
+	}
+	if !teamLimitReached("T1") || teamLimitReached("T3") {
+		t.Error("teamLimitReached doesn't follow the reservations")
+	}
+}
+
+func TestTeamRateLimitConfig(t *testing.T) {
+	if c := withConfig(t, map[string]string{"TEAM_RATE_LIMIT": "7"}); c.TeamRateLimit != 7 {
+		t.Errorf("TeamRateLimit = %d, want 7", c.TeamRateLimit)
+	}
+	t.Setenv("TEAM_RATE_LIMIT", "lots")
+	if _, err := loadConfig(); err == nil {
+		t.Error("TEAM_RATE_LIMIT=lots loaded")
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/slack-go/slack"
+)
+
+// commandFields formats where a slash command came from as key=value pairs
+// for log lines. With ANONYMIZE_LOGS the IDs are replaced by short hashes,
+// which still tell channels apart, and the channel name is left out.
+func commandFields(cmd slack.SlashCommand) string {
+	if cfg.AnonymizeLogs {
+		return fmt.Sprintf("command=%s team_id=%s channel_id=%s channel_name=%s",
+			commandName(cmd), anonymize(cmd.TeamID), anonymize(cmd.ChannelID), redacted)
+	}
+	return fmt.Sprintf("command=%s team_id=%s channel_id=%s channel_name=%q",
+		commandName(cmd), cmd.TeamID, cmd.ChannelID, cmd.ChannelName)
+}
+
+// debugCommandFields formats every parsed field of cmd that
+// DEBUG_LOG_BODY logs. Tokens are never included, and with ANONYMIZE_LOGS
+// the IDs are hashed and the names and question text left out.
+func debugCommandFields(cmd slack.SlashCommand) string {
+	if cfg.AnonymizeLogs {
+		return fmt.Sprintf("command=%s text=%s team_id=%s enterprise_id=%s channel_id=%s user_id=%s user_name=%s trigger_id=%t response_url=%t api_app_id=%s",
+			commandName(cmd), redacted, anonymize(cmd.TeamID), anonymize(cmd.EnterpriseID), anonymize(cmd.ChannelID),
+			anonymize(cmd.UserID), redacted, cmd.TriggerID != "", cmd.ResponseURL != "", cmd.APIAppID)
+	}
+	return fmt.Sprintf("command=%s text=%q team_id=%s enterprise_id=%s channel_id=%s channel_name=%q user_id=%s user_name=%q trigger_id=%t response_url=%t api_app_id=%s",
+		commandName(cmd), cmd.Text, cmd.TeamID, cmd.EnterpriseID, cmd.ChannelID, cmd.ChannelName,
+		cmd.UserID, cmd.UserName, cmd.TriggerID != "", cmd.ResponseURL != "", cmd.APIAppID)
+}
+
+// logCommandBody logs cmd's parsed fields at debug level when DEBUG_LOG_BODY
+// is on, to help diagnose payloads that don't parse as expected. It logs
+// what was parsed rather than the raw signed body.
+func logCommandBody(cmd slack.SlashCommand) {
+	if liveFlags().DebugLogBody {
+		logDebug("parsed slash command %s", debugCommandFields(cmd))
+	}
+}
+
+// logID is a team, channel or user ID as log lines show it: hashed with
+// ANONYMIZE_LOGS, as it is otherwise.
+func logID(id string) string {
+	if cfg.AnonymizeLogs {
+		return anonymize(id)
+	}
+	return id
+}
+
+// anonymize replaces an ID with a short, stable hash of it.
+func anonymize(id string) string {
+	if id == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(id))
+	return "anon-" + hex.EncodeToString(sum[:4])
+}
+
+// logLevel is the minimum level logged, from LOG_LEVEL. It is a LevelVar so
+// a reload can change it while requests are logging.
+var logLevel = new(slog.LevelVar)
+
+// parseLogLevel parses LOG_LEVEL: debug, info, warn or error.
+func parseLogLevel(raw string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(raw)); err != nil {
+		return 0, fmt.Errorf("invalid LOG_LEVEL %q: want debug, info, warn or error", raw)
+	}
+	return level, nil
+}
+
+// samplingHandler passes one in every rate records below slog.LevelWarn to
+// the next handler, and every warning and error. The rate may change while
+// it is in use; 1 passes everything.
+type samplingHandler struct {
+	next slog.Handler
+	rate *atomic.Uint64
+	seen *atomic.Uint64
+}
+
+func newSamplingHandler(next slog.Handler) *samplingHandler {
+	h := &samplingHandler{next: next, rate: new(atomic.Uint64), seen: new(atomic.Uint64)}
+	h.rate.Store(1)
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler, dropping routine records while sampling.
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if rate := h.rate.Load(); r.Level < slog.LevelWarn && rate > 1 && (h.seen.Add(1)-1)%rate != 0 {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler. The derived handler samples together
+// with h.
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), rate: h.rate, seen: h.seen}
+}
+
+// WithGroup implements slog.Handler. The derived handler samples together
+// with h.
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), rate: h.rate, seen: h.seen}
+}
+
+// logSampler is the handler installed by setupLogging.
+var logSampler *samplingHandler
+
+// setupLogging makes slog, at LOG_LEVEL, the destination of every log line.
+// Lines from the standard log package are logged at info level.
+func setupLogging(w io.Writer, level slog.Level) {
+	logLevel.Set(level)
+	logSampler = newSamplingHandler(slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevel}))
+	slog.SetDefault(slog.New(logSampler))
+}
+
+// sampleLogs starts keeping only one in rate routine log lines when
+// LOG_SAMPLE_RATE is above 1. Warnings and errors are always logged.
+func sampleLogs(rate int) {
+	if rate <= 1 || logSampler == nil {
+		return
+	}
+	log.Printf("Logging 1 in %d routine lines (LOG_SAMPLE_RATE); warnings and errors are always logged", rate)
+	logSampler.rate.Store(uint64(rate))
+}
+
+// logAt logs a formatted message at level through slog.
+func logAt(level slog.Level, format string, args ...any) {
+	slog.Default().Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+// logError logs a failure. LOG_SAMPLE_RATE never drops it.
+func logError(format string, args ...any) {
+	logAt(slog.LevelError, format, args...)
+}
+
+// logWarn logs a problem that didn't stop the request, or a refused one.
+// LOG_SAMPLE_RATE never drops it.
+func logWarn(format string, args ...any) {
+	logAt(slog.LevelWarn, format, args...)
+}
+
+// logDebug logs detail that is only wanted at LOG_LEVEL=debug.
+func logDebug(format string, args ...any) {
+	logAt(slog.LevelDebug, format, args...)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestCommandFields(t *testing.T) {
+	cmd := slack.SlashCommand{Command: "/ask8ball", TeamID: "T1", ChannelID: "C1", ChannelName: "general"}
+	tests := []struct {
+		anonymize string
+		want      string
+	}{
+		{"false", `command=/ask8ball team_id=T1 channel_id=C1 channel_name="general"`},
+		{"true", "command=/ask8ball team_id=" + anonymize("T1") + " channel_id=" + anonymize("C1") + " channel_name=[redacted]"},
+	}
+	for _, tt := range tests {
+		withConfig(t, map[string]string{"ANONYMIZE_LOGS": tt.anonymize})
+		if got := commandFields(cmd); got != tt.want {
+			t.Errorf("ANONYMIZE_LOGS=%s: commandFields = %q, want %q", tt.anonymize, got, tt.want)
+		}
+	}
+}
+
+func TestAnswerLogHasRequestFields(t *testing.T) {
+	cmd := slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", TeamID: "T1", ChannelID: "C1", ChannelName: "general", UserID: "U1"}
+	tests := []struct {
+		anonymize string
+		want      []string
+		notWant   []string
+	}{
+		{"false", []string{"command=/ask8ball", "team_id=T1", "channel_id=C1", `channel_name="general"`}, nil},
+		{"true", []string{"command=/ask8ball", "team_id=anon-", "channel_id=anon-", "channel_name=[redacted]"}, []string{"T1", "C1", "general"}},
+	}
+	for _, tt := range tests {
+		withConfig(t, map[string]string{"ANONYMIZE_LOGS": tt.anonymize})
+		logs := captureLog(t)
+		askCommand(cmd)
+		line := logs.String()
+		for _, want := range tt.want {
+			if !strings.Contains(line, want) {
+				t.Errorf("ANONYMIZE_LOGS=%s: log %q lacks %s", tt.anonymize, line, want)
+			}
+		}
+		for _, bad := range tt.notWant {
+			if strings.Contains(line, bad) {
+				t.Errorf("ANONYMIZE_LOGS=%s: log %q gives away %s", tt.anonymize, line, bad)
+			}
+		}
+	}
+}
+
+// countLevels counts the lines at each level in text handler output.
+func countLevels(out string) map[string]int {
+	counts := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if _, rest, ok := strings.Cut(line, "level="); ok {
+			level, _, _ := strings.Cut(rest, " ")
+			counts[level]++
+		}
+	}
+	return counts
+}
+
+func TestSamplingHandler(t *testing.T) {
+	tests := []struct {
+		rate     uint64
+		wantInfo int
+	}{
+		{1, 1000},
+		{10, 100},
+		{7, 143},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		h := newSamplingHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		h.rate.Store(tt.rate)
+		logger := slog.New(h)
+		// A derived logger samples in step with the one it came from.
+		derived := logger.With("request", "r1")
+		for i := range 1000 {
+			if i%2 == 0 {
+				logger.Info("routine")
+			} else {
+				derived.Info("routine")
+			}
+			if i%50 == 0 {
+				logger.Error("failure")
+				derived.Warn("problem")
+			}
+		}
+		counts := countLevels(buf.String())
+		if counts["INFO"] != tt.wantInfo {
+			t.Errorf("rate %d: %d info lines of 1000, want %d", tt.rate, counts["INFO"], tt.wantInfo)
+		}
+		if counts["ERROR"] != 20 || counts["WARN"] != 20 {
+			t.Errorf("rate %d: %d errors and %d warnings, want all 20 of each", tt.rate, counts["ERROR"], counts["WARN"])
+		}
+	}
+}
+
+// TestSamplingHandlerConcurrent logs from many goroutines; the sample is
+// still exact, and -race checks the counters.
+func TestSamplingHandlerConcurrent(t *testing.T) {
+	var buf lockedBuffer
+	h := newSamplingHandler(slog.NewTextHandler(&buf, nil))
+	h.rate.Store(10)
+	logger := slog.New(h)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				logger.Info("routine")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := countLevels(buf.String())["INFO"]; n != 100 {
+		t.Errorf("%d info lines of 1000, want 100", n)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSampleLogs(t *testing.T) {
+	old := logSampler
+	t.Cleanup(func() { logSampler = old })
+	logSampler = newSamplingHandler(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range []struct {
+		rate int
+		want uint64
+	}{
+		{0, 1},
+		{1, 1},
+		{10, 10},
+	} {
+		logSampler.rate.Store(1)
+		sampleLogs(tt.rate)
+		if got := logSampler.rate.Load(); got != tt.want {
+			t.Errorf("sampleLogs(%d): rate = %d, want %d", tt.rate, got, tt.want)
+		}
+	}
+	t.Setenv("LOG_SAMPLE_RATE", "often")
+	if _, err := loadConfig(); err == nil {
+		t.Error("LOG_SAMPLE_RATE=often loaded")
+	}
+}
+
+func TestDebugLogBody(t *testing.T) {
+	form := url.Values{
+		"command": {"/ask8ball"}, "text": {"Will it rain?"}, "team_id": {"T1"}, "channel_id": {"C1"},
+		"channel_name": {"general"}, "user_id": {"U1"}, "user_name": {"ada"}, "token": {"legacy-token"},
+		"trigger_id": {"tr-1"}, "response_url": {"https://hooks.slack.test/r"}, "api_app_id": {"A1"},
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    []string
+		notWant []string
+	}{
+		{"disabled", map[string]string{"DEBUG_LOG_BODY": "false"}, nil, []string{"parsed slash command"}},
+		{"enabled", map[string]string{"DEBUG_LOG_BODY": "true"}, []string{
+			"level=DEBUG msg=\"parsed slash command command=/ask8ball", `text=\"Will it rain?\"`, "team_id=T1", "channel_id=C1",
+			`channel_name=\"general\"`, "user_id=U1", `user_name=\"ada\"`, "trigger_id=true", "response_url=true", "api_app_id=A1",
+		}, []string{"legacy-token", "hooks.slack.test"}},
+		{"anonymized", map[string]string{"DEBUG_LOG_BODY": "true", "ANONYMIZE_LOGS": "true"}, []string{
+			"parsed slash command command=/ask8ball", "text=[redacted]", "user_id=" + anonymize("U1"), "user_name=[redacted]",
+		}, []string{"Will it rain", "U1 ", "general", "ada", "legacy-token"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			logs := captureDebugLog(t)
+			if _, ok := verifyCommand(httptest.NewRecorder(), signedRequest("/ask8ball", formContentType, form.Encode()), testSecret); !ok {
+				t.Fatal("verifyCommand refused the command")
+			}
+			// Socket Mode commands are logged the same way.
+			socketCommandReply(context.Background(), socketCommand(slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", UserID: "U1", UserName: "ada", ChannelName: "general"}))
+			line := logs.String()
+			if n := strings.Count(line, "parsed slash command"); len(tt.want) > 0 && n != 2 {
+				t.Errorf("logged %d parsed commands, want one for each transport", n)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(line, want) {
+					t.Errorf("log %q lacks %s", line, want)
+				}
+			}
+			for _, bad := range tt.notWant {
+				if strings.Contains(line, bad) {
+					t.Errorf("log %q gives away %s", line, bad)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// luckyUsage explains the set-lucky subcommand.
+const luckyUsage = "Usage: /ask8ball set-lucky <number>, or /ask8ball set-lucky off"
+
+// luckyRequest reports whether text is "set-lucky ...", returning what
+// follows it.
+func luckyRequest(text string) (arg string, ok bool) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 || fields[0] != "set-lucky" {
+		return "", false
+	}
+	return strings.Join(fields[1:], " "), true
+}
+
+// luckyCommand sets or clears the caller's lucky number.
+func luckyCommand(cmd slack.SlashCommand, arg string) *Reply {
+	if arg == "off" {
+		users.setLucky(cmd.UserID, 0, false, clock())
+		return &Reply{Text: "🎱 Lucky number cleared. The 8-ball is back to pure chance."}
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return &Reply{Text: luckyUsage}
+	}
+	users.setLucky(cmd.UserID, n, true, clock())
+	log.Printf("User %s set a lucky number %s", logID(cmd.UserID), commandFields(cmd))
+	return &Reply{Text: fmt.Sprintf("🎱 Your lucky number is now %d. The same question will bring the same fortune.", n)}
+}
+
+// luckyRand is the picker for a user with lucky number n asking question:
+// seeded from both, so the same question gets the same answer each time.
+func luckyRand(n int, question string) *rand.Rand {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d\x00%s", n, strings.ToLower(normalizeText(question)))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// pickerFor is the picker for userID asking question: their lucky one, if
+// they set a number, or the shared rng.
+func pickerFor(userID, question string) *rand.Rand {
+	if n, ok := users.lucky(userID); ok {
+		return luckyRand(n, question)
+	}
+	return rng
+}
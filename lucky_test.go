@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestLuckyCommand(t *testing.T) {
+	withConfig(t, nil)
+	steps := []struct {
+		text      string
+		want      string
+		wantLucky int
+		wantSet   bool
+	}{
+		{"set-lucky 7", "Your lucky number is now 7.", 7, true},
+		{"SET-LUCKY  42 ", "Your lucky number is now 42.", 42, true},
+		{"set-lucky seven", luckyUsage, 42, true},
+		{"set-lucky", luckyUsage, 42, true},
+		{"set-lucky off", "Lucky number cleared.", 0, false},
+	}
+	for _, step := range steps {
+		reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: step.text, TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+		if !strings.Contains(reply.Text, step.want) {
+			t.Errorf("%q: reply %q lacks %q", step.text, reply.Text, step.want)
+		}
+		if n, ok := users.lucky("U1"); n != step.wantLucky || ok != step.wantSet {
+			t.Errorf("after %q: lucky = %d, %v; want %d, %v", step.text, n, ok, step.wantLucky, step.wantSet)
+		}
+	}
+}
+
+func TestLuckyAnswersReproducible(t *testing.T) {
+	withConfig(t, nil)
+	users.setLucky("U1", 7, true, clock())
+	users.setLucky("U2", 7, true, clock())
+	questions := []string{"Will it rain?", "Should I get lunch?", "Is today the day?", "Will the build pass?"}
+	for _, q := range questions {
+		first := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: q}).Text
+		for range 5 {
+			if got := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: q}).Text; got != first {
+				t.Errorf("%q asked again = %q, want %q", q, got, first)
+			}
+		}
+		// The seed is the number and the question, not who asks or how.
+		if got := ask(askRequest{TeamID: "T1", ChannelID: "C2", UserID: "U2", Question: "  " + strings.ToUpper(q)}).Text; got != first {
+			t.Errorf("%q for another user with 7 = %q, want %q", q, got, first)
+		}
+	}
+}
+
+func TestLuckyNumbersDiffer(t *testing.T) {
+	withConfig(t, nil)
+	differ := false
+	for n := 1; n <= 20 && !differ; n++ {
+		a := luckyRand(n, "Will it rain?").Intn(1000)
+		b := luckyRand(n+1, "Will it rain?").Intn(1000)
+		differ = a != b
+	}
+	if !differ {
+		t.Error("every lucky number picks the same")
+	}
+	if luckyRand(7, "Will it rain?").Int63() == luckyRand(7, "Will it snow?").Int63() {
+		t.Error("lucky 7 seeds two questions the same")
+	}
+}
+
+func TestPickerWithoutLuckyNumber(t *testing.T) {
+	withConfig(t, nil)
+	seedRNG(t, 1)
+	if pickerFor("U1", "Will it rain?") != rng {
+		t.Error("user without a lucky number doesn't use the shared rng")
+	}
+	users.setLucky("U1", 7, true, clock())
+	if pickerFor("U1", "Will it rain?") == rng {
+		t.Error("user with a lucky number uses the shared rng")
+	}
+
+	// Without a number, answers follow the shared rng, so they vary.
+	users.setLucky("U1", 0, false, clock())
+	seen := map[string]bool{}
+	for range 50 {
+		seen[ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}).Text] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("50 asks without a lucky number gave %d answers", len(seen))
+	}
+}
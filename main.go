@@ -1,14 +1,20 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
-	"math/rand"
+	"net"
 	"net/http"
 	"os"
-	"regexp"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/slack-go/slack"
@@ -39,113 +45,308 @@ var magicResponse = []string{
 }
 
 func main() {
-	// Load environment variables
-	botToken := os.Getenv("SLACK_BOT_TOKEN")
-	signingSecret := os.Getenv("SLACK_SIGNING_SECRET")
+	// Load and validate configuration from the environment
+	var err error
+	cfg, err = loadConfig()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	setupLogging(os.Stderr, cfg.LogLevel)
+	liveResponses.Store(newResponseSet(cfg))
+	if cfg.RandomSeed != nil {
+		rng = newLockedRand(*cfg.RandomSeed)
+		logWarn("RANDOM_SEED=%d is set; answers follow a fixed, repeatable sequence", *cfg.RandomSeed)
+	}
+
+	if cfg.SelectionMode == selectionDecay {
+		answerUsage = newDecayCounter(cfg.DecayHalfLife)
+	}
 
-	// Validate environment variables
-	if botToken == "" || signingSecret == "" {
-		log.Fatal("Error: SLACK_BOT_TOKEN and SLACK_SIGNING_SECRET must be set")
+	// Subcommands run locally without Slack
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
 	}
 
+	if err := checkWrites(&cfg); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := startupChecks(cfg); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	signingSecret := cfg.SigningSecret
+	log.Printf("Using %s", cfg.Provenance)
+
 	// Initialize Slack client
-	client := slack.New(botToken)
+	options := []slack.Option{slack.OptionHTTPClient(httpClient)}
+	if cfg.SocketMode {
+		options = append(options, slack.OptionAppLevelToken(cfg.AppToken))
+	}
+	client := slack.New(cfg.BotToken, options...)
+
+	if cfg.Modals {
+		modals = client
+	}
+	if cfg.AnswerCacheTTL > 0 {
+		answerCache = newTTLCache(cfg.AnswerCacheTTL)
+	}
+	if cfg.ConsensusWindow > 0 {
+		consensusCache = newTTLCache(cfg.ConsensusWindow)
+	}
+	if cfg.ChannelRecentAnswers > 0 {
+		channelRecent = newRecentAnswers(cfg.ChannelRecentAnswers)
+	}
+	if cfg.AuditFile != "" {
+		if audit, err = openAuditLog(cfg.AuditFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+
+	startedAt = clock()
+
+	// Register the HTTP handlers and tell the operator how to wire them up
+	mux := http.NewServeMux()
+	rs := routes(signingSecret, client)
+	registerRoutes(mux, rs)
+	if !cfg.SocketMode {
+		logStartupSummary(cfg.Addr, rs)
+	}
+
+	// Background work and the server both stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go pruneUsers(ctx, cfg.UserPruneInterval)
+	go reloadOnHangup(ctx)
+	if cfg.SelfDestructTTL > 0 {
+		selfDestructs = newSelfDestructor(ctx, cfg.SelfDestructTTL)
+	}
+
+	// Startup logs are complete by now; sample the per-request ones
+	sampleLogs(cfg.LogSampleRate)
+
+	if cfg.SocketMode {
+		// Slack reaches us over the socket, so there is no HTTP server
+		log.Printf("Starting in Socket Mode")
+		runSocketMode(ctx, client)
+		log.Printf("Shutting down")
+	} else {
+		log.Printf("Starting HTTP server on %s", cfg.Addr)
+		ln, err := listen(cfg.Addr)
+		if err != nil {
+			log.Fatalf("Error starting HTTP server: %v", err)
+		}
+		srv := &http.Server{Addr: cfg.Addr, Handler: trackInFlight(mux)}
+		errc := make(chan error, 1)
+		go func() { errc <- srv.Serve(ln) }()
+		select {
+		case err := <-errc:
+			log.Fatalf("Error serving HTTP: %v", err)
+		case <-ctx.Done():
+		}
+
+		// Let in-flight requests finish before exiting, up to a point
+		log.Printf("Shutting down")
+		if err := drain(srv, cfg.ShutdownTimeout); err != nil {
+			logError("Error shutting down HTTP server: %v", err)
+		}
+	}
+	if selfDestructs != nil {
+		selfDestructs.wait()
+	}
+	if audit != nil {
+		if err := audit.close(); err != nil {
+			logError("Error closing audit log: %v", err)
+		}
+	}
+}
 
-	// Seed the random number generator
-	rand.Seed(time.Now().UnixNano())
+// inFlight counts requests being served, for reporting at shutdown.
+var inFlight atomic.Int64
 
-	// Set up the HTTP handler for /ask8ball
-	http.HandleFunc("/ask8ball", func(w http.ResponseWriter, r *http.Request) {
-		handleSlashCommand(w, r, signingSecret, client)
+// trackInFlight wraps h to maintain inFlight.
+func trackInFlight(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		h.ServeHTTP(w, r)
 	})
+}
 
-	// Get the port from environment variable, default to 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// drain shuts srv down gracefully, waiting up to timeout for in-flight
+// requests. After that it closes the remaining connections and logs how
+// many requests were abandoned.
+func drain(srv *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		logWarn("Shutdown timed out after %v; abandoning %d in-flight requests", timeout, inFlight.Load())
+		return srv.Close()
 	}
+	return err
+}
 
-	log.Printf("Starting HTTP server on :%s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Error starting HTTP server: %v", err)
+// listen binds addr, turning the common "port already in use" failure into
+// an actionable message.
+func listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return nil, fmt.Errorf("port %s is already in use; set PORT to a free port", strings.TrimPrefix(addr, ":"))
 	}
+	return ln, err
 }
 
-// handleSlashCommand processes the /ask8ball slash command
-func handleSlashCommand(w http.ResponseWriter, r *http.Request, signingSecret string, client *slack.Client) {
+// verifyRequest reads the request body and checks Slack's signature over it.
+// On failure it writes the error response itself and returns ok=false.
+func verifyRequest(w http.ResponseWriter, r *http.Request, signingSecret string) (body []byte, ok bool) {
 	if r.Method != http.MethodPost {
+		rejectedTotal.WithLabelValues(rejectMethod).Inc()
+		logWarn("Rejecting %s request to %s", r.Method, r.URL.Path)
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+		return nil, false
 	}
 
 	// Read the request body
-	body, err := io.ReadAll(r.Body)
+	body, err := readBody(r)
 	if err != nil {
-		log.Printf("Error reading request body: %v", err)
+		logError("Error reading request body: %v", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
-		return
+		return nil, false
+	}
+
+	// Say exactly which header is missing; this is the usual mistake when
+	// testing with curl and a failed signature check says little.
+	normalizeSlackHeaders(r.Header)
+	for _, h := range slackSignedHeaders {
+		if r.Header.Get(h) == "" {
+			rejectedTotal.WithLabelValues(rejectMissingHeader).Inc()
+			logWarn("Rejecting request without %s header", h)
+			writeJSONError(w, http.StatusBadRequest, "missing "+h+" header")
+			return nil, false
+		}
 	}
-	defer r.Body.Close()
 
 	// Verify the request signature
-	sv, err := slack.NewSecretsVerifier(r.Header, signingSecret)
-	if err != nil {
-		log.Printf("Error creating secrets verifier: %v", err)
+	err = verifySignature(r.Header, body, signingSecret, cfg.SignatureTolerance, clock())
+	if errors.Is(err, errBadTimestamp) {
+		rejectedTotal.WithLabelValues(rejectStaleTimestamp).Inc()
+		logError("Error verifying signature: %v", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
-		return
+		return nil, false
 	}
-
-	if _, err := sv.Write(body); err != nil {
-		log.Printf("Error writing to secrets verifier: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	if err != nil {
+		rejectedTotal.WithLabelValues(rejectBadSignature).Inc()
+		logError("Error verifying signature: %v", err)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
 	}
 
-	if err := sv.Ensure(); err != nil {
-		log.Printf("Error verifying signature: %v", err)
-		http.Error(w, "Forbidden", http.StatusForbidden)
-		return
+	return body, true
+}
+
+// verifyCommand verifies a slash command request and parses its payload,
+// writing the error response itself on failure.
+//
+// With STRICT_CONTENT_TYPE, the default, a POST of a content type commands
+// can't have is refused with 415 before its body is read.
+func verifyCommand(w http.ResponseWriter, r *http.Request, signingSecret string) (slack.SlashCommand, bool) {
+	if r.Method == http.MethodPost && cfg.StrictContentType && !commandContentTypeOK(r) {
+		rejectedTotal.WithLabelValues(rejectContentType).Inc()
+		logWarn("Rejecting slash command with Content-Type %q", r.Header.Get("Content-Type"))
+		writeJSONError(w, http.StatusUnsupportedMediaType, "unsupported content type; want application/x-www-form-urlencoded")
+		return slack.SlashCommand{}, false
+	}
+	body, ok := verifyRequest(w, r, signingSecret)
+	if !ok {
+		return slack.SlashCommand{}, false
 	}
 
 	// Parse the slash command payload
-	payload, err := slack.SlashCommandParse(r)
+	payload, err := parseCommand(r, body)
 	if err != nil {
-		log.Printf("Error parsing slash command: %v", err)
+		logError("Error parsing slash command: %v", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
-		return
+		return slack.SlashCommand{}, false
 	}
+	logCommandBody(payload)
+	return payload, true
+}
 
-	// Determine the response based on the input
-	var reply string
-	if !strings.HasSuffix(payload.Text, "?") {
-		reply = "Where's the question?"
-	} else {
-		matched, err := regexp.MatchString("^(who|what|when|where|why|how|if).*", strings.ToLower(payload.Text))
+// maxBodyBytes caps request bodies, after any decompression.
+const maxBodyBytes = 1 << 20
+
+// readBody reads the whole request body, transparently decompressing it when
+// Slack sends it gzip-encoded, so the signature is checked over the same
+// bytes that are parsed.
+func readBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+
+	var src io.Reader = r.Body
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
 		if err != nil {
-			log.Printf("Regex error: %v", err)
-			reply = "I'm having trouble understanding. Please try again."
-		} else if matched {
-			reply = "I'm not a tarot deck. Yes or no questions please."
-		} else {
-			// Provide a random response
-			reply = magicResponse[rand.Intn(len(magicResponse))]
+			return nil, fmt.Errorf("opening gzip body: %w", err)
 		}
+		defer zr.Close()
+		src = zr
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(src, maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBodyBytes {
+		return nil, fmt.Errorf("body exceeds %d bytes", maxBodyBytes)
+	}
+	return body, nil
+}
+
+// handleSlashCommand processes slash commands sent to /ask8ball. Several
+// commands may share this Request URL; dispatch routes them by name.
+func handleSlashCommand(w http.ResponseWriter, r *http.Request, signingSecret string, client *slack.Client) {
+	payload, ok := verifyCommand(w, r, signingSecret)
+	if !ok {
+		return
 	}
 
-	log.Printf("Slash Command Replying with: %s", reply)
+	writeJSON(w, commandReply(r.Context(), payload).webhookMessage())
+}
 
-	response := map[string]string{
-		 "text": "Magic 8-ball is here! (This is a test response)",
+// commandReply is the reply to a parsed slash command, however it arrived.
+// Answers are held back by ANSWER_DELAY, within ctx.
+func commandReply(ctx context.Context, cmd slack.SlashCommand) *Reply {
+	msg, err := dispatch(cmd)
+	if err != nil {
+		logError("Error dispatching slash command: %v %s", err, commandFields(cmd))
+		return &Reply{Text: fmt.Sprintf("🎱 I don't know %s.\n%s", cmd.Command, helpText())}
+	}
+	if resolveAlias(commandName(cmd)) == "/ask8ball" {
+		delayAnswer(ctx, cfg.AnswerDelay)
 	}
+	return msg
+}
+
+// writeJSONError sends {"error": msg} with the given status code.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	respBytes, _ := json.Marshal(map[string]string{"error": msg})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(respBytes)
+}
 
-	respBytes, err := json.Marshal(response)
+// writeJSON sends v as a 200 JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	respBytes, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("Error marshaling response: %v", err)
+		logError("Error marshaling response: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	w.Write(respBytes)
 }
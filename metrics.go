@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// answersTotal counts answers by category, with answers that weren't a plain
+// random pick (forced, reask) under their own label.
+var answersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ask8ball_answers_total",
+	Help: "Answers given, by category.",
+}, []string{"category"})
+
+// rejectedTotal counts requests refused by verifyRequest, by reason.
+var rejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ask8ball_rejected_total",
+	Help: "Requests rejected before reaching a handler, by reason.",
+}, []string{"reason"})
+
+// Reasons for rejectedTotal.
+const (
+	rejectMethod         = "method"
+	rejectMissingHeader  = "missing_header"
+	rejectStaleTimestamp = "stale_timestamp"
+	rejectBadSignature   = "bad_signature"
+	rejectContentType    = "content_type"
+)
+
+// questionLength observes how long answered questions are, in runes, to
+// show how people phrase them.
+var questionLength = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "ask8ball_question_rune_length",
+	Help:    "Length in runes of answered questions.",
+	Buckets: prometheus.ExponentialBuckets(8, 2, 7),
+})
+
+// selectionSeconds observes how long picking an answer takes, including
+// fetching the responses and the locking SELECTION_MODE and
+// CHANNEL_RECENT_ANSWERS need.
+var selectionSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    "ask8ball_selection_seconds",
+	Help:    "Time taken to select an answer.",
+	Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+})
+
+// metricCategory is the answersTotal label for resp.
+func metricCategory(resp Response) string {
+	if resp.Source != sourceRandom && resp.Source != "" {
+		return resp.Source
+	}
+	if resp.Category == "" {
+		return "unknown"
+	}
+	return resp.Category
+}
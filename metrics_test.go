@@ -0,0 +1,244 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricCategory(t *testing.T) {
+	tests := []struct {
+		resp Response
+		want string
+	}{
+		{Response{Category: categoryYes, Source: sourceRandom}, categoryYes},
+		{Response{Category: categoryNo}, categoryNo},
+		{Response{Category: categoryMaybe, Source: sourceSession}, sourceSession},
+		{Response{Category: categoryYes, Source: sourceForced}, sourceForced},
+		{Response{Category: categoryNo, Source: sourceReask}, sourceReask},
+		{Response{Source: sourceRandom}, "unknown"},
+	}
+	for _, tt := range tests {
+		if got := metricCategory(tt.resp); got != tt.want {
+			t.Errorf("metricCategory(%+v) = %q, want %q", tt.resp, got, tt.want)
+		}
+	}
+}
+
+// answerCounts scrapes answersTotal for every label an answer can carry.
+func answerCounts() map[string]float64 {
+	counts := map[string]float64{}
+	for _, label := range []string{categoryYes, categoryNo, categoryMaybe, sourceForced, sourceReask, sourceSession, "unknown"} {
+		counts[label] = testutil.ToFloat64(answersTotal.WithLabelValues(label))
+	}
+	return counts
+}
+
+func TestAnswersTotalCountsEachAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		question string
+		label    string // "" for each answer's own category
+	}{
+		{"random", nil, "Will it rain?", ""},
+		{"forced", map[string]string{"FORCE_YES_WORDS": "pizza"}, "Should we get pizza?", sourceForced},
+		{"reask", map[string]string{"REASK_RATE": "1"}, "Will it rain?", sourceReask},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			purgeAll()
+			before := answerCounts()
+			want := map[string]float64{}
+			for range 10 {
+				resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: tt.question})
+				if !resp.answered() {
+					t.Fatalf("ask = %+v, want an answer", resp)
+				}
+				if tt.label != "" {
+					want[tt.label]++
+				} else {
+					want[resp.Category]++
+				}
+			}
+			for label, count := range answerCounts() {
+				if got := count - before[label]; got != want[label] {
+					t.Errorf("%s count rose by %v, want %v", label, got, want[label])
+				}
+			}
+		})
+	}
+}
+
+func TestRejectedTotalCountsEachReason(t *testing.T) {
+	withConfig(t, nil)
+	now := time.Unix(1700000000, 0)
+	setClock(t, now)
+	signed := func() *http.Request {
+		return signedRequest("/ask8ball", formContentType, "command=%2Fask8ball")
+	}
+	tests := []struct {
+		reason string
+		req    func() *http.Request
+	}{
+		{rejectMethod, func() *http.Request { return httptest.NewRequest("GET", "/ask8ball", nil) }},
+		{rejectMissingHeader, func() *http.Request {
+			r := signed()
+			r.Header.Del("X-Slack-Signature")
+			return r
+		}},
+		{rejectStaleTimestamp, func() *http.Request {
+			r := signed()
+			r.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(now.Add(-time.Hour).Unix(), 10))
+			return r
+		}},
+		{rejectBadSignature, func() *http.Request {
+			r := signed()
+			r.Header.Set("X-Slack-Signature", "v0=00")
+			return r
+		}},
+		{rejectContentType, func() *http.Request {
+			r := signed()
+			r.Header.Set("Content-Type", "text/plain")
+			return r
+		}},
+	}
+	reasons := []string{rejectMethod, rejectMissingHeader, rejectStaleTimestamp, rejectBadSignature, rejectContentType}
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			before := map[string]float64{}
+			for _, reason := range reasons {
+				before[reason] = testutil.ToFloat64(rejectedTotal.WithLabelValues(reason))
+			}
+			if _, ok := verifyCommand(httptest.NewRecorder(), tt.req(), testSecret); ok {
+				t.Fatal("verifyCommand accepted the request")
+			}
+			for _, reason := range reasons {
+				want := 0.0
+				if reason == tt.reason {
+					want = 1
+				}
+				if got := testutil.ToFloat64(rejectedTotal.WithLabelValues(reason)) - before[reason]; got != want {
+					t.Errorf("%s count rose by %v, want %v", reason, got, want)
+				}
+			}
+		})
+	}
+
+	before := testutil.ToFloat64(rejectedTotal.WithLabelValues(rejectBadSignature))
+	if _, ok := verifyCommand(httptest.NewRecorder(), signed(), testSecret); !ok {
+		t.Fatal("verifyCommand refused a good request")
+	}
+	if testutil.ToFloat64(rejectedTotal.WithLabelValues(rejectBadSignature)) != before {
+		t.Error("a good request counted as rejected")
+	}
+}
+
+// histogramBuckets returns h's cumulative count for each bucket upper bound.
+func histogramBuckets(t *testing.T, h prometheus.Histogram) map[float64]uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	buckets := map[float64]uint64{}
+	for _, b := range m.GetHistogram().GetBucket() {
+		buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+	}
+	return buckets
+}
+
+func TestQuestionLengthObserved(t *testing.T) {
+	withConfig(t, map[string]string{"STRICT_QUESTIONS": "true"})
+	before := histogramBuckets(t, questionLength)
+
+	for _, q := range []string{
+		"Is it?",              // 6 runes
+		"  Will   it  rain? ", // 13 once normalized
+		"Will it rain on Zürich's lake tomorrow?", // 39
+		"Why is it raining?",                      // rejected, so not observed
+		"Will " + strings.Repeat("é", 95) + "?",   // 101
+	} {
+		ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: q})
+	}
+
+	after := histogramBuckets(t, questionLength)
+	// Buckets are cumulative: each counts the questions up to its bound.
+	want := map[float64]uint64{8: 1, 16: 2, 32: 2, 64: 3, 128: 4, 256: 4, 512: 4}
+	for bound, n := range want {
+		if got := after[bound] - before[bound]; got != n {
+			t.Errorf("bucket le=%v got %d questions, want %d", bound, got, n)
+		}
+	}
+}
+
+func TestSelectionObservesProvider(t *testing.T) {
+	withConfig(t, nil)
+	p := &fakeProvider{lists: map[string][]string{"C1": {"Yes."}}}
+	withProvider(t, p)
+	logs := captureLog(t)
+
+	// Holding the provider's lock stalls fetching the responses.
+	before := histogramBuckets(t, selectionSeconds)
+	p.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"})
+	}()
+	time.Sleep(80 * time.Millisecond)
+	p.mu.Unlock()
+	<-done
+	after := histogramBuckets(t, selectionSeconds)
+	if after[0.0256] != before[0.0256] || after[0.4096]-before[0.4096] != 1 {
+		t.Errorf("selection buckets went from %v to %v, want the slow provider observed over 25.6ms", before, after)
+	}
+	if !strings.Contains(logs.String(), "answer selection took") {
+		t.Errorf("log %q, want a warning about the slow selection", logs)
+	}
+}
+
+func TestSlowSelectionObserved(t *testing.T) {
+	c := withConfig(t, map[string]string{"SELECTION_MODE": "decay"})
+	answerUsage = newDecayCounter(c.DecayHalfLife)
+	t.Cleanup(func() { answerUsage = nil })
+	logs := captureLog(t)
+
+	// An ordinary selection is quick and quiet.
+	before := histogramBuckets(t, selectionSeconds)
+	ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"})
+	after := histogramBuckets(t, selectionSeconds)
+	if after[0.0256]-before[0.0256] != 1 {
+		t.Errorf("quick selection buckets went from %v to %v, want one observation under 25.6ms", before, after)
+	}
+	if strings.Contains(logs.String(), "answer selection took") {
+		t.Errorf("quick selection logged %q", logs)
+	}
+
+	// Holding the decay counts' lock stalls the selector.
+	before = after
+	answerUsage.mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U2", Question: "Will it snow?"})
+	}()
+	time.Sleep(80 * time.Millisecond)
+	answerUsage.mu.Unlock()
+	<-done
+	after = histogramBuckets(t, selectionSeconds)
+	if after[0.0256] != before[0.0256] || after[0.4096]-before[0.4096] != 1 {
+		t.Errorf("slow selection buckets went from %v to %v, want one observation over 25.6ms", before, after)
+	}
+	if !strings.Contains(logs.String(), "answer selection took") || !strings.Contains(logs.String(), "SELECTION_MODE=decay") {
+		t.Errorf("log %q, want a warning about the slow selection", logs)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Scopes for MILESTONE_SCOPE.
+const (
+	milestoneGlobal  = "global"
+	milestoneChannel = "channel"
+)
+
+// askCounter counts answered questions since startup, overall or per
+// channel, to spot milestones.
+type askCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// askCounts is shared by all requests.
+var askCounts = &askCounter{counts: make(map[string]int)}
+
+// add counts one more ask under key and returns the new count.
+func (c *askCounter) add(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[key]++
+	return c.counts[key]
+}
+
+// milestoneKey is the askCounts key for req under MILESTONE_SCOPE.
+func milestoneKey(req askRequest) string {
+	if cfg.MilestoneScope == milestoneChannel {
+		return req.ChannelID
+	}
+	return ""
+}
+
+// milestoneNote is the celebration to add to the count-th answer, or "" if
+// count is not one of the configured MILESTONES.
+func milestoneNote(count int) string {
+	if !slices.Contains(cfg.Milestones, count) {
+		return ""
+	}
+	if cfg.MilestoneScope == milestoneChannel {
+		return fmt.Sprintf("🎉 That's question #%d in this channel!", count)
+	}
+	return fmt.Sprintf("🎉 That's question #%d for the 8-ball!", count)
+}
+
+// parseMilestones parses MILESTONES, a list of positive ask counts.
+func parseMilestones(entries []string) ([]int, error) {
+	var milestones []int
+	for _, e := range entries {
+		n, err := strconv.Atoi(e)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid MILESTONES entry %q: must be a positive count", e)
+		}
+		milestones = append(milestones, n)
+	}
+	return milestones, nil
+}
+
+// purge resets the ask counts, returning how many were kept.
+func (c *askCounter) purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.counts)
+	clear(c.counts)
+	return n
+}
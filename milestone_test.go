@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMilestoneNote(t *testing.T) {
+	tests := []struct {
+		scope string
+		count int
+		want  string
+	}{
+		{"global", 100, "🎉 That's question #100 for the 8-ball!"},
+		{"global", 1000, "🎉 That's question #1000 for the 8-ball!"},
+		{"global", 99, ""},
+		{"global", 101, ""},
+		{"global", 0, ""},
+		{"channel", 100, "🎉 That's question #100 in this channel!"},
+		{"channel", 999, ""},
+	}
+	for _, tt := range tests {
+		withConfig(t, map[string]string{"MILESTONES": "100,1000", "MILESTONE_SCOPE": tt.scope})
+		if got := milestoneNote(tt.count); got != tt.want {
+			t.Errorf("%s milestoneNote(%d) = %q, want %q", tt.scope, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestParseMilestones(t *testing.T) {
+	got, err := parseMilestones([]string{"3", "100"})
+	if err != nil || !slices.Equal(got, []int{3, 100}) {
+		t.Errorf("parseMilestones = %v, %v; want [3 100]", got, err)
+	}
+	for _, bad := range []string{"0", "-5", "lots", "1.5"} {
+		if _, err := parseMilestones([]string{bad}); err == nil {
+			t.Errorf("parseMilestones(%q) succeeded", bad)
+		}
+	}
+	t.Setenv("MILESTONE_SCOPE", "team")
+	if _, err := loadConfig(); err == nil {
+		t.Error("MILESTONE_SCOPE=team loaded")
+	}
+}
+
+func TestMilestoneNoteOnlyAtMilestones(t *testing.T) {
+	tests := []struct {
+		scope    string
+		channels []string // the channel of each ask
+		noted    []int    // asks, from 1, that carry a note
+	}{
+		{"global", []string{"C1", "C2", "C1", "C2", "C1", "C2"}, []int{3, 5}},
+		{"channel", []string{"C1", "C2", "C1", "C2", "C1", "C2"}, []int{5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			withConfig(t, map[string]string{"MILESTONES": "3,5", "MILESTONE_SCOPE": tt.scope})
+			// Rejected questions don't count towards a milestone.
+			ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Why is it raining?"})
+			for i, channel := range tt.channels {
+				resp := ask(askRequest{TeamID: "T1", ChannelID: channel, UserID: "U1", Question: "Will it rain?"})
+				if want := slices.Contains(tt.noted, i+1); (resp.Note != "") != want {
+					t.Errorf("ask %d in %s: note %q, want a note %v", i+1, channel, resp.Note, want)
+				}
+				if resp.Note != "" && !strings.Contains(displayText(resp), resp.Text+"\n"+resp.Note) {
+					t.Errorf("ask %d: displayed %q, want the note after the answer", i+1, displayText(resp))
+				}
+			}
+		})
+	}
+}
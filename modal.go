@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// IDs of the ask modal and its question input.
+const (
+	askModalCallbackID = "ask_modal"
+	askModalBlockID    = "question"
+	askModalActionID   = "question_input"
+)
+
+// viewOpener is the part of the Slack client the ask modal needs.
+type viewOpener interface {
+	OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error)
+}
+
+// modals is set at startup when MODALS is enabled.
+var modals viewOpener
+
+// askModal is the modal asking for a question.
+func askModal() slack.ModalViewRequest {
+	input := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "Will it rain tomorrow?", false, false), askModalActionID)
+	return slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: askModalCallbackID,
+		Title:      slack.NewTextBlockObject(slack.PlainTextType, "Ask the 8-ball", false, false),
+		Submit:     slack.NewTextBlockObject(slack.PlainTextType, "Shake", false, false),
+		Close:      slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(askModalBlockID,
+				slack.NewTextBlockObject(slack.PlainTextType, "Your yes-or-no question", false, false), nil, input),
+		}},
+	}
+}
+
+// openAskModal opens the ask modal for the interaction behind triggerID.
+func openAskModal(triggerID string) error {
+	return slackCall(func() error {
+		_, err := modals.OpenView(triggerID, askModal())
+		return err
+	})
+}
+
+// modalRequest reports whether cmd should be answered with the ask modal:
+// MODALS is on and there is no question to answer yet.
+func modalRequest(cmd slack.SlashCommand) bool {
+	return modals != nil && strings.TrimSpace(cmd.Text) == "" && cmd.TriggerID != ""
+}
+
+// modalCommand opens the ask modal for cmd, replying only if that fails.
+func modalCommand(cmd slack.SlashCommand) *Reply {
+	log.Printf("Opening ask modal for trigger_id %s %s", cmd.TriggerID, commandFields(cmd))
+	if err := openAskModal(cmd.TriggerID); err != nil {
+		logError("Error opening ask modal: %v %s", err, commandFields(cmd))
+		return &Reply{Text: "🎱 I couldn't open the question box. Try `/ask8ball <your question>?` instead."}
+	}
+	return &Reply{}
+}
+
+// askModalSubmission answers the question submitted through the ask modal by
+// replacing the modal with the answer. Refusals replace it the same way.
+func askModalSubmission(callback *slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	var question string
+	if callback.View.State != nil {
+		question = callback.View.State.Values[askModalBlockID][askModalActionID].Value
+	}
+	question = normalizeText(question)
+
+	var text string
+	switch refusal := askRefusal(callback.Team.ID, "", callback.User.ID); {
+	case refusal != "":
+		text = refusal
+	case needsAnotherShake(callback.User.ID, question):
+		text = replyShakeAgain
+	default:
+		resp := ask(askRequest{
+			EnterpriseID: callback.Enterprise.ID,
+			TeamID:       callback.Team.ID,
+			UserID:       callback.User.ID,
+			Question:     question,
+		})
+		log.Printf("Ask modal Replying with: %s (index %d)", resp.Text, resp.Index)
+		text = replyText(question, resp)
+	}
+	return slack.NewUpdateViewSubmissionResponse(answerModal(question, text))
+}
+
+// answerModal is the view replacing the ask modal once question is answered.
+func answerModal(question, text string) *slack.ModalViewRequest {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+	}
+	if question != "" {
+		blocks = append([]slack.Block{
+			slack.NewContextBlock("", slack.NewTextBlockObject(slack.PlainTextType, question, false, false)),
+		}, blocks...)
+	}
+	return &slack.ModalViewRequest{
+		Type:   slack.VTModal,
+		Title:  slack.NewTextBlockObject(slack.PlainTextType, "Ask the 8-ball", false, false),
+		Close:  slack.NewTextBlockObject(slack.PlainTextType, "Done", false, false),
+		Blocks: slack.Blocks{BlockSet: blocks},
+	}
+}
+
+// isAskModalSubmission reports whether callback is the ask modal being submitted.
+func isAskModalSubmission(callback slack.InteractionCallback) bool {
+	return callback.Type == slack.InteractionTypeViewSubmission && callback.View.CallbackID == askModalCallbackID
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeViews records the views opened through it.
+type fakeViews struct {
+	triggerIDs []string
+	views      []slack.ModalViewRequest
+	err        error
+}
+
+func (f *fakeViews) OpenView(triggerID string, view slack.ModalViewRequest) (*slack.ViewResponse, error) {
+	f.triggerIDs = append(f.triggerIDs, triggerID)
+	f.views = append(f.views, view)
+	return &slack.ViewResponse{}, f.err
+}
+
+// withModals sets modals to a fake for the rest of the test.
+func withModals(t *testing.T) *fakeViews {
+	t.Helper()
+	f := &fakeViews{}
+	modals = f
+	t.Cleanup(func() { modals = nil })
+	return f
+}
+
+func TestAskCommandOpensModal(t *testing.T) {
+	withConfig(t, map[string]string{"MODALS": "true"})
+	views := withModals(t)
+
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", TeamID: "T1", ChannelID: "C1", UserID: "U1", Text: "  ", TriggerID: "13345224609.738474920.8088930838d88f008e0"})
+	if reply.Text != "" {
+		t.Errorf("reply = %q, want none once the modal opens", reply.Text)
+	}
+	if !reflect.DeepEqual(views.triggerIDs, []string{"13345224609.738474920.8088930838d88f008e0"}) {
+		t.Fatalf("OpenView trigger IDs = %v, want the command's", views.triggerIDs)
+	}
+	if !reflect.DeepEqual(views.views[0], askModal()) {
+		t.Errorf("opened %+v, want the ask modal", views.views[0])
+	}
+	if views.views[0].CallbackID != askModalCallbackID {
+		t.Errorf("modal callback_id = %q, want %q", views.views[0].CallbackID, askModalCallbackID)
+	}
+}
+
+func TestAskCommandSkipsModal(t *testing.T) {
+	tests := []struct {
+		name    string
+		modals  bool
+		text    string
+		trigger string
+	}{
+		{"modals off", false, "", "T.1"},
+		{"question given", true, "Will it rain?", "T.1"},
+		{"no trigger", true, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, nil)
+			views := withModals(t)
+			if !tt.modals {
+				modals = nil
+			}
+			askCommand(slack.SlashCommand{Command: "/ask8ball", TeamID: "T1", ChannelID: "C1", UserID: "U1", Text: tt.text, TriggerID: tt.trigger})
+			if len(views.triggerIDs) != 0 {
+				t.Errorf("opened a modal for %v", views.triggerIDs)
+			}
+		})
+	}
+}
+
+func TestAskCommandModalError(t *testing.T) {
+	withConfig(t, nil)
+	views := withModals(t)
+	views.err = errors.New("expired_trigger_id")
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", TeamID: "T1", ChannelID: "C1", UserID: "U1", TriggerID: "T.1"})
+	if !strings.Contains(reply.Text, "couldn't open the question box") {
+		t.Errorf("reply = %q, want the fallback", reply.Text)
+	}
+}
+
+func TestAskModalSubmission(t *testing.T) {
+	withConfig(t, nil)
+	var callback slack.InteractionCallback
+	callback.Type = slack.InteractionTypeViewSubmission
+	callback.View.CallbackID = askModalCallbackID
+	callback.Team.ID = "T1"
+	callback.User.ID = "U1"
+	callback.View.State = &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+		askModalBlockID: {askModalActionID: {Value: " Will  it rain? "}},
+	}}
+	if !isAskModalSubmission(callback) {
+		t.Fatal("isAskModalSubmission = false for the ask modal")
+	}
+
+	resp := askModalSubmission(&callback)
+	if resp.ResponseAction != slack.RAUpdate {
+		t.Errorf("response_action = %q, want update", resp.ResponseAction)
+	}
+	blocks := resp.View.Blocks.BlockSet
+	if len(blocks) != 2 {
+		t.Fatalf("answer modal has %d blocks, want the question and answer", len(blocks))
+	}
+	if q := blocks[0].(*slack.ContextBlock).ContextElements.Elements[0].(*slack.TextBlockObject).Text; q != "Will it rain?" {
+		t.Errorf("question block = %q, want the normalized question", q)
+	}
+	if answer := blocks[1].(*slack.SectionBlock).Text.Text; answer == "" {
+		t.Error("answer block is empty")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// httpClient is shared by every outbound call so connections are pooled and
+// a slow Slack can't hold a request past its response budget.
+var httpClient = &http.Client{
+	Timeout: 3 * time.Second,
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   2 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   2 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	},
+}
+
+// slackBreaker guards every outbound call to Slack so an outage or rate
+// limiting fails fast instead of piling up retries.
+var slackBreaker = newBreaker(5, 30*time.Second)
+
+// responseURLHosts are the hosts Slack issues response_urls on, including
+// for Enterprise Grid installs and GovSlack.
+var responseURLHosts = []string{"hooks.slack.com", "hooks.slack-gov.com"}
+
+// errInvalidResponseURL is returned for response_urls not issued by Slack.
+var errInvalidResponseURL = errors.New("invalid response_url")
+
+// validateResponseURL checks that u is an https URL on a Slack webhook host,
+// so a forged payload can't make the bot post to arbitrary addresses.
+func validateResponseURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidResponseURL, err)
+	}
+	if parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" {
+		return fmt.Errorf("%w %q: must be a plain https URL", errInvalidResponseURL, u)
+	}
+	if !slices.Contains(responseURLHosts, strings.ToLower(parsed.Hostname())) {
+		return fmt.Errorf("%w %q: host is not Slack's", errInvalidResponseURL, u)
+	}
+	return nil
+}
+
+// postResponseURL delivers msg to a slash command or interaction response_url.
+func postResponseURL(url string, msg *slack.WebhookMessage) error {
+	if err := validateResponseURL(url); err != nil {
+		return err
+	}
+	return slackCall(func() error {
+		return slack.PostWebhookCustomHTTP(url, httpClient, msg)
+	})
+}
+
+// postMessage posts to a channel through the Web API.
+func postMessage(client *slack.Client, channelID string, options ...slack.MsgOption) (string, error) {
+	var ts string
+	err := slackCall(func() error {
+		var err error
+		_, ts, err = client.PostMessage(channelID, options...)
+		return err
+	})
+	return ts, err
+}
+
+// postEphemeral shows a message only to userID in channelID.
+func postEphemeral(client *slack.Client, channelID, userID string, options ...slack.MsgOption) error {
+	return slackCall(func() error {
+		_, err := client.PostEphemeral(channelID, userID, options...)
+		return err
+	})
+}
+
+// postEphemeralFallback is postEphemeral around slackBreaker. It is the last
+// resort for a reply whose response_url delivery failed, often because the
+// breaker has just opened, so it must not be refused by the breaker too.
+// Its outcome doesn't count towards the breaker either.
+func postEphemeralFallback(client *slack.Client, channelID, userID string, options ...slack.MsgOption) error {
+	_, err := client.PostEphemeral(channelID, userID, options...)
+	noteTokenError(err)
+	return err
+}
+
+// responseURLAttempts is how many times delivery to a response_url is tried
+// before falling back to an ephemeral message.
+const responseURLAttempts = 3
+
+// retryDelay is the pause before the first retry; it doubles each time.
+var retryDelay = 200 * time.Millisecond
+
+// errNoResponseURL is the reason for falling back when a payload had no
+// response_url, as some test harnesses send.
+var errNoResponseURL = errors.New("payload has no response_url")
+
+// deliverWithFallback posts msg to responseURL, retrying transient failures.
+// If that fails for good, or there is no responseURL, it falls back to an
+// ephemeral message to userID in channelID so the user isn't left with
+// nothing.
+func deliverWithFallback(client *slack.Client, responseURL, channelID, userID string, msg *slack.WebhookMessage) error {
+	var err error
+	delay := retryDelay
+	if responseURL == "" {
+		log.Printf("No response_url to deliver to; falling back to an ephemeral message")
+		err = errNoResponseURL
+	}
+	for attempt := 1; attempt <= responseURLAttempts && responseURL != ""; attempt++ {
+		if err = postResponseURL(responseURL, msg); err == nil {
+			return nil
+		}
+		logWarn("Delivery to response_url failed (attempt %d/%d): %v", attempt, responseURLAttempts, err)
+		if errors.Is(err, errBreakerOpen) || errors.Is(err, errInvalidResponseURL) || attempt == responseURLAttempts {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	options := []slack.MsgOption{slack.MsgOptionText(msg.Text, false)}
+	if msg.Blocks != nil {
+		options = append(options, slack.MsgOptionBlocks(msg.Blocks.BlockSet...))
+	}
+	if fallbackErr := postEphemeralFallback(client, channelID, userID, options...); fallbackErr != nil {
+		return fmt.Errorf("response_url: %w; ephemeral fallback: %v", err, fallbackErr)
+	}
+	log.Printf("Delivered to %s via ephemeral fallback after response_url failed", logID(userID))
+	return nil
+}
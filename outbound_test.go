@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestHTTPClientDefaults(t *testing.T) {
+	if httpClient.Timeout != 3*time.Second {
+		t.Errorf("timeout = %v, want 3s", httpClient.Timeout)
+	}
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport is a %T, want a tuned *http.Transport", httpClient.Transport)
+	}
+	if transport.MaxIdleConnsPerHost < 2 || transport.IdleConnTimeout == 0 || transport.TLSHandshakeTimeout == 0 {
+		t.Errorf("transport isn't tuned for pooling: %+v", transport)
+	}
+}
+
+func TestOutboundUsesSharedClient(t *testing.T) {
+	withConfig(t, nil)
+	sent := captureOutbound(t)
+
+	msg := &slack.WebhookMessage{Text: "It is certain."}
+	for range 2 {
+		if err := postResponseURL("https://hooks.slack.com/commands/1", msg); err != nil {
+			t.Fatalf("postResponseURL: %v", err)
+		}
+		nextOutbound(t, sent)
+	}
+}
+
+// quickRetries makes delivery retries immediate and gives the test its own
+// breaker, so failures don't leak into other tests.
+func quickRetries(t *testing.T) {
+	t.Helper()
+	oldDelay, oldBreaker := retryDelay, slackBreaker
+	retryDelay = time.Millisecond
+	slackBreaker = newBreaker(5, 30*time.Second)
+	t.Cleanup(func() { retryDelay, slackBreaker = oldDelay, oldBreaker })
+}
+
+func TestDeliverWithFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		responseURL  string
+		openBreaker  bool
+		wantAttempts int
+		wantFallback bool
+	}{
+		{"delivered", http.StatusOK, "https://hooks.slack.com/commands/1", false, 1, false},
+		{"response_url always fails", http.StatusInternalServerError, "https://hooks.slack.com/commands/1", false, responseURLAttempts, true},
+		{"no response_url", http.StatusOK, "", false, 0, true},
+		{"forged response_url", http.StatusOK, "https://evil.example/hook", false, 0, true},
+		{"breaker open", http.StatusOK, "https://hooks.slack.com/commands/1", true, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, nil)
+			quickRetries(t)
+			if tt.openBreaker {
+				slackBreaker = newBreaker(1, time.Hour)
+				slackBreaker.do(func() error { return errors.New("down") })
+			}
+			sent := stubOutbound(t, tt.status)
+			client, calls := fakeSlack(t)
+
+			err := deliverWithFallback(client, tt.responseURL, "C1", "U1", &slack.WebhookMessage{Text: "It is certain."})
+			if err != nil {
+				t.Fatalf("deliverWithFallback: %v", err)
+			}
+			if attempts := len(sent); attempts != tt.wantAttempts {
+				t.Errorf("response_url attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			select {
+			case call := <-calls:
+				form, _ := url.ParseQuery(call.Body)
+				if !tt.wantFallback || call.URL != "chat.postEphemeral" || form.Get("user") != "U1" || form.Get("text") != "It is certain." {
+					t.Errorf("fell back with %s %v", call.URL, form)
+				}
+			default:
+				if tt.wantFallback {
+					t.Error("no ephemeral fallback")
+				}
+			}
+		})
+	}
+}
+
+func TestValidateResponseURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://hooks.slack.com/commands/T1/1/abc", true},
+		{"https://hooks.slack.com/actions/T1/1/abc", true},
+		{"https://HOOKS.slack.com/actions/1", true},
+		{"https://hooks.slack-gov.com/commands/1", true},
+		{"http://hooks.slack.com/commands/1", false},
+		{"https://hooks.slack.com:8443/x", false},
+		{"https://user@hooks.slack.com/x", false},
+		{"https://hooks.slack.com.evil.example/x", false},
+		{"https://evil.example/hooks.slack.com", false},
+		{"https://slack.com/api/chat.postMessage", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"://bad", false},
+		{"https://hooks.slack.com/%zz", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		err := validateResponseURL(tt.url)
+		if tt.ok && err != nil {
+			t.Errorf("validateResponseURL(%q): %v", tt.url, err)
+		}
+		if !tt.ok && !errors.Is(err, errInvalidResponseURL) {
+			t.Errorf("validateResponseURL(%q) = %v, want errInvalidResponseURL", tt.url, err)
+		}
+	}
+}
+
+func TestPostResponseURLRefusesOtherHosts(t *testing.T) {
+	withConfig(t, nil)
+	sent := captureOutbound(t)
+	if err := postResponseURL("https://evil.example/hook", &slack.WebhookMessage{Text: "hi"}); !errors.Is(err, errInvalidResponseURL) {
+		t.Errorf("postResponseURL = %v, want errInvalidResponseURL", err)
+	}
+	select {
+	case out := <-sent:
+		t.Errorf("posted to %s", out.URL)
+	default:
+	}
+}
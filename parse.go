@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/slack-go/slack"
+)
+
+// parseCommand decodes a slash command payload from an already-read request
+// body. Slack sends form-encoded bodies, but JSON objects using the same field
+// names are accepted too so other integrations can reuse the endpoint.
+func parseCommand(r *http.Request, body []byte) (slack.SlashCommand, error) {
+	cmd, err := decodeCommand(r, body)
+	if err != nil {
+		return cmd, err
+	}
+	return cleanCommand(cmd), nil
+}
+
+// cleanCommand tidies the text of a decoded command: see decodeRemnants and
+// trimCommandText.
+func cleanCommand(cmd slack.SlashCommand) slack.SlashCommand {
+	cmd.Text = decodeRemnants(cmd.Text)
+	cmd.Text = trimCommandText(cmd)
+	return cmd
+}
+
+// remnantPattern matches what a second layer of form encoding leaves
+// behind: a percent-encoded byte, or a "+" standing for a space between words.
+var remnantPattern = regexp.MustCompile(`%[0-9A-Fa-f]{2}|[\p{L}\p{N}]\+[\p{L}\p{N}]`)
+
+// decodeRemnants undoes a second layer of form encoding added by some
+// proxies, which leaves text like "Will+it+rain%3F" after the usual decoding.
+// Only text without spaces is decoded again: a real multi-word question
+// always has them once decoded, so a "+" in "Is C++ hard?" stays literal, as
+// does one not between two words, as in "C++?".
+func decodeRemnants(text string) string {
+	if strings.ContainsAny(text, " \t") || !remnantPattern.MatchString(text) {
+		return text
+	}
+	decoded, err := url.QueryUnescape(text)
+	if err != nil {
+		return text
+	}
+	return decoded
+}
+
+// decodeCommand decodes the payload according to its content type.
+//
+// With STRICT_CONTENT_TYPE=false, a body of any other or no content type is
+// decoded as a form, for proxies that mangle the header.
+func decodeCommand(r *http.Request, body []byte) (slack.SlashCommand, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil && cfg.StrictContentType {
+		return slack.SlashCommand{}, fmt.Errorf("parsing content type: %w", err)
+	}
+
+	switch mediaType {
+	case "application/json":
+		return decodeJSONCommand(body)
+	case formContentType:
+	default:
+		if cfg.StrictContentType {
+			return slack.SlashCommand{}, fmt.Errorf("unsupported content type %q", mediaType)
+		}
+		// The form parser only reads bodies it is told are forms.
+		r.Header.Set("Content-Type", formContentType)
+	}
+	// The body has already been consumed for signature verification, so
+	// hand the form parser a fresh reader over the same bytes.
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return slack.SlashCommandParse(r)
+}
+
+// decodeJSONCommand decodes a JSON slash command. slack.SlashCommand's own
+// decoding insists on is_enterprise_install, which only Slack sends, so here
+// it may be missing as well as a boolean or a string.
+func decodeJSONCommand(body []byte) (slack.SlashCommand, error) {
+	type plainCommand slack.SlashCommand
+	var cmd slack.SlashCommand
+	payload := struct {
+		*plainCommand
+		IsEnterpriseInstall any `json:"is_enterprise_install"`
+	}{plainCommand: (*plainCommand)(&cmd)}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return slack.SlashCommand{}, fmt.Errorf("decoding JSON command: %w", err)
+	}
+	switch v := payload.IsEnterpriseInstall.(type) {
+	case nil:
+	case bool:
+		cmd.IsEnterpriseInstall = v
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return slack.SlashCommand{}, fmt.Errorf("decoding JSON command: is_enterprise_install %q: %w", v, err)
+		}
+		cmd.IsEnterpriseInstall = b
+	default:
+		return slack.SlashCommand{}, fmt.Errorf("decoding JSON command: is_enterprise_install is a %T", v)
+	}
+	return cmd, nil
+}
+
+// formContentType is the content type Slack sends slash commands with.
+const formContentType = "application/x-www-form-urlencoded"
+
+// commandContentTypeOK reports whether r has a content type decodeCommand
+// accepts: a form, as Slack sends, or JSON.
+func commandContentTypeOK(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && (mediaType == formContentType || mediaType == "application/json")
+}
+
+// trimCommandText returns the user's argument to cmd, trimmed, without the
+// command name itself when it was pasted in by accident ("/ask8ball /ask8ball
+// Will it rain?").
+func trimCommandText(cmd slack.SlashCommand) string {
+	text := strings.TrimSpace(cmd.Text)
+	name := commandName(cmd)
+	if len(text) >= len(name) && strings.EqualFold(text[:len(name)], name) {
+		rest := text[len(name):]
+		if rest == "" || unicode.IsSpace(rune(rest[0])) {
+			text = strings.TrimSpace(rest)
+		}
+	}
+	return text
+}
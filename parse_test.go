@@ -0,0 +1,214 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/slack-go/slack"
+)
+
+func TestParseCommandContentTypes(t *testing.T) {
+	withConfig(t, nil)
+
+	form := url.Values{
+		"command":      {"/ask8ball"},
+		"text":         {"Will it rain?"},
+		"team_id":      {"T1"},
+		"channel_id":   {"C1"},
+		"user_id":      {"U1"},
+		"response_url": {"https://hooks.slack.com/commands/1"},
+	}.Encode()
+	jsonBody := `{"command":"/ask8ball","text":"Will it rain?","team_id":"T1","channel_id":"C1","user_id":"U1","response_url":"https://hooks.slack.com/commands/1"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"form", "application/x-www-form-urlencoded", form},
+		{"form with charset", "application/x-www-form-urlencoded; charset=utf-8", form},
+		{"json", "application/json", jsonBody},
+		{"json with charset", "application/json; charset=utf-8", jsonBody},
+		{"json enterprise install bool", "application/json", strings.Replace(jsonBody, "{", `{"is_enterprise_install":false,`, 1)},
+		{"json enterprise install string", "application/json", strings.Replace(jsonBody, "{", `{"is_enterprise_install":"false",`, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/ask8ball", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			cmd, err := parseCommand(r, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseCommand: %v", err)
+			}
+			if cmd.Command != "/ask8ball" || cmd.Text != "Will it rain?" || cmd.TeamID != "T1" ||
+				cmd.ChannelID != "C1" || cmd.UserID != "U1" || cmd.ResponseURL != "https://hooks.slack.com/commands/1" {
+				t.Errorf("parseCommand = %+v", cmd)
+			}
+		})
+	}
+}
+
+func TestParseCommandRejects(t *testing.T) {
+	withConfig(t, nil)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"malformed json", "application/json", `{"command":`},
+		{"json array", "application/json", `["/ask8ball"]`},
+		{"json bad enterprise install", "application/json", `{"command":"/ask8ball","is_enterprise_install":"maybe"}`},
+		{"unsupported type", "text/plain", "command=/ask8ball"},
+		{"unparsable type", "application/", "command=/ask8ball"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/ask8ball", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			if cmd, err := parseCommand(r, []byte(tt.body)); err == nil {
+				t.Errorf("parseCommand = %+v, want an error", cmd)
+			}
+		})
+	}
+}
+
+func TestTrimCommandText(t *testing.T) {
+	tests := []struct {
+		command string
+		text    string
+		want    string
+	}{
+		{"/ask8ball", "Will it rain?", "Will it rain?"},
+		{"/ask8ball", "  Will it rain?\n", "Will it rain?"},
+		{"/ask8ball", "/ask8ball Will it rain?", "Will it rain?"},
+		{"/ask8ball", " /ASK8BALL   Will it rain?", "Will it rain?"},
+		{"/ask8ball", "/ask8ball", ""},
+		{"/ask8ball", "/ask8ballWill it rain?", "/ask8ballWill it rain?"},
+		{"/ask8ball", "Is /ask8ball any good?", "Is /ask8ball any good?"},
+		{"/8ball-theme", "/8ball-theme pirate", "pirate"},
+		{"/8ball-theme", "/ask8ball pirate", "/ask8ball pirate"},
+		{"", "/ask8ball Will it rain?", "Will it rain?"},
+		{"/ask8ball", "/ask8bäll?", "/ask8bäll?"},
+	}
+	for _, tt := range tests {
+		cmd := slack.SlashCommand{Command: tt.command, Text: tt.text}
+		if got := trimCommandText(cmd); got != tt.want {
+			t.Errorf("trimCommandText(%q, %q) = %q, want %q", tt.command, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseCommandTrimsText(t *testing.T) {
+	withConfig(t, nil)
+	body := url.Values{"command": {"/ask8ball"}, "text": {" /ask8ball  Will it rain? "}}.Encode()
+	r := httptest.NewRequest("POST", "/ask8ball", strings.NewReader(body))
+	r.Header.Set("Content-Type", formContentType)
+	cmd, err := parseCommand(r, []byte(body))
+	if err != nil {
+		t.Fatalf("parseCommand: %v", err)
+	}
+	if cmd.Text != "Will it rain?" {
+		t.Errorf("text = %q, want the bare question", cmd.Text)
+	}
+}
+
+func TestDecodeRemnants(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Will it rain?", "Will it rain?"},
+		{"Will+it+rain%3F", "Will it rain?"},
+		{"Will%20it%20rain%3F", "Will it rain?"},
+		{"Will+it+rain?", "Will it rain?"},
+		{"Should+I+learn+C%2B%2B%3F", "Should I learn C++?"},
+		{"Is C++ hard?", "Is C++ hard?"},
+		{"C++?", "C++?"},
+		{"Is 1+1 two?", "Is 1+1 two?"},
+		{"Is 100% sure?", "Is 100% sure?"},
+		{"Ready%3F", "Ready?"},
+		{"Bad%zzescape+here", "Bad%zzescape+here"},
+		{"Café+ouvert%3F", "Café ouvert?"},
+	}
+	for _, tt := range tests {
+		if got := decodeRemnants(tt.text); got != tt.want {
+			t.Errorf("decodeRemnants(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseCommandDoubleEncoded(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"command=%2Fask8ball&text=Will%2Bit%2Brain%253F", "Will it rain?"},
+		{"command=%2Fask8ball&text=Will+it+rain%3F", "Will it rain?"},
+		{"command=%2Fask8ball&text=Is+C%2B%2B+hard%3F", "Is C++ hard?"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/ask8ball", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", formContentType)
+		cmd, err := parseCommand(r, []byte(tt.body))
+		if err != nil {
+			t.Fatalf("parseCommand(%s): %v", tt.body, err)
+		}
+		if cmd.Text != tt.want {
+			t.Errorf("parseCommand(%s) text = %q, want %q", tt.body, cmd.Text, tt.want)
+		}
+	}
+}
+
+func TestVerifyCommandContentType(t *testing.T) {
+	form := url.Values{"command": {"/ask8ball"}, "text": {"Will it rain?"}, "user_id": {"U1"}}.Encode()
+	tests := []struct {
+		name        string
+		strict      string
+		contentType string
+		want        int // http.StatusOK when the command is taken
+	}{
+		{"form", "true", "application/x-www-form-urlencoded", http.StatusOK},
+		{"form with charset", "true", "application/x-www-form-urlencoded; charset=utf-8", http.StatusOK},
+		{"plain text", "true", "text/plain", http.StatusUnsupportedMediaType},
+		{"multipart", "true", "multipart/form-data; boundary=x", http.StatusUnsupportedMediaType},
+		{"no content type", "true", "", http.StatusUnsupportedMediaType},
+		{"lenient plain text", "false", "text/plain", http.StatusOK},
+		{"lenient no content type", "false", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, map[string]string{"STRICT_CONTENT_TYPE": tt.strict})
+			w := httptest.NewRecorder()
+			cmd, ok := verifyCommand(w, signedRequest("/ask8ball", tt.contentType, form), testSecret)
+			if w.Code != tt.want || ok != (tt.want == http.StatusOK) {
+				t.Fatalf("verifyCommand = %v with status %d, want %d (%s)", ok, w.Code, tt.want, w.Body)
+			}
+			if ok && (cmd.Command != "/ask8ball" || cmd.Text != "Will it rain?") {
+				t.Errorf("parsed %+v", cmd)
+			}
+			if !ok && !strings.Contains(w.Body.String(), "unsupported content type") {
+				t.Errorf("415 body = %q", w.Body)
+			}
+		})
+	}
+}
+
+func TestVerifyCommandContentTypeBeforeSignature(t *testing.T) {
+	withConfig(t, nil)
+	before := testutil.ToFloat64(rejectedTotal.WithLabelValues(rejectContentType))
+	r := httptest.NewRequest("POST", "/ask8ball", strings.NewReader("command=/ask8ball"))
+	r.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	if _, ok := verifyCommand(w, r, testSecret); ok || w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("unsigned text/plain command = %v with status %d, want 415", ok, w.Code)
+	}
+	if got := testutil.ToFloat64(rejectedTotal.WithLabelValues(rejectContentType)) - before; got != 1 {
+		t.Errorf("content_type rejections went up by %v, want 1", got)
+	}
+}
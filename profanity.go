@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// defaultProfanityWords is used by FILTER_PROFANITY when PROFANITY_WORDS is
+// not set.
+var defaultProfanityWords = []string{"ass", "bastard", "bitch", "crap", "damn", "fuck", "hell", "piss", "shit"}
+
+// filterProfanity drops the responses containing any of words, matched
+// case-insensitively as whole words, and logs each removal.
+func filterProfanity(resps []string, words []string) []string {
+	out := make([]string, 0, len(resps))
+	for _, text := range resps {
+		if w, ok := containsWord(text, words); ok {
+			logWarn("Dropping response %q: contains %q", text, w)
+			continue
+		}
+		out = append(out, text)
+	}
+	return out
+}
+
+// containsWord returns the first of words found in text as a whole word.
+func containsWord(text string, words []string) (string, bool) {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	for _, w := range words {
+		if slices.Contains(fields, strings.ToLower(w)) {
+			return w, true
+		}
+	}
+	return "", false
+}
+
+// filterOverridesProfanity applies filterProfanity to every custom response
+// list in o, failing rather than leave one empty.
+func filterOverridesProfanity(o responseOverrides, words []string) error {
+	for id, resps := range o.Enterprises {
+		if o.Enterprises[id] = filterProfanity(resps, words); len(o.Enterprises[id]) == 0 {
+			return fmt.Errorf("FILTER_PROFANITY would leave enterprise %s with no responses", id)
+		}
+	}
+	for id, resps := range o.Teams {
+		if o.Teams[id] = filterProfanity(resps, words); len(o.Teams[id]) == 0 {
+			return fmt.Errorf("FILTER_PROFANITY would leave team %s with no responses", id)
+		}
+	}
+	return nil
+}
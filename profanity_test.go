@@ -0,0 +1,77 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFilterProfanity(t *testing.T) {
+	words := []string{"damn", "Heck"}
+	tests := []struct {
+		name  string
+		resps []string
+		want  []string
+	}{
+		{"clean", []string{"Yes.", "No."}, []string{"Yes.", "No."}},
+		{"drops matches", []string{"Yes.", "Damn right.", "No."}, []string{"Yes.", "No."}},
+		{"ignores case of both", []string{"HECK no.", "Maybe."}, []string{"Maybe."}},
+		{"punctuation around the word", []string{"Yes, damn!", "Sure."}, []string{"Sure."}},
+		{"whole words only", []string{"Sure, damnation awaits.", "Check again."}, []string{"Sure, damnation awaits.", "Check again."}},
+		{"can empty the list", []string{"Damn.", "Heck."}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterProfanity(tt.resps, words); !slices.Equal(got, tt.want) {
+				t.Errorf("filterProfanity(%q) = %q, want %q", tt.resps, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterProfanityLogsRemovals(t *testing.T) {
+	logs := captureLog(t)
+	filterProfanity([]string{"Damn right.", "Yes."}, []string{"damn"})
+	if !strings.Contains(logs.String(), `Dropping response "Damn right."`) {
+		t.Errorf("log %q doesn't record the removal", logs)
+	}
+}
+
+func TestProfanityConfig(t *testing.T) {
+	responses := writeFile(t, "responses.json", `["Yes.", "Hell yes.", "Frak no."]`)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    []string
+		wantErr string
+	}{
+		{"off", map[string]string{"RESPONSES_FILE": responses}, []string{"Yes.", "Hell yes.", "Frak no."}, ""},
+		{"default words", map[string]string{"RESPONSES_FILE": responses, "FILTER_PROFANITY": "true"}, []string{"Yes.", "Frak no."}, ""},
+		{"custom words", map[string]string{"RESPONSES_FILE": responses, "FILTER_PROFANITY": "true", "PROFANITY_WORDS": "frak"}, []string{"Yes.", "Hell yes."}, ""},
+		{"would empty the list", map[string]string{"RESPONSES_FILE": responses, "FILTER_PROFANITY": "true", "PROFANITY_WORDS": "yes,no"}, nil, "FILTER_PROFANITY would leave no responses"},
+		{"would empty a team", map[string]string{
+			"RESPONSE_OVERRIDES_FILE": writeFile(t, "overrides.json", `{"teams": {"T1": ["Damn."]}}`),
+			"FILTER_PROFANITY":        "true",
+		}, nil, "FILTER_PROFANITY would leave team T1 with no responses"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			c, err := loadConfig()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("loadConfig error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+			if !slices.Equal(c.Responses, tt.want) {
+				t.Errorf("Responses = %q, want %q", c.Responses, tt.want)
+			}
+		})
+	}
+}
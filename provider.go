@@ -0,0 +1,21 @@
+package main
+
+import "context"
+
+// ResponseProvider supplies the response list answers are picked from, so
+// the lists can live somewhere other than memory without touching the
+// picker. Implementations must be safe for concurrent use.
+type ResponseProvider interface {
+	Responses(ctx context.Context, enterpriseID, teamID, channelID string) ([]string, error)
+}
+
+// liveProvider serves the in-memory lists loaded from the built-in themes
+// and response files, via resolveResponses.
+type liveProvider struct{}
+
+func (liveProvider) Responses(ctx context.Context, enterpriseID, teamID, channelID string) ([]string, error) {
+	return resolveResponses(enterpriseID, teamID, channelID), nil
+}
+
+// responseProvider is where every answer's list comes from.
+var responseProvider ResponseProvider = liveProvider{}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeProvider serves a list per channel and records what it was asked for.
+type fakeProvider struct {
+	mu       sync.Mutex
+	lists    map[string][]string
+	err      error
+	channels []string
+}
+
+func (p *fakeProvider) Responses(ctx context.Context, enterpriseID, teamID, channelID string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.channels = append(p.channels, channelID)
+	return p.lists[channelID], p.err
+}
+
+// withProvider sets responseProvider to p for the rest of the test.
+func withProvider(t *testing.T, p ResponseProvider) {
+	t.Helper()
+	old := responseProvider
+	responseProvider = p
+	t.Cleanup(func() { responseProvider = old })
+}
+
+func TestAskUsesProvider(t *testing.T) {
+	withConfig(t, nil)
+	p := &fakeProvider{lists: map[string][]string{
+		"C1": {"Channel one yes."},
+		"C2": {"Channel two no.", "Channel two maybe."},
+	}}
+	withProvider(t, p)
+
+	tests := []struct {
+		channel string
+		want    []string
+	}{
+		{"C1", []string{"Channel one yes."}},
+		{"C2", []string{"Channel two no.", "Channel two maybe."}},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			resp := ask(askRequest{TeamID: "T1", ChannelID: tt.channel, UserID: "U1", Question: "Will it rain?"})
+			if !slices.Contains(tt.want, resp.Text) {
+				t.Fatalf("answer in %s = %q, want one of %v", tt.channel, resp.Text, tt.want)
+			}
+		}
+	}
+	if !slices.Contains(p.channels, "C1") || !slices.Contains(p.channels, "C2") {
+		t.Errorf("provider asked for %v", p.channels)
+	}
+
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "list", TeamID: "T1", ChannelID: "C2", UserID: "U1"})
+	if !strings.Contains(reply.Text, "Channel two no.") || strings.Contains(reply.Text, "Channel one yes.") {
+		t.Errorf("list in C2 = %q, want the provider's C2 list", reply.Text)
+	}
+}
+
+func TestAskProviderError(t *testing.T) {
+	withConfig(t, nil)
+	logs := captureLog(t)
+	withProvider(t, &fakeProvider{err: errors.New("database is down")})
+	resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"})
+	if resp.Text != replyNoResponses || resp.answered() {
+		t.Errorf("answer with a failing provider = %+v, want %q", resp, replyNoResponses)
+	}
+	if !strings.Contains(logs.String(), "database is down") {
+		t.Errorf("log %q doesn't say why", logs)
+	}
+	if reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "list", TeamID: "T1", ChannelID: "C1", UserID: "U1"}); reply.Text != replyNoResponses {
+		t.Errorf("list with a failing provider = %q", reply.Text)
+	}
+}
+
+func TestLiveProvider(t *testing.T) {
+	overrides := writeFile(t, "overrides.json", `{"teams": {"T2": ["Team yes."]}}`)
+	c := withConfig(t, map[string]string{"RESPONSE_OVERRIDES_FILE": overrides})
+	for _, team := range []string{"T1", "T2"} {
+		got, err := liveProvider{}.Responses(context.Background(), "", team, "C1")
+		if err != nil || !slices.Equal(got, resolveResponses("", team, "C1")) {
+			t.Errorf("liveProvider for %s = %v, %v", team, got, err)
+		}
+	}
+	if got, _ := (liveProvider{}).Responses(context.Background(), "", "T1", "C1"); !slices.Equal(got, c.Responses) {
+		t.Errorf("liveProvider = %v, want the global list", got)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// purgeable is an in-memory store /8ball-purge can wipe.
+type purgeable interface {
+	purge() int
+}
+
+// purgeTarget is a store and what its purge count is a count of.
+type purgeTarget struct {
+	what  string
+	store purgeable
+}
+
+// purgeTargets lists every in-memory store holding per-user or per-channel
+// state, skipping the optional ones that aren't enabled.
+func purgeTargets() []purgeTarget {
+	targets := []purgeTarget{
+		{"users", users},
+		{"channel themes", channelThemes},
+		{"channel strictness settings", channelStrictness},
+		{"answer stats", stats},
+		{"feedback tallies", feedback},
+		{"ask counts", askCounts},
+		{"channel daily counts", channelAnswers},
+		{"user daily counts", userAnswers},
+		{"team daily counts", teamAnswers},
+		{"shuffle bags", bags},
+	}
+	if answerCache != nil {
+		targets = append(targets, purgeTarget{"cached answers", answerCache})
+	}
+	if consensusCache != nil {
+		targets = append(targets, purgeTarget{"consensus answers", consensusCache})
+	}
+	if channelRecent != nil {
+		targets = append(targets, purgeTarget{"channel recent answers", channelRecent})
+	}
+	if answerUsage != nil {
+		targets = append(targets, purgeTarget{"decay counts", answerUsage})
+	}
+	return targets
+}
+
+// purgeAll wipes every store, describing what was cleared.
+func purgeAll() []string {
+	var cleared []string
+	for _, t := range purgeTargets() {
+		cleared = append(cleared, fmt.Sprintf("%d %s", t.store.purge(), t.what))
+	}
+	return cleared
+}
+
+// purgeCommand wipes all in-memory state, for incident response.
+func purgeCommand(cmd slack.SlashCommand) *Reply {
+	cleared := purgeAll()
+	log.Printf("Admin %s purged all state: %s", logID(cmd.UserID), strings.Join(cleared, ", "))
+	return &Reply{Text: "🎱 Purged all in-memory state:\n• " + strings.Join(cleared, "\n• ")}
+}
+
+// handlePurge processes the admin-only /8ball-purge slash command
+func handlePurge(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, adminOnly("purge state", purgeCommand))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// purgeViaHTTP sends /8ball-purge as userID and returns the reply text.
+func purgeViaHTTP(t *testing.T, userID string) string {
+	t.Helper()
+	body := url.Values{"command": {"/8ball-purge"}, "user_id": {userID}, "team_id": {"T1"}, "channel_id": {"C1"}}.Encode()
+	w := httptest.NewRecorder()
+	handlePurge(w, signedRequest("/8ball-purge", formContentType, body), testSecret)
+	var msg slack.WebhookMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("decoding reply %q: %v", w.Body, err)
+	}
+	return msg.Text
+}
+
+// fillStores puts something in every store purgeTargets lists, including
+// the optional ones.
+func fillStores(t *testing.T) {
+	t.Helper()
+	answerCache = newTTLCache(time.Minute)
+	consensusCache = newTTLCache(time.Minute)
+	channelRecent = newRecentAnswers(3)
+	answerUsage = newDecayCounter(time.Hour)
+	t.Cleanup(func() { answerCache, consensusCache, channelRecent, answerUsage = nil, nil, nil, nil })
+
+	for _, user := range []string{"U1", "U2"} {
+		ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: user, Question: "Will it rain?"})
+	}
+	feedbackCommand(slack.SlashCommand{UserID: "U1", Text: "up"})
+	themeCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", Text: "pirate"})
+	strictCommand(slack.SlashCommand{ChannelID: "C1", Text: "off"})
+	answerUsage.use("Yes.", clock())
+}
+
+func TestPurgeEmptiesEveryStore(t *testing.T) {
+	withConfig(t, map[string]string{
+		"ADMIN_USERS":         "UADMIN",
+		"SELECTION_MODE":      "bag",
+		"MILESTONES":          "100",
+		"CHANNEL_DAILY_LIMIT": "10",
+		"USER_DAILY_LIMIT":    "10",
+		"TEAM_RATE_LIMIT":     "10",
+	})
+	fillStores(t)
+
+	reply := purgeViaHTTP(t, "UADMIN")
+	lines := strings.Split(reply, "\n")
+	if lines[0] != "🎱 Purged all in-memory state:" || len(lines) != len(purgeTargets())+1 {
+		t.Fatalf("reply = %q, want a line per store", reply)
+	}
+	for i, target := range purgeTargets() {
+		if line := lines[i+1]; !strings.HasPrefix(line, "• ") || strings.HasPrefix(line, "• 0 ") || !strings.HasSuffix(line, " "+target.what) {
+			t.Errorf("reply line %q, want a count of %s", line, target.what)
+		}
+	}
+	if !strings.Contains(reply, "• 2 user daily counts") {
+		t.Errorf("reply %q doesn't count both users' asks", reply)
+	}
+	for _, target := range purgeTargets() {
+		if n := target.store.purge(); n != 0 {
+			t.Errorf("%d %s left after the purge", n, target.what)
+		}
+	}
+}
+
+func TestPurgeRejectsNonAdmin(t *testing.T) {
+	withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN"})
+	ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"})
+	if reply := purgeViaHTTP(t, "U1"); reply != "Sorry, only admins can purge state." {
+		t.Errorf("non-admin purge replied %q", reply)
+	}
+	if users.len() != 1 {
+		t.Errorf("%d users after a refused purge, want 1", users.len())
+	}
+}
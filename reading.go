@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// readingPrefix starts a question asking for a three-card reading.
+const readingPrefix = "reading:"
+
+// readingLabels name the draws of a reading, tarot-style.
+var readingLabels = []string{"Past", "Present", "Future"}
+
+// readingRequest reports whether text asks for a reading, returning the
+// question without the prefix.
+func readingRequest(text string) (question string, ok bool) {
+	trimmed := strings.TrimSpace(text)
+	if len(trimmed) < len(readingPrefix) || !strings.EqualFold(trimmed[:len(readingPrefix)], readingPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(trimmed[len(readingPrefix):]), true
+}
+
+// readingCommand answers "/ask8ball reading: ..." with three labeled draws.
+// Each draw is an ask of its own, limited, counted and recorded like any
+// other, and avoids the answers already drawn while the list has enough
+// distinct ones. A rejected question or a refusal gets its reply alone.
+func readingCommand(cmd slack.SlashCommand, question, responseType string) *Reply {
+	draws := make([]Response, 0, len(readingLabels))
+	var drawn []string
+	for range readingLabels {
+		resp := ask(askRequest{
+			EnterpriseID: cmd.EnterpriseID,
+			TeamID:       cmd.TeamID,
+			ChannelID:    cmd.ChannelID,
+			UserID:       cmd.UserID,
+			Question:     question,
+			Reroll:       true,
+			Exclude:      drawn,
+		})
+		if !resp.answered() {
+			if len(draws) == 0 {
+				return &Reply{Text: resp.Text}
+			}
+			// A limit was reached part way; show what was drawn.
+			break
+		}
+		draws = append(draws, resp)
+		drawn = append(drawn, resp.Text)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🎱 A reading for: %s", echoText(question))
+	for i, resp := range draws {
+		fmt.Fprintf(&b, "\n*%s:* %s", readingLabels[i], displayText(resp))
+	}
+	log.Printf("Reading with %d draws %s", len(draws), commandFields(cmd))
+	return &Reply{Text: b.String(), ResponseType: responseType}
+}
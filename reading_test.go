@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestReadingRequest(t *testing.T) {
+	tests := []struct {
+		text     string
+		question string
+		ok       bool
+	}{
+		{"reading: will the project succeed?", "will the project succeed?", true},
+		{"  READING:Will it rain? ", "Will it rain?", true},
+		{"reading:", "", true},
+		{"reading will it rain?", "", false},
+		{"a reading: will it rain?", "", false},
+		{"read", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if q, ok := readingRequest(tt.text); q != tt.question || ok != tt.ok {
+			t.Errorf("readingRequest(%q) = %q, %v; want %q, %v", tt.text, q, ok, tt.question, tt.ok)
+		}
+	}
+}
+
+// readingDraws returns the answer on each labeled line of a reading.
+func readingDraws(t *testing.T, text string) []string {
+	t.Helper()
+	lines := strings.Split(text, "\n")
+	var draws []string
+	for i, line := range lines[1:] {
+		answer, ok := strings.CutPrefix(line, "*"+readingLabels[i]+":* ")
+		if !ok {
+			t.Fatalf("line %d of %q isn't labeled %s", i+2, text, readingLabels[i])
+		}
+		draws = append(draws, answer)
+	}
+	return draws
+}
+
+func TestReadingCommand(t *testing.T) {
+	// Strict questions would turn "reading: will..." away, so an answer at
+	// all shows the prefix is gone before selection.
+	c := withConfig(t, map[string]string{"STRICT_QUESTIONS": "true"})
+	for range 20 {
+		reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "reading: will the project succeed?", TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+		if first, _, _ := strings.Cut(reply.Text, "\n"); first != "🎱 A reading for: will the project succeed?" {
+			t.Fatalf("reading = %q", reply.Text)
+		}
+		draws := readingDraws(t, reply.Text)
+		if len(draws) != 3 {
+			t.Fatalf("reading has %d draws, want 3: %q", len(draws), reply.Text)
+		}
+		for _, d := range draws {
+			if !slices.Contains(c.Responses, d) {
+				t.Errorf("drew %q, not a response", d)
+			}
+		}
+		if distinct := slices.Compact(slices.Sorted(slices.Values(draws))); len(distinct) != 3 {
+			t.Errorf("draws %v repeat though there are %d responses", draws, len(c.Responses))
+		}
+		if reply.ResponseType == slack.ResponseTypeInChannel {
+			t.Error("reading is public without --public")
+		}
+	}
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "reading: will it rain? --public", TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+	if reply.ResponseType != slack.ResponseTypeInChannel {
+		t.Errorf("reading with --public is %q", reply.ResponseType)
+	}
+}
+
+func TestReadingFewResponses(t *testing.T) {
+	withConfig(t, nil)
+	withProvider(t, &fakeProvider{lists: map[string][]string{"C1": {"Yes.", "No."}}})
+	reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: "reading: will it rain?", TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+	draws := readingDraws(t, reply.Text)
+	if len(draws) != 3 || draws[0] == draws[1] {
+		t.Errorf("draws from two responses = %v, want the first two distinct", draws)
+	}
+}
+
+func TestReadingRefusals(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		text      string
+		want      string
+		wantDraws int
+	}{
+		{"rejected question", map[string]string{"STRICT_QUESTIONS": "true"}, "reading: why is it raining?", replyOpenEnded, 0},
+		{"limit part way", map[string]string{"USER_DAILY_LIMIT": "2"}, "reading: will it rain?", "🎱 A reading for: will it rain?", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			reply := askCommand(slack.SlashCommand{Command: "/ask8ball", Text: tt.text, TeamID: "T1", ChannelID: "C1", UserID: "U1"})
+			if !strings.HasPrefix(reply.Text, tt.want) {
+				t.Fatalf("reply = %q, want %q", reply.Text, tt.want)
+			}
+			if tt.wantDraws > 0 {
+				if draws := readingDraws(t, reply.Text); len(draws) != tt.wantDraws {
+					t.Errorf("%d draws, want %d", len(draws), tt.wantDraws)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import "math/rand"
+
+// categoryReasons are the mock rationales SHOW_REASON picks from for each
+// category.
+var categoryReasons = map[string][]string{
+	categoryYes: {
+		"the stars are unusually aligned.",
+		"Mercury is finally out of retrograde.",
+		"the tea leaves were unanimous.",
+		"a passing pigeon nodded.",
+	},
+	categoryNo: {
+		"the tides are against it.",
+		"a black cat crossed the server room.",
+		"the tea leaves were not amused.",
+		"Saturn has concerns.",
+	},
+	categoryMaybe: {
+		"the spirits are on a coffee break.",
+		"the crystal ball needs a firmware update.",
+		"the fog in here is thicker than usual.",
+		"the omens are still loading.",
+	},
+}
+
+// reasonFor picks a rationale for an answer in category, or "" if the
+// category has none.
+func reasonFor(category string, r *rand.Rand) string {
+	set := categoryReasons[category]
+	if len(set) == 0 {
+		return ""
+	}
+	return set[r.Intn(len(set))]
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReasonFor(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for _, category := range []string{categoryYes, categoryNo, categoryMaybe} {
+		seen := map[string]bool{}
+		for range 100 {
+			reason := reasonFor(category, r)
+			if !slices.Contains(categoryReasons[category], reason) {
+				t.Fatalf("reasonFor(%s) = %q, not one of its reasons", category, reason)
+			}
+			seen[reason] = true
+		}
+		if len(seen) != len(categoryReasons[category]) {
+			t.Errorf("reasonFor(%s) gave %d of its %d reasons in 100 draws", category, len(seen), len(categoryReasons[category]))
+		}
+	}
+	if got := reasonFor("", r); got != "" {
+		t.Errorf("reasonFor without a category = %q, want none", got)
+	}
+}
+
+func TestAskShowReason(t *testing.T) {
+	for _, show := range []bool{true, false} {
+		withConfig(t, map[string]string{"SHOW_REASON": strconv.FormatBool(show)})
+		for range 50 {
+			resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?", Reroll: true})
+			if !show {
+				if resp.Reason != "" {
+					t.Fatalf("SHOW_REASON=false: %q came with reason %q", resp.Text, resp.Reason)
+				}
+				continue
+			}
+			if !slices.Contains(categoryReasons[resp.Category], resp.Reason) {
+				t.Fatalf("%s answer %q came with reason %q, not one of the category's", resp.Category, resp.Text, resp.Reason)
+			}
+			if want := resp.Text + " — " + resp.Reason; !strings.HasPrefix(displayText(resp), want) {
+				t.Fatalf("displayed %q, want it to start %q", displayText(resp), want)
+			}
+		}
+		// Questions that aren't answered get no reason.
+		if resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Why is it raining?"}); resp.Reason != "" {
+			t.Errorf("rejected question came with reason %q", resp.Reason)
+		}
+	}
+}
+
+func TestHandleWebhookAskReason(t *testing.T) {
+	withConfig(t, map[string]string{"SHOW_REASON": "true", "WEBHOOK_TOKEN": "ci-token"})
+	r := httptest.NewRequest("POST", "/webhook/ask", strings.NewReader(`{"question":"Will the build pass?"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer ci-token")
+	w := httptest.NewRecorder()
+	handleWebhookAsk(w, r)
+	var answer webhookAnswer
+	if err := json.Unmarshal(w.Body.Bytes(), &answer); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body, err)
+	}
+	if !slices.Contains(categoryReasons[answer.Category], answer.Reason) {
+		t.Errorf("webhook answer %+v, want a reason for its category", answer)
+	}
+}
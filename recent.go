@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// maxRecentChannels bounds how many channels have a recent-answers buffer;
+// the channel that started being tracked longest ago is dropped first.
+const maxRecentChannels = 5000
+
+// recentAnswers remembers the last few answers given in each channel so the
+// picker can avoid handing consecutive askers the same one.
+type recentAnswers struct {
+	size int
+
+	mu       sync.Mutex
+	channels map[string][]string // most recent last
+	order    []string            // channels, oldest first
+}
+
+func newRecentAnswers(size int) *recentAnswers {
+	return &recentAnswers{size: size, channels: make(map[string][]string)}
+}
+
+// channelRecent is set at startup when CHANNEL_RECENT_ANSWERS is configured.
+var channelRecent *recentAnswers
+
+// add records answer as the latest given in channelID.
+func (r *recentAnswers) add(channelID, answer string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buf, ok := r.channels[channelID]
+	if !ok {
+		if len(r.order) >= maxRecentChannels {
+			delete(r.channels, r.order[0])
+			r.order = r.order[1:]
+		}
+		r.order = append(r.order, channelID)
+	}
+	buf = append(buf, answer)
+	if len(buf) > r.size {
+		buf = buf[len(buf)-r.size:]
+	}
+	r.channels[channelID] = buf
+}
+
+// avoid returns resps without the answers recently given in channelID, or
+// resps itself if that would leave nothing to pick from.
+func (r *recentAnswers) avoid(channelID string, resps []string) []string {
+	r.mu.Lock()
+	recent := r.channels[channelID]
+	r.mu.Unlock()
+	if len(recent) == 0 {
+		return resps
+	}
+
+	out := make([]string, 0, len(resps))
+	for _, text := range resps {
+		if !slices.Contains(recent, text) {
+			out = append(out, text)
+		}
+	}
+	if len(out) == 0 {
+		return resps
+	}
+	return out
+}
+
+// purge forgets every channel's recent answers, returning how many channels
+// there were.
+func (r *recentAnswers) purge() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.channels)
+	clear(r.channels)
+	r.order = nil
+	return n
+}
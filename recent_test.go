@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestRecentAnswersAvoid(t *testing.T) {
+	resps := []string{"Yes.", "No.", "Maybe."}
+	r := newRecentAnswers(2)
+	r.add("C1", "Yes.")
+	r.add("C1", "No.")
+
+	tests := []struct {
+		name    string
+		channel string
+		want    []string
+	}{
+		{"avoids recent", "C1", []string{"Maybe."}},
+		{"other channel", "C2", resps},
+	}
+	for _, tt := range tests {
+		if got := r.avoid(tt.channel, resps); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: avoid = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := r.avoid("C1", []string{"Yes.", "No."}); !slices.Equal(got, []string{"Yes.", "No."}) {
+		t.Errorf("avoid with nothing else to pick = %q, want the full list", got)
+	}
+
+	r.add("C1", "Maybe.")
+	if got := r.avoid("C1", resps); !slices.Equal(got, []string{"Yes."}) {
+		t.Errorf("avoid after the buffer rolled = %q, want only the oldest back", got)
+	}
+}
+
+func TestRecentAnswersBounds(t *testing.T) {
+	r := newRecentAnswers(3)
+	for i := range 10 {
+		r.add("C1", strconv.Itoa(i))
+	}
+	if got := r.channels["C1"]; !slices.Equal(got, []string{"7", "8", "9"}) {
+		t.Errorf("C1 keeps %q, want the last 3", got)
+	}
+	for i := range maxRecentChannels + 5 {
+		r.add("C"+strconv.Itoa(i+2), "Yes.")
+	}
+	if n := len(r.channels); n != maxRecentChannels {
+		t.Errorf("tracking %d channels, want %d", n, maxRecentChannels)
+	}
+	if _, ok := r.channels["C1"]; ok {
+		t.Error("the oldest channel wasn't dropped")
+	}
+}
+
+func TestAskAvoidsChannelRepeats(t *testing.T) {
+	path := writeFile(t, "responses.json", `["Yes.", "No.", "Maybe.", "Ask again later."]`)
+	withConfig(t, map[string]string{"RESPONSES_FILE": path, "CHANNEL_RECENT_ANSWERS": "3"})
+	seedRNG(t, 1)
+	channelRecent = newRecentAnswers(3)
+	t.Cleanup(func() { channelRecent = nil })
+
+	// With four answers and the last three avoided, each answer is the one
+	// not given in the previous three, whichever user asks.
+	var given []string
+	for i := range 40 {
+		resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U" + strconv.Itoa(i%5), Question: "Will it rain?", Reroll: true})
+		if !resp.answered() {
+			t.Fatalf("ask = %q, want an answer", resp.Text)
+		}
+		if start := max(0, len(given)-3); slices.Contains(given[start:], resp.Text) {
+			t.Fatalf("answer %d, %q, repeats one of %q", i, resp.Text, given[start:])
+		}
+		if want := slices.Index([]string{"Yes.", "No.", "Maybe.", "Ask again later."}, resp.Text); resp.Index != want {
+			t.Fatalf("index = %d, want %d into the full list", resp.Index, want)
+		}
+		given = append(given, resp.Text)
+	}
+}
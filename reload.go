@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/slack-go/slack"
+)
+
+// responseSet is the part of the configuration that can be reloaded while
+// running: the global response list, chosen by THEME or read from a file,
+// the themes channels can pick, the per-workspace overrides and the feature
+// flags.
+type responseSet struct {
+	Responses  []string
+	Themes     map[string][]string
+	Overrides  responseOverrides
+	Provenance responsesProvenance
+	Flags      featureFlags
+	Lengths    map[string][]string
+}
+
+func newResponseSet(c Config) *responseSet {
+	return &responseSet{Responses: c.Responses, Themes: c.Themes, Overrides: c.Overrides, Provenance: c.Provenance, Flags: flagsOf(c), Lengths: c.LengthResponses}
+}
+
+// featureFlags are the on/off features a reload can change while running,
+// tagged with their environment variables.
+type featureFlags struct {
+	EmojiPrefix       bool `env:"EMOJI_PREFIX"`
+	MirrorCase        bool `env:"MIRROR_CASE"`
+	WindowArt         bool `env:"WINDOW_ART"`
+	MrkdwnEmphasis    bool `env:"MRKDWN_EMPHASIS"`
+	ShowReason        bool `env:"SHOW_REASON"`
+	LuckyNumber       bool `env:"LUCKY_NUMBER"`
+	ShowFooter        bool `env:"SHOW_FOOTER"`
+	Mood              bool `env:"MOOD"`
+	DeflectThirdParty bool `env:"DEFLECT_THIRD_PARTY"`
+	DebugLogBody      bool `env:"DEBUG_LOG_BODY"`
+	DebugTrace        bool `env:"DEBUG_TRACE"`
+}
+
+// flagsOf returns c's feature flags.
+func flagsOf(c Config) featureFlags {
+	return featureFlags{
+		EmojiPrefix:       c.EmojiPrefix,
+		MirrorCase:        c.MirrorCase,
+		WindowArt:         c.WindowArt,
+		MrkdwnEmphasis:    c.MrkdwnEmphasis,
+		ShowReason:        c.ShowReason,
+		LuckyNumber:       c.LuckyNumber,
+		ShowFooter:        c.ShowFooter,
+		Mood:              c.Mood,
+		DeflectThirdParty: c.DeflectThirdParty,
+		DebugLogBody:      c.DebugLogBody,
+		DebugTrace:        c.DebugTrace,
+	}
+}
+
+// liveFlags returns the active feature flags. Code paths a reload can
+// change read their flag from here rather than from cfg.
+func liveFlags() featureFlags {
+	return currentResponses().Flags
+}
+
+// changedFlags describes each flag that differs between old and new, such as
+// "MOOD false → true".
+func changedFlags(old, new featureFlags) []string {
+	var changes []string
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := range ov.NumField() {
+		if o, n := ov.Field(i).Bool(), nv.Field(i).Bool(); o != n {
+			changes = append(changes, fmt.Sprintf("%s %t → %t", ov.Type().Field(i).Tag.Get("env"), o, n))
+		}
+	}
+	return changes
+}
+
+// liveResponses is the active responseSet, replaced whole by each reload so
+// readers always see a consistent list.
+var liveResponses atomic.Pointer[responseSet]
+
+// currentResponses returns the active responseSet, falling back to cfg's
+// until one has been stored.
+func currentResponses() *responseSet {
+	if s := liveResponses.Load(); s != nil {
+		return s
+	}
+	return newResponseSet(cfg)
+}
+
+// reloadMu serializes reloads. A reload arriving while another runs waits
+// for it rather than being skipped, so the last one to finish has read the
+// newest files.
+var reloadMu sync.Mutex
+
+// reloadResponses re-reads the configuration from the environment and
+// files and swaps in what can change while running: the responses, and so
+// THEME, the feature flags and LOG_LEVEL. It logs what changed. On error
+// nothing changes.
+func reloadResponses() (*responseSet, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	c, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	s := newResponseSet(c)
+	old := currentResponses()
+	liveResponses.Store(s)
+
+	var changes []string
+	if old.Provenance != s.Provenance || !slices.Equal(old.Responses, s.Responses) {
+		changes = append(changes, "responses now "+s.Provenance.String())
+	}
+	changes = append(changes, changedFlags(old.Flags, s.Flags)...)
+	if oldLevel := logLevel.Level(); oldLevel != c.LogLevel {
+		logLevel.Set(c.LogLevel)
+		changes = append(changes, fmt.Sprintf("LOG_LEVEL %s → %s", oldLevel, c.LogLevel))
+	}
+	if len(changes) == 0 {
+		log.Printf("Reloaded configuration: unchanged, using %s", s.Provenance)
+	} else {
+		log.Printf("Reloaded configuration: %s", strings.Join(changes, ", "))
+	}
+	// Everything else is read without locking, so it only changes on restart.
+	if c.Addr != cfg.Addr {
+		logWarn("ignoring the changed listen address %s until restart", c.Addr)
+	}
+	return s, nil
+}
+
+// reloadOnHangup reloads the configuration whenever the process gets SIGHUP,
+// until ctx is done.
+func reloadOnHangup(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if _, err := reloadResponses(); err != nil {
+				logError("Error reloading on SIGHUP, keeping the current configuration: %v", err)
+			}
+		}
+	}
+}
+
+// reloadCommand reloads the configuration on an admin's request.
+func reloadCommand(cmd slack.SlashCommand) *Reply {
+	s, err := reloadResponses()
+	if err != nil {
+		logError("Error reloading responses: %v", err)
+		return &Reply{Text: "🎱 Reload failed, so nothing changed: " + err.Error()}
+	}
+	log.Printf("Admin %s reloaded the responses", logID(cmd.UserID))
+	return &Reply{Text: "🎱 Reloaded: using " + s.Provenance.String() + "."}
+}
+
+// handleReload processes the admin-only /8ball-reload slash command
+func handleReload(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, adminOnly("reload the responses", reloadCommand))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestReloadResponses(t *testing.T) {
+	path := writeFile(t, "responses.json", `["Yes.", "No."]`)
+	withConfig(t, map[string]string{"RESPONSES_FILE": path})
+	if err := os.WriteFile(path, []byte(`["Maybe.", "Ask again later."]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reply := reloadCommand(slack.SlashCommand{UserID: "UADMIN"})
+	if !strings.Contains(reply.Text, "Reloaded: using 2 responses from file "+path) {
+		t.Errorf("reload reply = %q", reply.Text)
+	}
+	if got := currentResponses().Responses; !slices.Equal(got, []string{"Maybe.", "Ask again later."}) {
+		t.Errorf("responses after reload = %v", got)
+	}
+
+	// A broken file leaves the active list alone.
+	if err := os.WriteFile(path, []byte(`not json`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if reply := reloadCommand(slack.SlashCommand{UserID: "UADMIN"}); !strings.Contains(reply.Text, "Reload failed, so nothing changed") {
+		t.Errorf("broken reload reply = %q", reply.Text)
+	}
+	if got := currentResponses().Responses; !slices.Equal(got, []string{"Maybe.", "Ask again later."}) {
+		t.Errorf("responses after a failed reload = %v", got)
+	}
+}
+
+func TestChangedFlags(t *testing.T) {
+	got := changedFlags(featureFlags{Mood: false, ShowFooter: true}, featureFlags{Mood: true, ShowFooter: true})
+	if !slices.Equal(got, []string{"MOOD false → true"}) {
+		t.Errorf("changedFlags = %v", got)
+	}
+	if got := changedFlags(featureFlags{}, featureFlags{}); len(got) != 0 {
+		t.Errorf("changedFlags of equal flags = %v", got)
+	}
+}
+
+// TestConcurrentReloads reloads from two goroutines while the file flips
+// between two lists. Every list seen, and the one left active, must be one
+// of them in full. Run with -race to check the swaps are synchronized.
+func TestConcurrentReloads(t *testing.T) {
+	lists := [][]string{{"Yes.", "No."}, {"Maybe.", "Ask again later.", "Cannot predict now."}}
+	contents := []string{`["Yes.", "No."]`, `["Maybe.", "Ask again later.", "Cannot predict now."]`}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "responses.json")
+	if err := os.WriteFile(path, []byte(contents[0]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withConfig(t, map[string]string{"RESPONSES_FILE": path})
+
+	valid := func(list []string) bool {
+		return slices.Equal(list, lists[0]) || slices.Equal(list, lists[1])
+	}
+	const rounds = 50
+	var wg sync.WaitGroup
+	for g := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				// Swap the file in whole, as editors and config
+				// management do, so each reload reads one list or the other.
+				tmp := filepath.Join(dir, "next"+string(rune('a'+g)))
+				if err := os.WriteFile(tmp, []byte(contents[(i+g)%2]), 0o644); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := os.Rename(tmp, path); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := reloadResponses(); err != nil {
+					t.Errorf("reload: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range rounds * 4 {
+			if s := currentResponses(); !valid(s.Responses) || s.Provenance.Count != len(s.Responses) {
+				t.Errorf("saw %v (count %d) mid-reload", s.Responses, s.Provenance.Count)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	final := currentResponses().Responses
+	if !valid(final) {
+		t.Fatalf("active list after concurrent reloads = %v", final)
+	}
+	// The last reload read the file as it is now.
+	want := lists[0]
+	if data, _ := os.ReadFile(path); string(data) == contents[1] {
+		want = lists[1]
+	}
+	if !slices.Equal(final, want) {
+		t.Errorf("active list = %v, want the file's %v", final, want)
+	}
+}
+
+func TestReloadAppliesConfig(t *testing.T) {
+	withConfig(t, map[string]string{"LOG_LEVEL": "info", "THEME": "classic", "MOOD": "false", "PORT": "8080"})
+	oldLevel := logLevel.Level()
+	t.Cleanup(func() { logLevel.Set(oldLevel) })
+	logLevel.Set(slog.LevelInfo)
+	logs := captureLog(t)
+
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("THEME", "pirate")
+	t.Setenv("MOOD", "true")
+	t.Setenv("PORT", "9090")
+	if _, err := reloadResponses(); err != nil {
+		t.Fatal(err)
+	}
+	if got := logLevel.Level(); got != slog.LevelWarn {
+		t.Errorf("log level after reload = %v, want WARN", got)
+	}
+	if got := currentResponses().Responses; !slices.Equal(got, cfg.Themes["pirate"]) {
+		t.Errorf("responses after reload = %v, want the pirate theme", got)
+	}
+	if !liveFlags().Mood {
+		t.Error("MOOD still off after reload")
+	}
+	for _, want := range []string{"LOG_LEVEL INFO → WARN", "MOOD false → true", "built-in theme \"pirate\"", "ignoring the changed listen address"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("reload log %q lacks %q", logs, want)
+		}
+	}
+	if cfg.Addr != ":8080" {
+		t.Errorf("listen address = %q, want it kept until restart", cfg.Addr)
+	}
+}
+
+func TestReloadAppliesThemes(t *testing.T) {
+	c := withConfig(t, nil)
+	if err := channelThemes.set("C1", "pirate"); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolveResponses("", "T1", "C1"); !slices.Equal(got, c.Themes["pirate"]) {
+		t.Fatalf("C1's responses = %v, want the pirate theme", got)
+	}
+
+	t.Setenv("DISABLED_RESPONSES", "Nay.")
+	if _, err := reloadResponses(); err != nil {
+		t.Fatal(err)
+	}
+	got := resolveResponses("", "T1", "C1")
+	if slices.Contains(got, "Nay.") || len(got) != len(c.Themes["pirate"])-1 {
+		t.Errorf("C1's responses after reload = %v, want the pirate theme without \"Nay.\"", got)
+	}
+	if answers, err := previewAnswers("pirate", len(got)+1, rand.New(rand.NewSource(1))); err != nil || slices.Contains(answers, "Nay.") {
+		t.Errorf("previewAnswers(pirate) after reload = %v, %v; want \"Nay.\" gone", answers, err)
+	}
+}
+
+func TestReloadOnHangup(t *testing.T) {
+	withConfig(t, map[string]string{"LOG_LEVEL": "info"})
+	oldLevel := logLevel.Level()
+	t.Cleanup(func() { logLevel.Set(oldLevel) })
+	logLevel.Set(slog.LevelInfo)
+
+	// Catch SIGHUP for the whole test, so a signal sent before
+	// reloadOnHangup starts listening can't kill the test binary.
+	guard := make(chan os.Signal, 16)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reloadOnHangup(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	t.Setenv("LOG_LEVEL", "error")
+	deadline := time.Now().Add(5 * time.Second)
+	for logLevel.Level() != slog.LevelError {
+		if time.Now().After(deadline) {
+			t.Fatalf("log level = %v after SIGHUP, want ERROR", logLevel.Level())
+		}
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
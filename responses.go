@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// applyBlacklist removes the disabled texts from resps. Entries must match a
+// response exactly. It fails rather than leave the 8-ball with nothing to say.
+func applyBlacklist(resps []string, disabled []string) ([]string, error) {
+	out := make([]string, 0, len(resps))
+	for _, text := range resps {
+		if !slices.Contains(disabled, text) {
+			out = append(out, text)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("DISABLED_RESPONSES would leave no responses")
+	}
+	return out, nil
+}
+
+// findDuplicates returns the texts that appear more than once in resps, in
+// order of their first repeat.
+func findDuplicates(resps []string) []string {
+	seen := make(map[string]int, len(resps))
+	var dups []string
+	for _, text := range resps {
+		seen[text]++
+		if seen[text] == 2 {
+			dups = append(dups, text)
+		}
+	}
+	return dups
+}
+
+// checkDuplicates reports duplicate texts in every response list of c, since
+// they silently skew the odds. Duplicates are logged, or returned as an
+// error when c.StrictResponses is set.
+func checkDuplicates(c Config) error {
+	lists := make(map[string][]string)
+	for name, resps := range c.Themes {
+		lists["theme "+name] = resps
+	}
+	// A blend repeats answers on purpose, but a responses file shouldn't.
+	if c.Provenance.Source == provenanceFile {
+		lists["responses file "+c.Provenance.Path] = c.Responses
+	}
+	for id, resps := range c.Overrides.Enterprises {
+		lists["enterprise "+id] = resps
+	}
+	for id, resps := range c.Overrides.Teams {
+		lists["team "+id] = resps
+	}
+
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		dups := findDuplicates(lists[name])
+		if len(dups) == 0 {
+			continue
+		}
+		if c.StrictResponses {
+			return fmt.Errorf("%s has duplicate responses: %q", name, dups)
+		}
+		logWarn("%s has duplicate responses: %q", name, dups)
+	}
+	return nil
+}
+
+// filterThemes applies the blacklist to every theme, logging disabled
+// entries that match no theme at all.
+func filterThemes(themes map[string][]string, disabled []string) (map[string][]string, error) {
+	for _, d := range disabled {
+		found := false
+		for _, resps := range themes {
+			found = found || slices.Contains(resps, d)
+		}
+		if !found {
+			logWarn("disabled response %q is not in any theme", d)
+		}
+	}
+
+	out := make(map[string][]string, len(themes))
+	for name, resps := range themes {
+		filtered, err := applyBlacklist(resps, disabled)
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %w", name, err)
+		}
+		out[name] = filtered
+	}
+	return out, nil
+}
+
+// responseOverrides maps Enterprise Grid and team IDs to their own response
+// lists, loaded from RESPONSE_OVERRIDES_FILE. Each response is either a plain
+// string or an object carrying provenance:
+//
+//	{"enterprises": {"E123": ["..."]},
+//	 "teams": {"T456": ["...", {"text": "...", "category": "yes", "author": "sam", "added_at": "2024-05-01T00:00:00Z"}]}}
+type responseOverrides struct {
+	Enterprises map[string][]string `json:"enterprises"`
+	Teams       map[string][]string `json:"teams"`
+
+	// Meta holds the metadata given for responses in object form, by text.
+	Meta map[string]responseEntry `json:"meta,omitempty"`
+}
+
+// responseEntry is one response in a response file, with its optional
+// metadata.
+type responseEntry struct {
+	Text     string    `json:"text"`
+	Category string    `json:"category,omitempty"`
+	Author   string    `json:"author,omitempty"`
+	AddedAt  time.Time `json:"added_at,omitzero"`
+}
+
+// UnmarshalJSON accepts either a plain string or an object.
+func (e *responseEntry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*e = responseEntry{}
+		return json.Unmarshal(data, &e.Text)
+	}
+
+	// plain has responseEntry's fields but not this method.
+	type plain responseEntry
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.Text == "" {
+		return errors.New("response object has no text")
+	}
+	if p.Category != "" && !knownCategory(p.Category) {
+		return fmt.Errorf("response %q has unknown category %q", p.Text, p.Category)
+	}
+	*e = responseEntry(p)
+	return nil
+}
+
+// entryTexts returns the texts of entries, recording in meta those that
+// carry metadata.
+func entryTexts(entries []responseEntry, meta map[string]responseEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Text
+		if e != (responseEntry{Text: e.Text}) {
+			meta[e.Text] = e
+		}
+	}
+	return out
+}
+
+// loadResponsesFile reads the global response list from RESPONSES_FILE, a
+// JSON array of responses in the same forms as the overrides file.
+func loadResponsesFile(path string) ([]responseEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading responses file: %w", err)
+	}
+	var entries []responseEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing responses file %s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("responses file %s has no responses", path)
+	}
+	return entries, nil
+}
+
+// loadOverrides reads and validates a response overrides file.
+func loadOverrides(path string) (responseOverrides, error) {
+	var o responseOverrides
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return o, fmt.Errorf("reading response overrides: %w", err)
+	}
+	var file struct {
+		Enterprises map[string][]responseEntry `json:"enterprises"`
+		Teams       map[string][]responseEntry `json:"teams"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return o, fmt.Errorf("parsing response overrides %s: %w", path, err)
+	}
+
+	o.Meta = make(map[string]responseEntry)
+	o.Enterprises = make(map[string][]string, len(file.Enterprises))
+	for id, entries := range file.Enterprises {
+		if len(entries) == 0 {
+			return o, fmt.Errorf("response overrides %s: enterprise %s has no responses", path, id)
+		}
+		o.Enterprises[id] = entryTexts(entries, o.Meta)
+	}
+	o.Teams = make(map[string][]string, len(file.Teams))
+	for id, entries := range file.Teams {
+		if len(entries) == 0 {
+			return o, fmt.Errorf("response overrides %s: team %s has no responses", path, id)
+		}
+		o.Teams[id] = entryTexts(entries, o.Meta)
+	}
+	return o, nil
+}
+
+// overrideScope names the override resolveResponses serves enterpriseID and
+// teamID instead of any channel theme: "organization" or "workspace", or ""
+// if there is none.
+func overrideScope(enterpriseID, teamID string) string {
+	live := currentResponses()
+	if _, ok := live.Overrides.Enterprises[enterpriseID]; ok && enterpriseID != "" {
+		return "organization"
+	}
+	if _, ok := live.Overrides.Teams[teamID]; ok && teamID != "" {
+		return "workspace"
+	}
+	return ""
+}
+
+// resolveResponses returns the response list for a request, preferring an
+// enterprise override, then a team override, then the channel's theme, then
+// the global list.
+func resolveResponses(enterpriseID, teamID, channelID string) []string {
+	live := currentResponses()
+	if resps, ok := live.Overrides.Enterprises[enterpriseID]; ok && enterpriseID != "" {
+		return resps
+	}
+	if resps, ok := live.Overrides.Teams[teamID]; ok && teamID != "" {
+		return resps
+	}
+	if theme, ok := channelThemes.get(channelID); ok {
+		return live.Themes[theme]
+	}
+	return live.Responses
+}
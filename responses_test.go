@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyBlacklist(t *testing.T) {
+	resps := []string{"Yes.", "No.", "Maybe."}
+	tests := []struct {
+		name     string
+		disabled []string
+		want     []string
+	}{
+		{"filters", []string{"No."}, []string{"Yes.", "Maybe."}},
+		{"no-op", nil, resps},
+		{"unknown text", []string{"Perhaps."}, resps},
+		{"exact match only", []string{"no."}, resps},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyBlacklist(resps, tt.disabled)
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("applyBlacklist = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyBlacklistEmptiesList(t *testing.T) {
+	if got, err := applyBlacklist([]string{"Yes.", "No."}, []string{"No.", "Yes."}); err == nil {
+		t.Errorf("applyBlacklist = %q, want an error", got)
+	}
+}
+
+func TestDisabledResponsesConfig(t *testing.T) {
+	c := withConfig(t, map[string]string{"DISABLED_RESPONSES": "It is certain., Very doubtful."})
+	for _, text := range []string{"It is certain.", "Very doubtful."} {
+		if slices.Contains(c.Responses, text) {
+			t.Errorf("%q is still in the responses", text)
+		}
+	}
+	if !slices.Contains(c.Responses, "Without a doubt.") {
+		t.Error("DISABLED_RESPONSES removed answers it doesn't name")
+	}
+}
+
+// writeFile writes content to name in a fresh temporary directory and
+// returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveResponses(t *testing.T) {
+	path := writeFile(t, "overrides.json", `{
+		"enterprises": {"E1": ["Enterprise yes."]},
+		"teams": {"T1": ["Team yes."], "T2": [{"text": "Team two yes.", "category": "yes"}]}
+	}`)
+	c := withConfig(t, map[string]string{"RESPONSE_OVERRIDES_FILE": path})
+
+	tests := []struct {
+		name         string
+		enterpriseID string
+		teamID       string
+		want         []string
+		wantScope    string
+	}{
+		{"enterprise override", "E1", "T1", []string{"Enterprise yes."}, "organization"},
+		{"enterprise override for another team", "E1", "T9", []string{"Enterprise yes."}, "organization"},
+		{"team override", "E9", "T1", []string{"Team yes."}, "workspace"},
+		{"team override without enterprise", "", "T2", []string{"Team two yes."}, "workspace"},
+		{"global fallback", "E9", "T9", c.Responses, ""},
+		{"no IDs", "", "", c.Responses, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveResponses(tt.enterpriseID, tt.teamID, "C1"); !slices.Equal(got, tt.want) {
+				t.Errorf("resolveResponses = %q, want %q", got, tt.want)
+			}
+			if got := overrideScope(tt.enterpriseID, tt.teamID); got != tt.wantScope {
+				t.Errorf("overrideScope = %q, want %q", got, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestLoadOverridesRejectsEmptyList(t *testing.T) {
+	for _, content := range []string{`{"teams": {"T1": []}}`, `{"enterprises": {"E1": []}}`, `{"teams": `} {
+		path := writeFile(t, "overrides.json", content)
+		if _, err := loadOverrides(path); err == nil {
+			t.Errorf("loadOverrides(%s) succeeded, want an error", content)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		resps []string
+		want  []string
+	}{
+		{"clean", []string{"Yes.", "No.", "Maybe."}, nil},
+		{"empty", nil, nil},
+		{"duplicates", []string{"Yes.", "No.", "Yes.", "Maybe.", "No.", "Yes."}, []string{"Yes.", "No."}},
+		{"exact match only", []string{"Yes.", "yes.", "Yes"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findDuplicates(tt.resps); !slices.Equal(got, tt.want) {
+				t.Errorf("findDuplicates = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateResponsesConfig(t *testing.T) {
+	dupFile := writeFile(t, "responses.json", `["Yes.", "No.", "Yes."]`)
+	cleanFile := writeFile(t, "clean.json", `["Yes.", "No."]`)
+	dupOverrides := writeFile(t, "overrides.json", `{"teams": {"T1": ["Yes.", "Yes."]}}`)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+		wantLog string
+	}{
+		{"clean strict", map[string]string{"RESPONSES_FILE": cleanFile, "STRICT_RESPONSES": "true"}, false, ""},
+		{"duplicates lenient", map[string]string{"RESPONSES_FILE": dupFile}, false, `duplicate responses: ["Yes."]`},
+		{"duplicates strict", map[string]string{"RESPONSES_FILE": dupFile, "STRICT_RESPONSES": "true"}, true, ""},
+		{"override duplicates lenient", map[string]string{"RESPONSE_OVERRIDES_FILE": dupOverrides}, false, `team T1 has duplicate responses: ["Yes."]`},
+		{"override duplicates strict", map[string]string{"RESPONSE_OVERRIDES_FILE": dupOverrides, "STRICT_RESPONSES": "true"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			buf := captureLog(t)
+			_, err := loadConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), `duplicate responses: ["Yes."]`) {
+				t.Errorf("loadConfig error %q doesn't name the duplicate", err)
+			}
+			if tt.wantLog != "" && !strings.Contains(buf.String(), tt.wantLog) {
+				t.Errorf("log %q lacks %q", buf.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestResponseEntryUnmarshal(t *testing.T) {
+	added := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		json    string
+		want    []responseEntry
+		wantErr bool
+	}{
+		{"strings", `["Yes.", "No."]`, []responseEntry{{Text: "Yes."}, {Text: "No."}}, false},
+		{"objects", `[{"text": "Yes.", "category": "yes", "author": "sam", "added_at": "2024-05-01T00:00:00Z"}]`,
+			[]responseEntry{{Text: "Yes.", Category: categoryYes, Author: "sam", AddedAt: added}}, false},
+		{"mixed", `["Yes.", {"text": "Ask the cat.", "author": "kim"}]`,
+			[]responseEntry{{Text: "Yes."}, {Text: "Ask the cat.", Author: "kim"}}, false},
+		{"object without text", `[{"author": "sam"}]`, nil, true},
+		{"unknown category", `[{"text": "Yes.", "category": "perhaps"}]`, nil, true},
+		{"bad date", `[{"text": "Yes.", "added_at": "yesterday"}]`, nil, true},
+		{"number", `[7]`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []responseEntry
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("Unmarshal = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseMetadataFromFiles(t *testing.T) {
+	responses := writeFile(t, "responses.json", `["Yes.", {"text": "Ask the cat.", "category": "maybe", "author": "kim"}]`)
+	overrides := writeFile(t, "overrides.json", `{"teams": {"T1": [{"text": "Ship it.", "category": "yes", "author": "sam"}, "Not today."]}}`)
+	c := withConfig(t, map[string]string{"RESPONSES_FILE": responses, "RESPONSE_OVERRIDES_FILE": overrides})
+
+	if want := []string{"Yes.", "Ask the cat."}; !slices.Equal(c.Responses, want) {
+		t.Errorf("Responses = %q, want %q", c.Responses, want)
+	}
+	if want := []string{"Ship it.", "Not today."}; !slices.Equal(c.Overrides.Teams["T1"], want) {
+		t.Errorf("team T1 = %q, want %q", c.Overrides.Teams["T1"], want)
+	}
+	for text, author := range map[string]string{"Ask the cat.": "kim", "Ship it.": "sam"} {
+		if got := c.Overrides.Meta[text].Author; got != author {
+			t.Errorf("author of %q = %q, want %q", text, got, author)
+		}
+	}
+	if _, ok := c.Overrides.Meta["Yes."]; ok {
+		t.Error("a plain string response has metadata")
+	}
+	if got := categoryOf("Ask the cat."); got != categoryMaybe {
+		t.Errorf("categoryOf a file response = %q, want its given category", got)
+	}
+}
+
+func TestResponsesEmptyFallback(t *testing.T) {
+	builtin := withConfig(t, nil).Responses
+	empty := writeFile(t, "empty.json", `[]`)
+	tests := []struct {
+		name    string
+		file    string
+		env     string
+		want    []string
+		wantErr string
+		wantLog string
+	}{
+		{"empty file, strict", empty, "", nil, "has no responses", ""},
+		{"empty file, fallback", empty, "true", builtin, "", "has no responses; using the built-in responses instead"},
+		{"invalid file, strict", writeFile(t, "bad.json", `{`), "false", nil, "parsing responses file", ""},
+		{"invalid file, fallback", writeFile(t, "bad.json", `{`), "true", builtin, "", "using the built-in responses instead"},
+		{"missing file, fallback", filepath.Join(t.TempDir(), "nope.json"), "true", builtin, "", "reading responses file"},
+		{"good file, fallback", writeFile(t, "good.json", `["Yes.", "No."]`), "true", []string{"Yes.", "No."}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			t.Setenv("RESPONSES_FILE", tt.file)
+			t.Setenv("RESPONSES_EMPTY_FALLBACK", tt.env)
+			c, err := loadConfig()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadConfig error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+			if !slices.Equal(c.Responses, tt.want) {
+				t.Errorf("Responses = %q, want %q", c.Responses, tt.want)
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("log %q lacks %q", logs, tt.wantLog)
+			}
+			if tt.wantLog != "" && c.Provenance.Source == provenanceFile {
+				t.Errorf("provenance says the responses came from %s", c.Provenance.Path)
+			}
+		})
+	}
+	t.Setenv("RESPONSES_FILE", empty)
+	t.Setenv("RESPONSES_EMPTY_FALLBACK", "perhaps")
+	if _, err := loadConfig(); err == nil {
+		t.Error("RESPONSES_EMPTY_FALLBACK=perhaps loaded")
+	}
+}
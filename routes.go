@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/slack-go/slack"
+)
+
+// route is an HTTP endpoint served by the bot.
+type route struct {
+	path    string
+	purpose string
+	handler http.HandlerFunc
+
+	// ops marks unsigned operational endpoints (health checks and the like)
+	// rather than Slack-facing ones.
+	ops bool
+}
+
+// routes lists every endpoint the bot serves.
+func routes(signingSecret string, client *slack.Client) []route {
+	rs := []route{
+		{path: "/ask8ball", purpose: "slash command Request URL for /ask8ball", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleSlashCommand(w, r, signingSecret, client)
+		}},
+		{path: "/interactions", purpose: "Interactivity Request URL (Shake again button)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleInteraction(w, r, signingSecret, client)
+		}},
+		{path: "/events", purpose: "Event Subscriptions Request URL (app mentions)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleEvents(w, r, signingSecret, client)
+		}},
+		{path: "/8ball-theme", purpose: "slash command Request URL for /8ball-theme", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleTheme(w, r, signingSecret)
+		}},
+		{path: "/8ball-themes", purpose: "slash command Request URL for /8ball-themes", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleThemes(w, r, signingSecret)
+		}},
+		{path: "/8ball-preview", purpose: "slash command Request URL for /8ball-preview", handler: func(w http.ResponseWriter, r *http.Request) {
+			handlePreview(w, r, signingSecret)
+		}},
+		{path: "/8ball-strict", purpose: "slash command Request URL for /8ball-strict (admins only)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleStrict(w, r, signingSecret)
+		}},
+		{path: "/8ball-feedback", purpose: "slash command Request URL for /8ball-feedback", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleFeedback(w, r, signingSecret)
+		}},
+		{path: "/8ball-stats", purpose: "slash command Request URL for /8ball-stats", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleStats(w, r, signingSecret)
+		}},
+		{path: "/8ball-export", purpose: "slash command Request URL for /8ball-export (admins only; answers text/csv)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleExport(w, r, signingSecret)
+		}},
+		{path: "/8ball-selftest", purpose: "slash command Request URL for /8ball-selftest (admins only)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleSelfTest(w, r, signingSecret)
+		}},
+		{path: "/8ball-reset", purpose: "slash command Request URL for /8ball-reset (admins only)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleResetUser(w, r, signingSecret)
+		}},
+		{path: "/8ball-purge", purpose: "slash command Request URL for /8ball-purge (admins only)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handlePurge(w, r, signingSecret)
+		}},
+		{path: "/8ball-add", purpose: "slash command Request URL for /8ball-add (admins only)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleAddResponse(w, r, signingSecret)
+		}},
+		{path: "/8ball-reload", purpose: "slash command Request URL for /8ball-reload (admins only)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleReload(w, r, signingSecret)
+		}},
+		{path: "/8ball-config", purpose: "slash command Request URL for /8ball-config (admins only)", handler: func(w http.ResponseWriter, r *http.Request) {
+			handleConfig(w, r, signingSecret)
+		}},
+		{path: "/webhook/ask", purpose: "JSON questions from other tools (bearer WEBHOOK_TOKEN)", handler: handleWebhookAsk},
+		{path: "/healthz", purpose: "liveness check", handler: handleHealth, ops: true},
+		{path: "/ready", purpose: "readiness check (503 during READINESS_DELAY)", handler: handleReady, ops: true},
+		{path: "/version", purpose: "version and where the responses came from", handler: handleVersion, ops: true},
+		{path: "/metrics", purpose: "Prometheus metrics", handler: promhttp.Handler().ServeHTTP, ops: true},
+	}
+	return append(rs, testHookRoutes()...)
+}
+
+// routePath returns the path rt is served on, applying cfg.PathPrefix unless
+// rt is an ops endpoint and those are configured to stay at the root.
+func routePath(rt route) string {
+	if rt.ops && cfg.OpsPrefixExempt {
+		return rt.path
+	}
+	return cfg.PathPrefix + rt.path
+}
+
+func registerRoutes(mux *http.ServeMux, rs []route) {
+	for _, rt := range rs {
+		mux.HandleFunc(routePath(rt), rt.handler)
+	}
+}
+
+// logStartupSummary explains which endpoints are registered and how Slack
+// must call them, since a wrong Request URL is the most common setup mistake.
+// It never logs secrets.
+func logStartupSummary(addr string, rs []route) {
+	log.Printf("Registered endpoints on %s:", addr)
+	for _, rt := range rs {
+		method := "POST"
+		if rt.ops {
+			method = "GET "
+		}
+		log.Printf("  %s %s - %s", method, routePath(rt), rt.purpose)
+	}
+	log.Printf("Slack endpoints accept only POST requests signed with the app's signing secret.")
+	log.Printf("Set each Slack command's Request URL to https://<your-public-host>/<path> from the list above.")
+}
+
+// normalizePathPrefix turns PATH_PREFIX into "" or "/some/prefix" with no
+// trailing slash, so it can be prepended to route paths directly.
+func normalizePathPrefix(raw string) (string, error) {
+	p := strings.Trim(strings.TrimSpace(raw), "/")
+	if p == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(p, " ?#") {
+		return "", fmt.Errorf("invalid PATH_PREFIX %q", raw)
+	}
+	return "/" + p, nil
+}
+
+// handleHealth reports that the process is up.
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// startedAt is when the server started, for READINESS_DELAY.
+var startedAt time.Time
+
+// ready reports whether READINESS_DELAY has passed since startedAt at now.
+func ready(now time.Time) bool {
+	return now.Sub(startedAt) >= cfg.ReadinessDelay
+}
+
+// handleReady reports whether the bot should receive traffic. Unlike
+// /healthz, it stays 503 for READINESS_DELAY after startup so caches can warm,
+// and once Slack has revoked the bot token.
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if tokenRevoked.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("bot token revoked\n"))
+		return
+	}
+	if !ready(clock()) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("starting\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
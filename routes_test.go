@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogStartupSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"default", nil, []string{
+			"Registered endpoints on :8080:",
+			"POST /ask8ball - slash command Request URL for /ask8ball",
+			"POST /interactions",
+			"POST /events",
+			"GET  /healthz",
+			"signed with the app's signing secret",
+			"Request URL",
+		}},
+		{"path prefix", map[string]string{"PATH_PREFIX": "/bot/"}, []string{
+			"POST /bot/ask8ball",
+			"GET  /bot/healthz",
+		}},
+		{"ops exempt from prefix", map[string]string{"PATH_PREFIX": "bot", "PATH_PREFIX_EXEMPT_OPS": "true"}, []string{
+			"POST /bot/ask8ball",
+			"GET  /healthz",
+			"GET  /metrics",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			buf := captureLog(t)
+			rs := routes("very-secret-value", nil)
+			logStartupSummary(":8080", rs)
+			out := buf.String()
+
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("summary lacks %q:\n%s", want, out)
+				}
+			}
+			for _, rt := range rs {
+				if !strings.Contains(out, " "+routePath(rt)+" - ") {
+					t.Errorf("summary doesn't list %s", routePath(rt))
+				}
+			}
+			if strings.Contains(out, "very-secret-value") {
+				t.Error("summary logs the signing secret")
+			}
+		})
+	}
+}
+
+func TestNormalizePathPrefix(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"bots/8ball", "/bots/8ball"},
+		{"/bots/8ball/", "/bots/8ball"},
+		{" /bots ", "/bots"},
+	}
+	for _, tt := range tests {
+		if got, err := normalizePathPrefix(tt.raw); err != nil || got != tt.want {
+			t.Errorf("normalizePathPrefix(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+		}
+	}
+	for _, raw := range []string{"/bots 8ball", "/bots?x=1", "/bots#top"} {
+		if got, err := normalizePathPrefix(raw); err == nil {
+			t.Errorf("normalizePathPrefix(%q) = %q, want an error", raw, got)
+		}
+	}
+}
+
+func TestRegisterRoutesWithPrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		path       string
+		wantStatus int
+	}{
+		{"no prefix", nil, "/healthz", http.StatusOK},
+		{"prefixed command", map[string]string{"PATH_PREFIX": "/bots/8ball"}, "/bots/8ball/ask8ball", http.StatusMethodNotAllowed},
+		{"prefixed health", map[string]string{"PATH_PREFIX": "/bots/8ball"}, "/bots/8ball/healthz", http.StatusOK},
+		{"unprefixed health", map[string]string{"PATH_PREFIX": "/bots/8ball"}, "/healthz", http.StatusNotFound},
+		{"unprefixed command", map[string]string{"PATH_PREFIX": "/bots/8ball"}, "/ask8ball", http.StatusNotFound},
+		{"exempt health", map[string]string{"PATH_PREFIX": "/bots/8ball", "PATH_PREFIX_EXEMPT_OPS": "true"}, "/healthz", http.StatusOK},
+		{"exempt keeps commands prefixed", map[string]string{"PATH_PREFIX": "/bots/8ball", "PATH_PREFIX_EXEMPT_OPS": "true"}, "/bots/8ball/ask8ball", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			mux := http.NewServeMux()
+			registerRoutes(mux, routes(testSecret, nil))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestReadinessDelay(t *testing.T) {
+	tests := []struct {
+		delay string
+		wait  time.Duration
+		want  int
+	}{
+		{"0s", 0, http.StatusOK},
+		{"30s", 0, http.StatusServiceUnavailable},
+		{"30s", 29 * time.Second, http.StatusServiceUnavailable},
+		{"30s", 30 * time.Second, http.StatusOK},
+		{"30s", time.Hour, http.StatusOK},
+	}
+	for _, tt := range tests {
+		withConfig(t, map[string]string{"READINESS_DELAY": tt.delay})
+		start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		oldStarted := startedAt
+		startedAt = start
+		setClock(t, start.Add(tt.wait))
+
+		rec := httptest.NewRecorder()
+		handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		if rec.Code != tt.want {
+			t.Errorf("READINESS_DELAY=%s after %v: /ready = %d, want %d", tt.delay, tt.wait, rec.Code, tt.want)
+		}
+		// Liveness doesn't wait.
+		rec = httptest.NewRecorder()
+		handleHealth(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("READINESS_DELAY=%s after %v: /healthz = %d, want 200", tt.delay, tt.wait, rec.Code)
+		}
+		startedAt = oldStarted
+	}
+}
+
+func TestReadinessDelayConfig(t *testing.T) {
+	t.Setenv("READINESS_DELAY", "soon")
+	if _, err := loadConfig(); err == nil {
+		t.Error("READINESS_DELAY=soon loaded")
+	}
+}
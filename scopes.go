@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// requiredScopes are the bot token scopes the bot can't work without:
+// slash commands, and posting answers.
+var requiredScopes = []string{"commands", "chat:write"}
+
+// missingScopes returns the required scopes absent from granted, Slack's
+// comma-separated X-OAuth-Scopes header.
+func missingScopes(granted string) []string {
+	var have []string
+	for _, s := range strings.Split(granted, ",") {
+		have = append(have, strings.TrimSpace(s))
+	}
+	var missing []string
+	for _, s := range requiredScopes {
+		if !slices.Contains(have, s) {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// validateToken checks with auth.test that token works and carries the
+// required scopes, so a misconfigured install fails at startup rather than
+// with confusing errors later. options are passed to the Slack client.
+func validateToken(token string, options ...slack.Option) error {
+	var scopes string
+	options = append(options, slack.OptionOnResponseHeaders(func(path string, headers http.Header) {
+		if path == "auth.test" {
+			scopes = headers.Get("X-OAuth-Scopes")
+		}
+	}))
+	auth, err := slack.New(token, options...).AuthTest()
+	if err != nil {
+		return fmt.Errorf("SLACK_BOT_TOKEN failed auth.test: %w", err)
+	}
+	log.Printf("Authenticated as %s in %s", auth.User, auth.Team)
+
+	if scopes == "" {
+		logWarn("Slack didn't report the token's scopes; can't check for %s", strings.Join(requiredScopes, ", "))
+		return nil
+	}
+	if missing := missingScopes(scopes); len(missing) > 0 {
+		return fmt.Errorf("SLACK_BOT_TOKEN is missing scopes %s; add them under OAuth & Permissions and reinstall the app", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestMissingScopes(t *testing.T) {
+	tests := []struct {
+		granted string
+		want    []string
+	}{
+		{"commands,chat:write", nil},
+		{"chat:write, commands, users:read", nil},
+		{"commands", []string{"chat:write"}},
+		{"chat:write.public,commands", []string{"chat:write"}},
+		{"users:read", []string{"commands", "chat:write"}},
+	}
+	for _, tt := range tests {
+		if got := missingScopes(tt.granted); !slices.Equal(got, tt.want) {
+			t.Errorf("missingScopes(%q) = %v, want %v", tt.granted, got, tt.want)
+		}
+	}
+}
+
+// scopedSlack is a Slack API answering auth.test for a token carrying
+// scopes, sent in X-OAuth-Scopes unless it is "".
+func scopedSlack(t *testing.T, scopes string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth.test" {
+			t.Errorf("called %s, want auth.test", r.URL.Path)
+		}
+		if scopes != "" {
+			w.Header().Set("X-OAuth-Scopes", scopes)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":true,"user":"8ball","team":"Acme"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/"
+}
+
+func TestValidateTokenScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  string
+		wantErr string
+		wantLog string
+	}{
+		{"all scopes", "commands,chat:write,users:read", "", ""},
+		{"limited scopes", "commands,users:read", "SLACK_BOT_TOKEN is missing scopes chat:write;", ""},
+		{"no scopes", "incoming-webhook", "SLACK_BOT_TOKEN is missing scopes commands, chat:write;", ""},
+		{"unreported", "", "", "can't check for commands, chat:write"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, nil)
+			logs := captureLog(t)
+			err := validateToken("xoxb-test", slack.OptionAPIURL(scopedSlack(t, tt.scopes)))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validateToken: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+				t.Errorf("validateToken = %v, want %q", err, tt.wantErr)
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("log %q lacks %q", logs, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestValidateTokenAuthFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":false,"error":"invalid_auth"}`)
+	}))
+	t.Cleanup(srv.Close)
+	err := validateToken("xoxb-bad", slack.OptionAPIURL(srv.URL+"/"))
+	if err == nil || !strings.Contains(err.Error(), "failed auth.test: invalid_auth") {
+		t.Errorf("validateToken = %v, want the auth.test failure", err)
+	}
+}
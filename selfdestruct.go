@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// messageDeleter is the part of the Slack client self-destructing answers
+// need.
+type messageDeleter interface {
+	DeleteMessage(channel, messageTimestamp string) (string, string, error)
+}
+
+// selfDestructor deletes posted answers after a TTL, for prophecies that
+// vanish. Pending deletes are dropped when its context ends: running them all
+// at once on shutdown would delete answers early in a burst against Slack's
+// rate limits, so those answers stay instead.
+type selfDestructor struct {
+	ttl     time.Duration
+	ctx     context.Context
+	wg      sync.WaitGroup
+	dropped atomic.Int64
+}
+
+func newSelfDestructor(ctx context.Context, ttl time.Duration) *selfDestructor {
+	return &selfDestructor{ttl: ttl, ctx: ctx}
+}
+
+// selfDestructs is set at startup when SELF_DESTRUCT_TTL is configured.
+var selfDestructs *selfDestructor
+
+// schedule deletes the message ts in channelID once the TTL has passed.
+func (s *selfDestructor) schedule(client messageDeleter, channelID, ts string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		t := time.NewTimer(s.ttl)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-s.ctx.Done():
+			s.dropped.Add(1)
+			return
+		}
+		err := slackCall(func() error {
+			_, _, err := client.DeleteMessage(channelID, ts)
+			return err
+		})
+		if err != nil {
+			logError("Error deleting self-destructing message %s: %v", ts, err)
+		}
+	}()
+}
+
+// wait blocks until every scheduled delete has run or been dropped, and
+// logs how many were dropped.
+func (s *selfDestructor) wait() {
+	s.wg.Wait()
+	if n := s.dropped.Load(); n > 0 {
+		logWarn("dropped %d pending self-destructs on shutdown; those answers stay posted", n)
+	}
+}
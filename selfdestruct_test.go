@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestSelfDestructFires(t *testing.T) {
+	withConfig(t, nil)
+	client, calls := fakeSlack(t)
+	s := newSelfDestructor(context.Background(), 10*time.Millisecond)
+	s.schedule(client, "C1", "1700000000.000100")
+
+	call := nextOutbound(t, calls)
+	if call.URL != "chat.delete" {
+		t.Fatalf("called %s, want chat.delete", call.URL)
+	}
+	form, _ := url.ParseQuery(call.Body)
+	if form.Get("channel") != "C1" || form.Get("ts") != "1700000000.000100" {
+		t.Errorf("deleted %v, want the posted answer", form)
+	}
+	s.wait()
+}
+
+func TestSelfDestructDroppedOnShutdown(t *testing.T) {
+	withConfig(t, nil)
+	logs := captureLog(t)
+	client, calls := fakeSlack(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newSelfDestructor(ctx, time.Hour)
+	s.schedule(client, "C1", "1")
+	s.schedule(client, "C1", "2")
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("wait blocked after shutdown")
+	}
+	select {
+	case call := <-calls:
+		t.Errorf("shutdown still called %s", call.URL)
+	default:
+	}
+	if !strings.Contains(logs.String(), "dropped 2 pending self-destructs") {
+		t.Errorf("log %q doesn't count the dropped deletes", logs)
+	}
+}
+
+func TestMentionAnswerSelfDestructs(t *testing.T) {
+	withConfig(t, map[string]string{"SELF_DESTRUCT_TTL": "10ms"})
+	selfDestructs = newSelfDestructor(context.Background(), 10*time.Millisecond)
+	t.Cleanup(func() { selfDestructs = nil })
+	client, calls := fakeSlack(t)
+
+	replyToMention(client, "T1", &slackevents.AppMentionEvent{User: "U1", Channel: "C1", Text: "<@U0BOT> Will it rain?", TimeStamp: "1700000000.000001"})
+	if call := nextOutbound(t, calls); call.URL != "chat.postMessage" {
+		t.Fatalf("first call = %s, want chat.postMessage", call.URL)
+	}
+	call := nextOutbound(t, calls)
+	form, _ := url.ParseQuery(call.Body)
+	if call.URL != "chat.delete" || form.Get("ts") != "1700000000.000100" {
+		t.Errorf("second call = %s %v, want the answer's delete", call.URL, form)
+	}
+	selfDestructs.wait()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// selfTestResult is the outcome of running the answer logic on one canned input.
+type selfTestResult struct {
+	Name   string
+	Input  string
+	Got    string
+	Passed bool
+}
+
+// selfTestCases covers every branch of the answer logic.
+var selfTestCases = []struct {
+	name  string
+	input string
+	check func(reply string, idx int) bool
+}{
+	{"yes/no question", "Will the deploy work?", func(reply string, idx int) bool {
+		return idx >= 0 && slices.Contains(currentResponses().Responses, reply)
+	}},
+	{"open-ended question", "What is the meaning of life?", func(reply string, idx int) bool {
+		return reply == replyOpenEnded && idx == -1
+	}},
+	{"trivial question", "Really?", func(reply string, idx int) bool {
+		return reply == replyTrivial && idx == -1
+	}},
+	{"emoji only", "🤔?", func(reply string, idx int) bool {
+		return reply == replyNoWords && idx == -1
+	}},
+	{"no question mark", "The deploy will work", func(reply string, idx int) bool {
+		return reply == replyNoQuestion && idx == -1
+	}},
+	{"empty", "", func(reply string, idx int) bool {
+		return reply == replyNoQuestion && idx == -1
+	}},
+}
+
+// runSelfTest runs the answer logic against canned inputs using the live
+// configuration and reports whether each produced the expected kind of reply.
+// It draws from its own source, so it leaves rng's sequence alone.
+func runSelfTest() []selfTestResult {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	results := make([]selfTestResult, 0, len(selfTestCases))
+	for _, tc := range selfTestCases {
+		resp := selfTestAnswer(tc.input, currentResponses().Responses, r)
+		results = append(results, selfTestResult{
+			Name:   tc.name,
+			Input:  tc.input,
+			Got:    resp.Text,
+			Passed: tc.check(resp.Text, resp.Index),
+		})
+	}
+	return results
+}
+
+// selfTestAnswer is answer without the selection modes, forced words and
+// the like: it checks the question and picks a response plainly, so a
+// self-test doesn't deal from the shuffle bag or count towards the decay.
+func selfTestAnswer(text string, resps []string, r *rand.Rand) Response {
+	if err := shouldAnswer(normalizeText(text), true); err != nil {
+		return Response{Text: errorReply(err), Index: -1}
+	}
+	if len(resps) == 0 {
+		return Response{Text: replyNoResponses, Index: -1}
+	}
+	return pickedResponse(resps, pickIndex(r, len(resps)), sourceRandom)
+}
+
+// formatSelfTest renders self-test results as a Slack message.
+func formatSelfTest(results []selfTestResult) string {
+	var b strings.Builder
+	passed := 0
+	for _, res := range results {
+		mark := "❌"
+		if res.Passed {
+			mark = "✅"
+			passed++
+		}
+		fmt.Fprintf(&b, "%s %s: %q → %q\n", mark, res.Name, res.Input, res.Got)
+	}
+	fmt.Fprintf(&b, "%d/%d checks passed", passed, len(results))
+	return b.String()
+}
+
+// selfTestCommand runs the self-test and reports the results.
+func selfTestCommand(cmd slack.SlashCommand) *Reply {
+	summary := formatSelfTest(runSelfTest())
+	log.Printf("Self-test requested by %s:\n%s", logID(cmd.UserID), summary)
+	return &Reply{Text: summary}
+}
+
+// handleSelfTest processes the admin-only /8ball-selftest slash command
+func handleSelfTest(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, adminOnly("run the self-test", selfTestCommand))
+}
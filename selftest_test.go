@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	withConfig(t, nil)
+	results := runSelfTest()
+	if len(results) != len(selfTestCases) {
+		t.Fatalf("runSelfTest ran %d checks, want %d", len(results), len(selfTestCases))
+	}
+	for _, res := range results {
+		if !res.Passed {
+			t.Errorf("%s: %q got %q, which the check rejects", res.Name, res.Input, res.Got)
+		}
+	}
+}
+
+// TestRunSelfTestLeavesSelection checks a self-test between asks doesn't
+// change what the seeded asks after it get, in any selection mode.
+func TestRunSelfTestLeavesSelection(t *testing.T) {
+	for _, mode := range []string{"random", "bag", "decay"} {
+		t.Run(mode, func(t *testing.T) {
+			c := withConfig(t, map[string]string{"SELECTION_MODE": mode})
+			answerUsage = newDecayCounter(c.DecayHalfLife)
+			t.Cleanup(func() { answerUsage = nil })
+			var runs [2][]string
+			for i := range runs {
+				purgeAll()
+				seedRNG(t, 7)
+				for j := range 10 {
+					if i == 1 && j == 5 {
+						runSelfTest()
+					}
+					runs[i] = append(runs[i], ask(askRequest{UserID: "U1", Question: "Will it rain?"}).Text)
+				}
+			}
+			if !slices.Equal(runs[0], runs[1]) {
+				t.Errorf("a self-test changed the asks after it:\n%q\n%q", runs[0], runs[1])
+			}
+		})
+	}
+}
+
+func TestRunSelfTestCatchesBrokenConfig(t *testing.T) {
+	// With every question counted as open-ended, the yes/no check fails.
+	withConfig(t, map[string]string{"OPEN_QUESTION_WORDS": "will"})
+	results := runSelfTest()
+	failed := map[string]bool{}
+	for _, res := range results {
+		failed[res.Name] = !res.Passed
+	}
+	if !failed["yes/no question"] {
+		t.Errorf("yes/no check passed with OPEN_QUESTION_WORDS=will: %v", results)
+	}
+}
+
+func TestFormatSelfTest(t *testing.T) {
+	summary := formatSelfTest([]selfTestResult{
+		{Name: "a", Input: "x?", Got: "Yes.", Passed: true},
+		{Name: "b", Input: "", Got: "no", Passed: false},
+	})
+	for _, want := range []string{`✅ a: "x?" → "Yes."`, `❌ b: "" → "no"`, "1/2 checks passed"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary %q lacks %q", summary, want)
+		}
+	}
+}
+
+func TestSelfTestCommandAdminOnly(t *testing.T) {
+	withConfig(t, map[string]string{"ADMIN_USERS": "U1"})
+	run := commands["/8ball-selftest"].run
+	tests := []struct {
+		user string
+		want string
+	}{
+		{"U1", "checks passed"},
+		{"U2", "only admins"},
+	}
+	for _, tt := range tests {
+		if reply := run(slack.SlashCommand{UserID: tt.user}); !strings.Contains(reply.Text, tt.want) {
+			t.Errorf("%s: reply %q lacks %q", tt.user, reply.Text, tt.want)
+		}
+	}
+}
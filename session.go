@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// sessionPrefix starts a question shared by a group, as in
+// "/ask8ball session:standup Should we ship today?".
+const sessionPrefix = "session:"
+
+// sessionRequest reports whether text starts with "session:<id>",
+// returning the id and the question after it.
+func sessionRequest(text string) (session, question string, ok bool) {
+	text = strings.TrimSpace(text)
+	if len(text) < len(sessionPrefix) || !strings.EqualFold(text[:len(sessionPrefix)], sessionPrefix) {
+		return "", "", false
+	}
+	session, question, _ = strings.Cut(text[len(sessionPrefix):], " ")
+	if session == "" {
+		return "", "", false
+	}
+	return session, strings.TrimSpace(question), true
+}
+
+// sessionAnswer is answer for a question asked in session: checked the same
+// way, but picked with sessionPick.
+func sessionAnswer(session, question string, resps []string, strict bool) Response {
+	if err := shouldAnswer(normalizeText(question), strict); err != nil {
+		return Response{Text: errorReply(err), Index: -1}
+	}
+	if len(resps) == 0 {
+		return Response{Text: replyNoResponses, Index: -1}
+	}
+	text := sessionPick(session, question, resps)
+	return pickedResponse(resps, slices.Index(resps, text), sourceSession)
+}
+
+// sessionPick picks the answer to question in session from responses.
+// Its picker is seeded from the session id and the normalized question
+// alone, so everyone asking the same question in a session gets the same
+// answer, whoever and whenever they are. It returns "" for an empty list.
+func sessionPick(session, question string, responses []string) string {
+	if len(responses) == 0 {
+		return ""
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s", session, strings.ToLower(normalizeText(question)))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	return responses[pickIndex(r, len(responses))]
+}
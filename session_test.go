@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSessionRequest(t *testing.T) {
+	tests := []struct {
+		text              string
+		session, question string
+		ok                bool
+	}{
+		{"session:standup Should we ship today?", "standup", "Should we ship today?", true},
+		{"  SESSION:standup   Should we ship?", "standup", "Should we ship?", true},
+		{"session:standup", "standup", "", true},
+		{"session: Should we ship?", "", "", false},
+		{"Should we ship? session:standup", "", "", false},
+		{"sess", "", "", false},
+	}
+	for _, tt := range tests {
+		session, question, ok := sessionRequest(tt.text)
+		if session != tt.session || question != tt.question || ok != tt.ok {
+			t.Errorf("sessionRequest(%q) = %q, %q, %v; want %q, %q, %v", tt.text, session, question, ok, tt.session, tt.question, tt.ok)
+		}
+	}
+}
+
+func TestSessionPick(t *testing.T) {
+	c := withConfig(t, nil)
+	want := sessionPick("standup", "Should we ship today?", c.Responses)
+	// The same question, however it is typed, gets the same answer.
+	for _, question := range []string{"Should we ship today?", "  should WE ship   today??", "Should we ship today?​"} {
+		if got := sessionPick("standup", question, c.Responses); got != want {
+			t.Errorf("sessionPick(standup, %q) = %q, want %q", question, got, want)
+		}
+	}
+	// Other sessions, and other questions, are picked independently.
+	sessions, questions := map[string]bool{}, map[string]bool{}
+	for i := range 50 {
+		sessions[sessionPick("standup-"+strconv.Itoa(i), "Should we ship today?", c.Responses)] = true
+		questions[sessionPick("standup", "Should we ship build "+strconv.Itoa(i)+"?", c.Responses)] = true
+	}
+	if len(sessions) < 5 || len(questions) < 5 {
+		t.Errorf("50 sessions gave %d answers and 50 questions %d, want them to diverge", len(sessions), len(questions))
+	}
+	if got := sessionPick("standup", "Should we ship today?", nil); got != "" {
+		t.Errorf("sessionPick with no responses = %q, want \"\"", got)
+	}
+}
+
+func TestAskCommandSession(t *testing.T) {
+	c := withConfig(t, nil)
+	advance := fakeClock(t, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+	want := sessionPick("standup", "Should we ship today?", c.Responses)
+	// Everyone in the session sees the same answer, in any channel and at
+	// any time.
+	for i, user := range []string{"U1", "U2", "U3"} {
+		advance(time.Hour)
+		reply := askCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C" + strconv.Itoa(i), UserID: user, Text: "session:standup Should we ship today?"})
+		if !strings.Contains(reply.Text, want) {
+			t.Errorf("%s got %q, want the session's %q", user, reply.Text, want)
+		}
+		// Shaking again would only repeat the answer.
+		if _, ok := replyButtons(t, reply)[shakeAgainActionID]; ok {
+			t.Errorf("%s's session answer offers Shake again", user)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRequestMissingHeaders(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		name     string
+		drop     []string
+		wantBody string
+	}{
+		{"no timestamp", []string{"X-Slack-Request-Timestamp"}, `{"error":"missing X-Slack-Request-Timestamp header"}`},
+		{"no signature", []string{"X-Slack-Signature"}, `{"error":"missing X-Slack-Signature header"}`},
+		{"neither", []string{"X-Slack-Request-Timestamp", "X-Slack-Signature"}, `{"error":"missing X-Slack-Request-Timestamp header"}`},
+		{"blank timestamp", nil, `{"error":"missing X-Slack-Request-Timestamp header"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := signedRequest("/ask8ball", formContentType, "command=%2Fask8ball")
+			for _, h := range tt.drop {
+				r.Header.Del(h)
+			}
+			if tt.drop == nil {
+				r.Header.Set("X-Slack-Request-Timestamp", "  ")
+			}
+			w := httptest.NewRecorder()
+			if _, ok := verifyRequest(w, r, testSecret); ok {
+				t.Fatal("verifyRequest accepted the request")
+			}
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestSigned(t *testing.T) {
+	withConfig(t, nil)
+	body, ok := verifyRequest(httptest.NewRecorder(), signedRequest("/ask8ball", formContentType, "command=%2Fask8ball"), testSecret)
+	if !ok || string(body) != "command=%2Fask8ball" {
+		t.Errorf("verifyRequest = %q, %v; want the body", body, ok)
+	}
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		raw     string
+		tol     time.Duration
+		wantErr bool
+	}{
+		{"now", "1700000000", 5 * time.Minute, false},
+		{"within tolerance", "1699999760", 5 * time.Minute, false},
+		{"at the edge", "1699999700", 5 * time.Minute, false},
+		{"too old", "1699999699", 5 * time.Minute, true},
+		{"ahead within tolerance", "1700000200", 5 * time.Minute, false},
+		{"too far ahead", "1700000301", 5 * time.Minute, true},
+		{"wider tolerance", "1699999000", 30 * time.Minute, false},
+		{"not a number", "yesterday", time.Hour, true},
+		{"empty", "", time.Hour, true},
+	}
+	for _, tt := range tests {
+		err := checkTimestamp(tt.raw, now, tt.tol)
+		if (err != nil) != tt.wantErr || err != nil && !errors.Is(err, errBadTimestamp) {
+			t.Errorf("%s: checkTimestamp(%q, %v) = %v, want error %v", tt.name, tt.raw, tt.tol, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSignatureToleranceConfig(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 5 * time.Minute, false},
+		{"10m", 10 * time.Minute, false},
+		{"1h", time.Hour, false},
+		{"24h", maxSignatureTolerance, false},
+		{"0s", 0, true},
+		{"-1m", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("SIGNATURE_TOLERANCE", tt.env)
+		c, err := loadConfig()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SIGNATURE_TOLERANCE=%q: loadConfig error = %v, want error %v", tt.env, err, tt.wantErr)
+			continue
+		}
+		if err == nil && c.SignatureTolerance != tt.want {
+			t.Errorf("SIGNATURE_TOLERANCE=%q loaded as %v, want %v", tt.env, c.SignatureTolerance, tt.want)
+		}
+	}
+}
+
+func TestVerifyRequestUsesTolerance(t *testing.T) {
+	withConfig(t, map[string]string{"SIGNATURE_TOLERANCE": "10m"})
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		age  time.Duration
+		want bool
+	}{
+		{8 * time.Minute, true},
+		{-8 * time.Minute, true},
+		{12 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		setClock(t, now.Add(-tt.age))
+		r := signedRequest("/ask8ball", formContentType, "command=%2Fask8ball")
+		setClock(t, now)
+		w := httptest.NewRecorder()
+		if _, ok := verifyRequest(w, r, testSecret); ok != tt.want {
+			t.Errorf("request signed %v ago: ok = %v, want %v (%d %s)", tt.age, ok, tt.want, w.Code, w.Body)
+		}
+	}
+}
+
+func TestNormalizeSlackHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		wantLog bool
+	}{
+		{"single", []string{"v0=abc"}, "v0=abc", false},
+		{"padded", []string{"  v0=abc\t"}, "v0=abc", false},
+		{"duplicated", []string{"v0=abc", "v0=abc"}, "v0=abc", true},
+		{"duplicated and different", []string{" v0=abc ", "v0=def"}, "v0=abc", true},
+		{"folded", []string{"v0=abc, v0=abc"}, "v0=abc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			h := http.Header{}
+			for _, v := range tt.values {
+				h.Add("X-Slack-Signature", v)
+			}
+			normalizeSlackHeaders(h)
+			if got := h.Values("X-Slack-Signature"); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("X-Slack-Signature = %q, want [%q]", got, tt.want)
+			}
+			if logged := strings.Contains(logs.String(), "X-Slack-Signature repeated"); logged != tt.wantLog {
+				t.Errorf("logged the repeat %v, want %v: %s", logged, tt.wantLog, logs)
+			}
+		})
+	}
+
+	h := http.Header{}
+	normalizeSlackHeaders(h)
+	if len(h) != 0 {
+		t.Errorf("normalizing no headers added %v", h)
+	}
+}
+
+func TestVerifyRequestProxiedHeaders(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		name   string
+		mangle func(h http.Header)
+		want   bool
+	}{
+		{"duplicated signature", func(h http.Header) { h.Add("X-Slack-Signature", h.Get("X-Slack-Signature")) }, true},
+		{"padded signature", func(h http.Header) { h.Set("X-Slack-Signature", "  "+h.Get("X-Slack-Signature")+" ") }, true},
+		{"padded timestamp", func(h http.Header) { h.Set("X-Slack-Request-Timestamp", " "+h.Get("X-Slack-Request-Timestamp")+"\t") }, true},
+		{"folded signature", func(h http.Header) {
+			h.Set("X-Slack-Signature", h.Get("X-Slack-Signature")+", "+h.Get("X-Slack-Signature"))
+		}, true},
+		{"duplicated timestamp", func(h http.Header) { h.Add("X-Slack-Request-Timestamp", h.Get("X-Slack-Request-Timestamp")) }, true},
+		{"forged signature first", func(h http.Header) {
+			good := h.Get("X-Slack-Signature")
+			h.Set("X-Slack-Signature", "v0=00")
+			h.Add("X-Slack-Signature", good)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := signedRequest("/ask8ball", formContentType, "command=%2Fask8ball")
+			tt.mangle(r.Header)
+			w := httptest.NewRecorder()
+			if _, ok := verifyRequest(w, r, testSecret); ok != tt.want {
+				t.Errorf("verifyRequest ok = %v, want %v (%d %s)", ok, tt.want, w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// Reconnection backoff for Socket Mode.
+const (
+	socketRetryMin = time.Second
+	socketRetryMax = time.Minute
+)
+
+// runSocketMode serves Slack over a Socket Mode connection instead of public
+// HTTP endpoints, reconnecting with exponential backoff whenever the
+// connection fails, until ctx is done. client must carry the app-level token.
+func runSocketMode(ctx context.Context, client *slack.Client) {
+	delay := socketRetryMin
+	for {
+		sm := socketmode.New(client)
+		connCtx, cancel := context.WithCancel(ctx)
+		go handleSocketEvents(connCtx, sm, client)
+
+		start := clock()
+		err := sm.RunContext(connCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that stayed up a while starts the backoff over.
+		if clock().Sub(start) > socketRetryMax {
+			delay = socketRetryMin
+		}
+		logWarn("Socket Mode connection lost: %v; reconnecting in %v", err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, socketRetryMax)
+	}
+}
+
+// handleSocketEvents acknowledges and handles the requests arriving on sm.
+func handleSocketEvents(ctx context.Context, sm *socketmode.Client, client *slack.Client) {
+	for {
+		var evt socketmode.Event
+		select {
+		case <-ctx.Done():
+			return
+		case evt = <-sm.Events:
+		}
+
+		switch evt.Type {
+		case socketmode.EventTypeConnected:
+			log.Printf("Connected to Slack over Socket Mode")
+		case socketmode.EventTypeSlashCommand:
+			// The reply may be held back by ANSWER_DELAY, so build it off
+			// the event loop; Slack waits for the acknowledgement.
+			go func(evt socketmode.Event) {
+				reply, ok := socketCommandReply(ctx, evt)
+				if !ok {
+					logError("Error decoding Socket Mode slash command: %T", evt.Data)
+					ack(sm, evt)
+					return
+				}
+				ack(sm, evt, reply.webhookMessage())
+			}(evt)
+		case socketmode.EventTypeEventsAPI:
+			ack(sm, evt)
+			event, ok := evt.Data.(slackevents.EventsAPIEvent)
+			if !ok {
+				continue
+			}
+			if mention, ok := event.InnerEvent.Data.(*slackevents.AppMentionEvent); ok && mention.BotID == "" {
+				go replyToMention(client, event.TeamID, mention)
+			}
+		case socketmode.EventTypeInteractive:
+			callback, ok := evt.Data.(slack.InteractionCallback)
+			if ok && isAskModalSubmission(callback) {
+				// The answer replaces the modal, so it goes in the
+				// acknowledgement; build it off the event loop.
+				go func(evt socketmode.Event) {
+					ack(sm, evt, askModalSubmission(&callback))
+				}(evt)
+				continue
+			}
+			ack(sm, evt)
+			if ok && callback.Type == slack.InteractionTypeBlockActions {
+				go handleBlockActions(client, &callback)
+			}
+		}
+	}
+}
+
+// ack acknowledges evt with an optional payload. Events that arrive without
+// a request, such as malformed ones, have nothing to acknowledge.
+func ack(sm *socketmode.Client, evt socketmode.Event, payload ...any) {
+	if evt.Request == nil {
+		return
+	}
+	sm.Ack(*evt.Request, payload...)
+}
+
+// socketCommandReply adapts a Socket Mode slash command event to the same
+// Reply the HTTP endpoint would give.
+func socketCommandReply(ctx context.Context, evt socketmode.Event) (*Reply, bool) {
+	cmd, ok := evt.Data.(slack.SlashCommand)
+	if !ok || evt.Request == nil {
+		return nil, false
+	}
+	cmd = cleanCommand(cmd)
+	logCommandBody(cmd)
+	return commandReply(ctx, cmd), true
+}
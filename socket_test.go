@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// socketCommand is a Socket Mode event carrying cmd, as the client delivers
+// one.
+func socketCommand(cmd any) socketmode.Event {
+	return socketmode.Event{
+		Type:    socketmode.EventTypeSlashCommand,
+		Data:    cmd,
+		Request: &socketmode.Request{Type: "slash_commands", EnvelopeID: "env-1"},
+	}
+}
+
+func TestSocketCommandReply(t *testing.T) {
+	c := withConfig(t, nil)
+	base := slack.SlashCommand{TeamID: "T1", ChannelID: "C1", UserID: "U1"}
+	withText := func(command, text string) slack.SlashCommand {
+		cmd := base
+		cmd.Command, cmd.Text = command, text
+		return cmd
+	}
+
+	tests := []struct {
+		name  string
+		cmd   slack.SlashCommand
+		check func(r *Reply) bool
+	}{
+		{"answer", withText("/ask8ball", "Will it rain?"), func(r *Reply) bool {
+			return r.Category != "" && slices.ContainsFunc(c.Responses, func(a string) bool { return strings.Contains(r.Text, a) })
+		}},
+		{"stray command prefix", withText("/ask8ball", "/ask8ball  Why is it raining?"), func(r *Reply) bool {
+			return strings.Contains(r.Text, replyOpenEnded)
+		}},
+		{"no question", withText("/ask8ball", "It will rain"), func(r *Reply) bool {
+			return strings.Contains(r.Text, replyNoQuestion) && r.Category == ""
+		}},
+		{"same as HTTP", withText("/8ball-help", ""), func(r *Reply) bool {
+			return r.Text == commandReply(context.Background(), withText("/8ball-help", "")).Text
+		}},
+		{"unknown command", withText("/tarot", ""), func(r *Reply) bool {
+			return strings.HasPrefix(r.Text, "🎱 I don't know /tarot.")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, ok := socketCommandReply(context.Background(), socketCommand(tt.cmd))
+			if !ok {
+				t.Fatal("socketCommandReply didn't take the command")
+			}
+			if !tt.check(reply) {
+				t.Errorf("reply = %+v", reply)
+			}
+		})
+	}
+}
+
+func TestSocketCommandReplyRejects(t *testing.T) {
+	withConfig(t, nil)
+	noRequest := socketCommand(slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?"})
+	noRequest.Request = nil
+	for name, evt := range map[string]socketmode.Event{
+		"wrong data": socketCommand("not a command"),
+		"no data":    socketCommand(nil),
+		"no request": noRequest,
+	} {
+		if reply, ok := socketCommandReply(context.Background(), evt); ok {
+			t.Errorf("%s: socketCommandReply = %+v, want it refused", name, reply)
+		}
+	}
+}
+
+func TestRequireSlackSocketMode(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Config
+		ok   bool
+	}{
+		{"socket mode", Config{SocketMode: true, BotToken: "xoxb-1", AppToken: "xapp-1"}, true},
+		{"socket mode without signing secret", Config{SocketMode: true, BotToken: "xoxb-1", AppToken: "xapp-1", SigningSecret: ""}, true},
+		{"socket mode with a bot token as app token", Config{SocketMode: true, BotToken: "xoxb-1", AppToken: "xoxb-2"}, false},
+		{"socket mode without bot token", Config{SocketMode: true, AppToken: "xapp-1"}, false},
+		{"http", Config{BotToken: "xoxb-1", SigningSecret: "s"}, true},
+		{"http without signing secret", Config{BotToken: "xoxb-1", AppToken: "xapp-1"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.c.requireSlack(); (err == nil) != tt.ok {
+			t.Errorf("%s: requireSlack = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
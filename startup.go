@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// startupChecks checks that c can serve Slack before the bot starts
+// listening, returning the first fatal problem. On success it logs a single
+// "startup OK" line summarising the configuration's health, so a healthy
+// boot is one line to look for.
+func startupChecks(c Config) error {
+	if err := c.requireSlack(); err != nil {
+		return err
+	}
+	if c.SigningSecret != "" && isPlaceholderSecret(c.SigningSecret) {
+		return errors.New("SLACK_SIGNING_SECRET is a template placeholder; set it to the app's signing secret")
+	}
+	if len(c.Responses) == 0 {
+		return errors.New("no responses configured")
+	}
+	if !c.SocketMode {
+		if err := checkAddr(c.Addr); err != nil {
+			return err
+		}
+	}
+	if c.OpenQuestion == nil {
+		return errors.New("open-question pattern isn't compiled")
+	}
+	token := "unchecked"
+	if c.ValidateToken {
+		if err := validateToken(c.BotToken, slack.OptionHTTPClient(httpClient)); err != nil {
+			return err
+		}
+		token = "valid"
+	}
+
+	transport := "http addr=" + c.Addr
+	if c.SocketMode {
+		transport = "socket"
+	}
+	log.Printf("startup OK transport=%s responses=%d source=%s token=%s selection=%s admins=%d",
+		transport, len(c.Responses), c.Provenance.Source, token, c.SelectionMode, len(c.AdminUsers))
+	return nil
+}
+
+// placeholderSecrets are signing secrets copied from templates and
+// examples rather than from the app's settings, in placeholderKey form.
+var placeholderSecrets = map[string]struct{}{
+	"changeme": {}, "change-me": {}, "secret": {}, "signing-secret": {},
+	"your-signing-secret": {}, "your-slack-signing-secret": {},
+	"slack-signing-secret": {}, "replace-me": {}, "placeholder": {},
+	"todo": {}, "xxx": {}, "xxxx": {}, "test": {},
+}
+
+// isPlaceholderSecret reports whether s is a known template placeholder,
+// ignoring case, surrounding angle brackets and the choice of separator, so
+// "<Your Signing Secret>" and "YOUR_SIGNING_SECRET" both count.
+func isPlaceholderSecret(s string) bool {
+	key := strings.ToLower(strings.Trim(strings.TrimSpace(s), "<>"))
+	key = strings.NewReplacer("_", "-", " ", "-").Replace(key)
+	_, ok := placeholderSecrets[key]
+	return ok
+}
+
+// checkAddr checks that addr is a listen address with a usable port.
+func checkAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid listen address %q: port must be between 1 and 65535", addr)
+	}
+	return nil
+}
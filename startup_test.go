@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// goodEnv is a configuration every startup check passes.
+var goodEnv = map[string]string{
+	"SLACK_BOT_TOKEN":      "xoxb-1",
+	"SLACK_SIGNING_SECRET": "0123456789abcdef",
+	"SOCKET_MODE":          "false",
+	"VALIDATE_TOKEN":       "false",
+}
+
+// allScopes are the scopes the bot needs.
+const allScopes = "commands,chat:write,users:read"
+
+// authSlack answers auth.test through httpClient for the rest of the test,
+// granting scopes, or rejecting the token when scopes is empty.
+func authSlack(t *testing.T, scopes string) {
+	t.Helper()
+	old := httpClient
+	httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"ok":true,"user":"8ball","team":"Acme"}`
+		if scopes == "" {
+			body = `{"ok":false,"error":"invalid_auth"}`
+		}
+		header := http.Header{"Content-Type": {"application/json"}, "X-Oauth-Scopes": {scopes}}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body))), Header: header}, nil
+	})}
+	t.Cleanup(func() { httpClient = old })
+}
+
+func TestStartupChecksOK(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"http", nil, "startup OK transport=http addr=:8080 responses=20 source=builtin token=unchecked"},
+		{"socket", map[string]string{"SOCKET_MODE": "true", "SLACK_SIGNING_SECRET": "", "SLACK_APP_TOKEN": "xapp-1"}, "startup OK transport=socket"},
+		{"validated token", map[string]string{"VALIDATE_TOKEN": "true"}, "token=valid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range goodEnv {
+				t.Setenv(name, value)
+			}
+			c := withConfig(t, tt.env)
+			authSlack(t, allScopes)
+			logs := captureLog(t)
+			if err := startupChecks(c); err != nil {
+				t.Fatalf("startupChecks: %v", err)
+			}
+			if n := strings.Count(logs.String(), "startup OK"); n != 1 || !strings.Contains(logs.String(), tt.want) {
+				t.Errorf("log %q, want one line with %q", logs, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartupChecksFail(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		change  func(c *Config)
+		scopes  string
+		wantErr string
+	}{
+		{"no bot token", map[string]string{"SLACK_BOT_TOKEN": ""}, nil, allScopes, "SLACK_BOT_TOKEN and SLACK_SIGNING_SECRET must be set"},
+		{"no signing secret", map[string]string{"SLACK_SIGNING_SECRET": ""}, nil, allScopes, "SLACK_BOT_TOKEN and SLACK_SIGNING_SECRET must be set"},
+		{"socket without app token", map[string]string{"SOCKET_MODE": "true"}, nil, allScopes, "SOCKET_MODE needs SLACK_BOT_TOKEN and an app-level SLACK_APP_TOKEN"},
+		{"placeholder secret", map[string]string{"SLACK_SIGNING_SECRET": "<Your Signing Secret>"}, nil, allScopes, "SLACK_SIGNING_SECRET is a template placeholder"},
+		{"no responses", nil, func(c *Config) { c.Responses = nil }, allScopes, "no responses configured"},
+		{"bad port", nil, func(c *Config) { c.Addr = ":70000" }, allScopes, `invalid listen address ":70000": port must be between 1 and 65535`},
+		{"bad address", nil, func(c *Config) { c.Addr = "8080" }, allScopes, `invalid listen address "8080"`},
+		{"uncompiled pattern", nil, func(c *Config) { c.OpenQuestion = nil }, allScopes, "open-question pattern isn't compiled"},
+		{"rejected token", map[string]string{"VALIDATE_TOKEN": "true"}, nil, "", "SLACK_BOT_TOKEN failed auth.test: invalid_auth"},
+		{"missing scopes", map[string]string{"VALIDATE_TOKEN": "true"}, nil, "chat:write", "SLACK_BOT_TOKEN is missing scopes commands"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range goodEnv {
+				t.Setenv(name, value)
+			}
+			c := withConfig(t, tt.env)
+			if tt.change != nil {
+				tt.change(&c)
+			}
+			authSlack(t, tt.scopes)
+			logs := captureLog(t)
+			err := startupChecks(c)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("startupChecks = %v, want %q", err, tt.wantErr)
+			}
+			if strings.Contains(logs.String(), "startup OK") {
+				t.Errorf("logged %q despite failing", logs)
+			}
+		})
+	}
+}
+
+func TestIsPlaceholderSecret(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   bool
+	}{
+		{"changeme", true},
+		{"CHANGE_ME", true},
+		{"your-signing-secret", true},
+		{"<Your Signing Secret>", true},
+		{"YOUR_SLACK_SIGNING_SECRET", true},
+		{"  xxxx ", true},
+		{"8f742231b10e8888abcd99yyyzzz85a5", false},
+		{"changeme2", false},
+		{"my-signing-secret", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isPlaceholderSecret(tt.secret); got != tt.want {
+			t.Errorf("isPlaceholderSecret(%q) = %v, want %v", tt.secret, got, tt.want)
+		}
+	}
+}
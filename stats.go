@@ -0,0 +1,96 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// statsStore counts how often each answer has been given since startup.
+type statsStore struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// stats is shared by all requests.
+var stats = &statsStore{counts: make(map[string]int)}
+
+func (s *statsStore) record(answer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[answer]++
+}
+
+// snapshot returns a copy of the counts that is safe to read without locking.
+func (s *statsStore) snapshot() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]int, len(s.counts))
+	for k, v := range s.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// statEntry is one answer's count.
+type statEntry struct {
+	Answer string
+	Count  int
+}
+
+// sortedStats orders counts by count descending, then answer text, so the
+// output is stable regardless of map iteration order.
+func sortedStats(counts map[string]int) []statEntry {
+	entries := make([]statEntry, 0, len(counts))
+	for answer, n := range counts {
+		entries = append(entries, statEntry{answer, n})
+	}
+	slices.SortFunc(entries, func(a, b statEntry) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Answer, b.Answer)
+	})
+	return entries
+}
+
+// formatStats renders answer counts as a Slack message, with any
+// /8ball-feedback votes on each answer.
+func formatStats(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "No questions answered yet."
+	}
+	var b strings.Builder
+	for _, e := range sortedStats(counts) {
+		fmt.Fprintf(&b, "%d × %s", e.Count, e.Answer)
+		if t := feedback.tally(e.Answer); t != (feedbackTally{}) {
+			fmt.Fprintf(&b, " (👍 %d 👎 %d)", t.Up, t.Down)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// statsCommand reports how often each answer has been given.
+func statsCommand(cmd slack.SlashCommand) *Reply {
+	return &Reply{Text: formatStats(stats.snapshot())}
+}
+
+// handleStats processes the /8ball-stats slash command
+func handleStats(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, statsCommand)
+}
+
+// purge resets all counts, returning how many answers had been counted.
+func (s *statsStore) purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.counts)
+	clear(s.counts)
+	return n
+}
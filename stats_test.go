@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestFormatStats(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		name   string
+		counts map[string]int
+		want   string
+	}{
+		{"empty", nil, "No questions answered yet."},
+		{"by count", map[string]int{"No.": 1, "Yes.": 3, "Maybe.": 2}, "3 × Yes.\n2 × Maybe.\n1 × No."},
+		{"ties alphabetical", map[string]int{"Very doubtful.": 2, "Ask again later.": 2, "It is certain.": 5, "Most likely.": 2},
+			"5 × It is certain.\n2 × Ask again later.\n2 × Most likely.\n2 × Very doubtful."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map order varies between runs, so a flaky sort shows up
+			// within a few tries.
+			for range 20 {
+				if got := formatStats(tt.counts); got != tt.want {
+					t.Fatalf("formatStats = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
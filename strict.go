@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// channelStrictStore remembers channels that overrode STRICT_QUESTIONS with
+// /8ball-strict.
+type channelStrictStore struct {
+	mu       sync.Mutex
+	channels map[string]bool
+}
+
+// channelStrictness is shared by all requests.
+var channelStrictness = &channelStrictStore{channels: make(map[string]bool)}
+
+func (s *channelStrictStore) set(channelID string, strict bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[channelID] = strict
+}
+
+// clear drops channelID's override.
+func (s *channelStrictStore) clear(channelID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.channels, channelID)
+}
+
+// get returns channelID's override, if any.
+func (s *channelStrictStore) get(channelID string) (strict, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	strict, ok = s.channels[channelID]
+	return strict, ok
+}
+
+// strictFor reports whether questions in channelID must be yes/no.
+func strictFor(channelID string) bool {
+	if strict, ok := channelStrictness.get(channelID); ok {
+		return strict
+	}
+	return cfg.StrictQuestions
+}
+
+// strictnessName describes a strictness setting for replies.
+func strictnessName(strict bool) string {
+	if strict {
+		return "strict (yes/no questions only)"
+	}
+	return "lenient (any question)"
+}
+
+// strictCommand shows or sets the channel's strictness: "on", "off", or
+// "default" to follow STRICT_QUESTIONS again.
+func strictCommand(cmd slack.SlashCommand) *Reply {
+	switch arg := strings.ToLower(normalizeText(cmd.Text)); arg {
+	case "":
+		return &Reply{Text: fmt.Sprintf("🎱 This channel is %s.", strictnessName(strictFor(cmd.ChannelID)))}
+	case "on", "off":
+		channelStrictness.set(cmd.ChannelID, arg == "on")
+	case "default":
+		channelStrictness.clear(cmd.ChannelID)
+	default:
+		return &Reply{Text: "Usage: /8ball-strict [on|off|default]"}
+	}
+	strict := strictFor(cmd.ChannelID)
+	log.Printf("Channel %s is now %s", logID(cmd.ChannelID), strictnessName(strict))
+	return &Reply{Text: fmt.Sprintf("🎱 This channel is now %s.", strictnessName(strict))}
+}
+
+// handleStrict processes the /8ball-strict slash command (admins only)
+func handleStrict(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, adminOnly("set channel strictness", strictCommand))
+}
+
+// purge forgets every channel's strictness setting, returning how many were
+// set.
+func (s *channelStrictStore) purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.channels)
+	clear(s.channels)
+	return n
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestStrictCommand(t *testing.T) {
+	withConfig(t, nil)
+	steps := []struct {
+		text string
+		want string
+	}{
+		{"", "This channel is strict"},
+		{"off", "This channel is now lenient"},
+		{"", "This channel is lenient"},
+		{" ON ", "This channel is now strict"},
+		{"off", "This channel is now lenient"},
+		{"default", "This channel is now strict"},
+		{"sometimes", "Usage: /8ball-strict [on|off|default]"},
+	}
+	for _, step := range steps {
+		reply := strictCommand(slack.SlashCommand{ChannelID: "C1", Text: step.text})
+		if !strings.Contains(reply.Text, step.want) {
+			t.Errorf("/8ball-strict %q: reply %q lacks %q", step.text, reply.Text, step.want)
+		}
+	}
+}
+
+func TestHandleStrictAdminOnly(t *testing.T) {
+	withConfig(t, map[string]string{"ADMIN_USERS": "UADMIN", "STRICT_QUESTIONS": "true"})
+	for _, user := range []string{"U1", "UADMIN"} {
+		body := url.Values{"command": {"/8ball-strict"}, "text": {"off"}, "channel_id": {"C1"}, "user_id": {user}}.Encode()
+		w := httptest.NewRecorder()
+		handleStrict(w, signedRequest("/8ball-strict", formContentType, body), testSecret)
+		var msg slack.WebhookMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+			t.Fatalf("decoding reply %q: %v", w.Body, err)
+		}
+		if user == "U1" {
+			if msg.Text != "Sorry, only admins can set channel strictness." || !strictFor("C1") {
+				t.Errorf("non-admin /8ball-strict off = %q, strict %v; want it refused", msg.Text, strictFor("C1"))
+			}
+			continue
+		}
+		if strictFor("C1") {
+			t.Errorf("admin /8ball-strict off = %q, want the channel lenient", msg.Text)
+		}
+	}
+	// The shared /ask8ball Request URL refuses too.
+	reply, err := dispatch(slack.SlashCommand{Command: "/8ball-strict", Text: "on", ChannelID: "C1", UserID: "U1"})
+	if err != nil || strictFor("C1") || !strings.HasPrefix(reply.Text, "Sorry, only admins") {
+		t.Errorf("non-admin dispatch /8ball-strict on = %v, %v; want it refused", reply, err)
+	}
+}
+
+func TestChannelStrictnessOverridesGlobal(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		setting string // "" leaves the channel on the global setting
+		want    bool   // whether the open question is answered
+	}{
+		{"strict channel", "false", "on", false},
+		{"lenient channel", "true", "off", true},
+		{"global strict", "true", "", false},
+		{"global lenient", "false", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, map[string]string{"STRICT_QUESTIONS": tt.global})
+			if tt.setting != "" {
+				strictCommand(slack.SlashCommand{ChannelID: "C1", Text: tt.setting})
+			}
+			resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Why is it raining?"})
+			if resp.answered() != tt.want {
+				t.Errorf("ask in C1 = %q, want answered %v", resp.Text, tt.want)
+			}
+			if !tt.want && resp.Text != replyOpenEnded {
+				t.Errorf("ask in C1 = %q, want %q", resp.Text, replyOpenEnded)
+			}
+
+			// Other channels keep following STRICT_QUESTIONS.
+			other := ask(askRequest{TeamID: "T1", ChannelID: "C2", UserID: "U1", Question: "Why is it raining?"})
+			if other.answered() != (tt.global == "false") {
+				t.Errorf("ask in C2 = %q with STRICT_QUESTIONS=%s", other.Text, tt.global)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+//go:build testhooks
+
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// nextAnswer is the answer index queued by /testhooks/next-answer.
+var nextAnswer struct {
+	mu  sync.Mutex
+	idx int
+	set bool
+}
+
+// forcedNextIndex returns the queued answer index, if any, and clears it,
+// so the override applies to the next answer only. An index out of range
+// for a list of n responses is discarded.
+func forcedNextIndex(n int) (int, bool) {
+	nextAnswer.mu.Lock()
+	defer nextAnswer.mu.Unlock()
+
+	if !nextAnswer.set {
+		return 0, false
+	}
+	nextAnswer.set = false
+	if nextAnswer.idx >= n {
+		return 0, false
+	}
+	return nextAnswer.idx, true
+}
+
+// testHookRoutes lists the endpoints only built with the testhooks tag.
+func testHookRoutes() []route {
+	return []route{
+		{path: "/testhooks/next-answer", purpose: "test hook: force the next answer (?index=N)", handler: handleNextAnswer},
+	}
+}
+
+// handleNextAnswer queues the answer index for the next question, for
+// deterministic end-to-end tests.
+func handleNextAnswer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	idx, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil || idx < 0 {
+		writeJSONError(w, http.StatusBadRequest, "index must be a non-negative integer")
+		return
+	}
+
+	nextAnswer.mu.Lock()
+	nextAnswer.idx, nextAnswer.set = idx, true
+	nextAnswer.mu.Unlock()
+	log.Printf("Test hook: next answer is index %d", idx)
+	w.WriteHeader(http.StatusNoContent)
+}
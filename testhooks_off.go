@@ -0,0 +1,13 @@
+//go:build !testhooks
+
+package main
+
+// forcedNextIndex never overrides the answer outside testhooks builds.
+func forcedNextIndex(n int) (int, bool) {
+	return 0, false
+}
+
+// testHookRoutes is empty outside testhooks builds.
+func testHookRoutes() []route {
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !testhooks
+
+package main
+
+import "testing"
+
+func TestNoTestHooksInProduction(t *testing.T) {
+	if routes := testHookRoutes(); len(routes) != 0 {
+		t.Errorf("testHookRoutes = %+v outside testhooks builds", routes)
+	}
+	if _, ok := forcedNextIndex(20); ok {
+		t.Error("forcedNextIndex overrides answers outside testhooks builds")
+	}
+}
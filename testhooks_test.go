@@ -0,0 +1,86 @@
+//go:build testhooks
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// queueNextAnswer posts index to the next-answer hook and returns the status.
+func queueNextAnswer(t *testing.T, index string) int {
+	t.Helper()
+	t.Cleanup(func() { forcedNextIndex(1) })
+	w := httptest.NewRecorder()
+	handleNextAnswer(w, httptest.NewRequest("POST", "/testhooks/next-answer?index="+index, nil))
+	return w.Code
+}
+
+func TestNextAnswerHookForcesOnce(t *testing.T) {
+	c := withConfig(t, nil)
+	seedRNG(t, 1)
+	if code := queueNextAnswer(t, "3"); code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", code)
+	}
+	req := askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?"}
+	if resp := ask(req); resp.Index != 3 || resp.Text != c.Responses[3] {
+		t.Fatalf("first ask = %q at %d, want the forced %q", resp.Text, resp.Index, c.Responses[3])
+	}
+
+	// Normal picking resumes: with 20 answers, 30 picks at index 3 alone
+	// would mean the override stuck.
+	stuck := true
+	for range 30 {
+		if resp := ask(req); resp.Index != 3 {
+			stuck = false
+		}
+		if _, ok := forcedNextIndex(len(c.Responses)); ok {
+			t.Fatal("the override is still queued after answering")
+		}
+	}
+	if stuck {
+		t.Error("every later answer was the forced one")
+	}
+}
+
+func TestNextAnswerHookOutOfRange(t *testing.T) {
+	c := withConfig(t, nil)
+	queueNextAnswer(t, "99")
+	if _, ok := forcedNextIndex(len(c.Responses)); ok {
+		t.Error("an out-of-range index was used")
+	}
+	if _, ok := forcedNextIndex(len(c.Responses)); ok {
+		t.Error("an out-of-range index stayed queued")
+	}
+}
+
+func TestNextAnswerHookRejects(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		method, index string
+		want          int
+	}{
+		{"POST", "-1", http.StatusBadRequest},
+		{"POST", "first", http.StatusBadRequest},
+		{"POST", "", http.StatusBadRequest},
+		{"GET", "1", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handleNextAnswer(w, httptest.NewRequest(tt.method, "/testhooks/next-answer?index="+tt.index, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s index=%q: status = %d, want %d", tt.method, tt.index, w.Code, tt.want)
+		}
+	}
+	if _, ok := forcedNextIndex(20); ok {
+		t.Error("a rejected request queued an answer")
+	}
+}
+
+func TestNextAnswerHookRouted(t *testing.T) {
+	routes := testHookRoutes()
+	if len(routes) != 1 || routes[0].path != "/testhooks/next-answer" {
+		t.Errorf("testHookRoutes = %+v, want the next-answer hook", routes)
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mentionPattern matches Slack user mentions such as <@U123> or <@U123|sam>.
+var mentionPattern = regexp.MustCompile(`<@[UW][A-Z0-9]+(\|[^>]*)?>`)
+
+// stripMentions removes user mentions from message text.
+func stripMentions(text string) string {
+	return mentionPattern.ReplaceAllString(text, "")
+}
+
+// normalizeText trims text, drops invisible characters and collapses runs of
+// whitespace to single spaces, so padding can't slip past the empty or
+// trivial-question checks or corrupt logs. Trailing punctuation is collapsed
+// too, so "Will it???" becomes "Will it?".
+func normalizeText(text string) string {
+	// The common case needs no copy.
+	if !isNormalized(text) {
+		text = strings.Join(strings.Fields(stripInvisible(text)), " ")
+	}
+	return collapseTrailingPunct(text)
+}
+
+// emojiCodePattern matches emoji as Slack sends them in message and command
+// text, such as :thinking_face: or :skin-tone-2:.
+var emojiCodePattern = regexp.MustCompile(`:[a-z0-9_+'-]+:`)
+
+// hasWords reports whether text has any letters or digits once emoji,
+// punctuation and other symbols are set aside, so "🤔?" and
+// ":thinking_face:?" are wordless while "🤔 rain?" is not.
+func hasWords(text string) bool {
+	return strings.IndexFunc(emojiCodePattern.ReplaceAllString(text, ""), func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r)
+	}) >= 0
+}
+
+// trailingPunct is the punctuation that may end a question.
+const trailingPunct = "?!.…"
+
+// collapseTrailingPunct reduces a run of trailing punctuation to a single
+// mark: "?" if the run has one, so the question check still sees it, and
+// otherwise the run's first mark. "Ok?!" becomes "Ok?" and "Hmm..." "Hmm.".
+func collapseTrailingPunct(text string) string {
+	core := strings.TrimRight(text, trailingPunct)
+	run := text[len(core):]
+	if utf8.RuneCountInString(run) <= 1 {
+		return text
+	}
+	if strings.Contains(run, "?") {
+		return core + "?"
+	}
+	r, _ := utf8.DecodeRuneInString(run)
+	return core + string(r)
+}
+
+// isNormalized reports whether text's whitespace is already normalized:
+// single ASCII spaces between words, none at either end, nothing invisible.
+func isNormalized(text string) bool {
+	prevSpace := true
+	for _, r := range text {
+		if r == ' ' {
+			if prevSpace {
+				return false
+			}
+			prevSpace = true
+			continue
+		}
+		if unicode.IsSpace(r) || r == zeroWidthJoiner || dropInvisible(r) == -1 {
+			return false
+		}
+		prevSpace = false
+	}
+	return !prevSpace || text == ""
+}
+
+// dropInvisible is a strings.Map function removing control characters other
+// than whitespace, and format characters such as zero-width space (U+200B),
+// the zero-width joiner (U+200D) and the byte order mark (U+FEFF).
+func dropInvisible(r rune) rune {
+	switch {
+	case unicode.IsSpace(r):
+		return r
+	case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+		return -1
+	}
+	return r
+}
+
+// zeroWidthJoiner glues emoji into sequences such as 👩‍💻.
+const zeroWidthJoiner = '\u200d'
+
+// stripInvisible applies dropInvisible to text, except that a zero-width
+// joiner directly between two emoji runes is kept so emoji sequences survive.
+// Any other joiner, including a run of them, is as invisible as the rest.
+func stripInvisible(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text))
+	for i, r := range runes {
+		if r == zeroWidthJoiner {
+			if i > 0 && i < len(runes)-1 && isEmojiRune(runes[i-1]) && isEmojiRune(runes[i+1]) {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		if dropInvisible(r) != -1 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isEmojiRune reports whether r can sit either side of a joiner in an emoji
+// sequence: a pictographic symbol, a skin-tone modifier or the emoji
+// presentation selector (U+FE0F), as in ❤️‍🔥.
+func isEmojiRune(r rune) bool {
+	switch {
+	case r == '\ufe0f':
+		return true
+	case r >= '\U0001F3FB' && r <= '\U0001F3FF':
+		return true
+	}
+	return unicode.Is(unicode.So, r)
+}
+
+// slackEscaper escapes the characters Slack treats as control sequences.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeSlackText makes user-supplied text safe to echo in a Slack message.
+func escapeSlackText(text string) string {
+	return slackEscaper.Replace(text)
+}
+
+// truncateRunes shortens text to at most n runes, ending it with an
+// ellipsis when anything was cut. n <= 0 means no limit.
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if n <= 0 || len(runes) <= n {
+		return text
+	}
+	return strings.TrimRightFunc(string(runes[:n-1]), unicode.IsSpace) + "…"
+}
+
+// echoText is question as echoed back into a channel: normalized, cut to
+// ECHO_MAX_LEN runes and escaped.
+func echoText(question string) string {
+	return escapeSlackText(truncateRunes(normalizeText(question), cfg.EchoMaxLen))
+}
+
+// threadReplyText formats an in-thread answer, quoting the original question
+// so the thread reads naturally.
+func threadReplyText(question, answer string) string {
+	quoted := echoText(stripMentions(question))
+	if quoted == "" {
+		return answer
+	}
+	return "> " + quoted + "\n" + answer
+}
+
+// categoryEmoji are the prefixes EMOJI_PREFIX picks from for each category.
+var categoryEmoji = map[string][]string{
+	categoryYes:   {"✅", "👍", "🎉"},
+	categoryNo:    {"🚫", "👎", "⛔"},
+	categoryMaybe: {"🔮", "✨", "🌫️"},
+}
+
+// prefixEmoji picks an emoji suiting category, or 🎱 if it has none.
+func prefixEmoji(category string, r *rand.Rand) string {
+	set := categoryEmoji[category]
+	if len(set) == 0 {
+		return "🎱"
+	}
+	return set[r.Intn(len(set))]
+}
+
+// answerPrefix is the emoji shown before an answer: 🎱, or with EMOJI_PREFIX
+// one suiting the answer's category.
+func answerPrefix(answer string) string {
+	if !liveFlags().EmojiPrefix {
+		return "🎱"
+	}
+	return prefixEmoji(categoryOf(answer), rng)
+}
+
+// parseCategorySuffix parses CATEGORY_SUFFIX entries such as "yes:🎉".
+func parseCategorySuffix(entries []string) (map[string]string, error) {
+	suffixes := make(map[string]string, len(entries))
+	for _, e := range entries {
+		category, suffix, ok := strings.Cut(e, ":")
+		category, suffix = strings.ToLower(strings.TrimSpace(category)), strings.TrimSpace(suffix)
+		if !ok || suffix == "" {
+			return nil, fmt.Errorf("invalid CATEGORY_SUFFIX entry %q: want category:suffix", e)
+		}
+		if !knownCategory(category) {
+			return nil, fmt.Errorf("invalid CATEGORY_SUFFIX entry %q: unknown category %q", e, category)
+		}
+		suffixes[category] = suffix
+	}
+	return suffixes, nil
+}
+
+// applyCategorySuffix returns resp's text with the suffix configured for its
+// category, if any.
+func applyCategorySuffix(resp Response, suffixes map[string]string) string {
+	if suffix, ok := suffixes[resp.Category]; ok {
+		return resp.Text + " " + suffix
+	}
+	return resp.Text
+}
+
+// displayText is resp's text as shown to users, with any reason, followed
+// by its note.
+func displayText(resp Response) string {
+	if resp.Reason != "" {
+		resp.Text += " — " + resp.Reason
+	}
+	text := applyCategorySuffix(resp, cfg.CategorySuffix)
+	if resp.Note != "" {
+		text += "\n" + resp.Note
+	}
+	return text
+}
+
+// matchCase mirrors question's casing onto answer: a question shouted
+// predominantly in capitals gets a shouted answer, an all-lowercase one a
+// lowercase answer. Anything else leaves answer as it is.
+func matchCase(question, answer string) string {
+	var upper, lower int
+	for _, r := range question {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	switch {
+	case upper >= 3 && upper >= 4*lower:
+		return strings.ToUpper(answer)
+	case lower > 0 && upper == 0:
+		return strings.ToLower(answer)
+	}
+	return answer
+}
+
+// replyText is displayText for an answer to question, with its casing
+// mirrored when MIRROR_CASE is set, and MRKDWN_EMPHASIS and a LUCKY_NUMBER
+// line for a genuine answer.
+func replyText(question string, resp Response) string {
+	resp.Text = answerText(question, resp)
+	return addLuckyNumber(resp, displayText(resp))
+}
+
+// answerText is resp's text alone, as replyText shows it.
+func answerText(question string, resp Response) string {
+	if liveFlags().MirrorCase {
+		resp.Text = matchCase(question, resp.Text)
+	}
+	if flags := liveFlags(); flags.MrkdwnEmphasis && !flags.WindowArt && resp.answered() {
+		resp.Text = emphasize(resp)
+	}
+	return resp.Text
+}
+
+// addLuckyNumber adds the LUCKY_NUMBER line to text shown for resp, if it is a
+// genuine answer.
+func addLuckyNumber(resp Response, text string) string {
+	if liveFlags().LuckyNumber && resp.answered() {
+		text = withLuckyNumber(text, rng)
+	}
+	return text
+}
+
+// withLuckyNumber adds a lottery-style lucky number from 1 to 99, picked
+// with r, on its own line after text.
+func withLuckyNumber(text string, r *rand.Rand) string {
+	return fmt.Sprintf("%s\n🔢 Lucky number: %d", text, luckyNumber(r))
+}
+
+// luckyNumber picks a lucky number from 1 to 99 with r.
+func luckyNumber(r *rand.Rand) int {
+	return 1 + r.Intn(99)
+}
+
+// categoryEmphasis is the mrkdwn marker emphasize wraps each category in.
+var categoryEmphasis = map[string]string{
+	categoryYes: "*",
+	categoryNo:  "_",
+}
+
+// emphasize is resp's text in bold for a yes answer or italics for a no.
+// Slack has no way to escape the markers, so a custom answer that already
+// has some is left as it is rather than risk unbalancing them. Other answers
+// are unchanged.
+func emphasize(resp Response) string {
+	marker, ok := categoryEmphasis[resp.Category]
+	if !ok || strings.ContainsAny(resp.Text, "*_") {
+		return resp.Text
+	}
+	return marker + resp.Text + marker
+}
+
+// renderWindow draws answer in a little triangular window, like the die
+// floating in a real 8-ball. It is a code block so the lines stay aligned.
+func renderWindow(answer string) string {
+	width := utf8.RuneCountInString(answer) + 4
+	return "```\n" +
+		strings.Repeat(" ", width/2) + "▲\n" +
+		"╱ " + answer + " ╲\n" +
+		strings.Repeat("▔", width) + "\n```"
+}
+
+// windowText is replyText with a genuine answer in the 8-ball window when
+// WINDOW_ART is set. Only the answer goes in the window; its reason, note and
+// lucky number follow the code block on lines of their own.
+func windowText(question string, resp Response) string {
+	if !liveFlags().WindowArt || !resp.answered() {
+		return replyText(question, resp)
+	}
+	resp.Text = answerText(question, resp)
+	text := renderWindow(applyCategorySuffix(resp, cfg.CategorySuffix))
+	if resp.Reason != "" {
+		text += "\n" + resp.Reason
+	}
+	if resp.Note != "" {
+		text += "\n" + resp.Note
+	}
+	return addLuckyNumber(resp, text)
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHasWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"🤔?", false},
+		{"🤔🔮 ?!", false},
+		{":thinking_face:?", false},
+		{":+1::skin-tone-2: :eight-ball:?", false},
+		{"¿¡…?", false},
+		{"🤔 rain?", true},
+		{":thinking_face: will it rain?", true},
+		{"Will it rain?", true},
+		{"Überall?", true},
+		{"明日は雨?", true},
+		{"2024?", true},
+		{"Back at 10:30:00?", true},
+	}
+	for _, tt := range tests {
+		if got := hasWords(tt.text); got != tt.want {
+			t.Errorf("hasWords(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestThreadReplyText(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		name     string
+		question string
+		want     string
+	}{
+		{"mention stripped", "<@U0BOT> Will it rain?", "> Will it rain?\nYes."},
+		{"labelled mention stripped", "<@W0BOT|8ball>   Will   it rain?", "> Will it rain?\nYes."},
+		{"mention mid-question", "Should <@U0BOT> <@U123> decide?", "> Should decide?\nYes."},
+		{"special chars escaped", "<@U0BOT> Is A<B & C>D?", "> Is A&lt;B &amp; C&gt;D?\nYes."},
+		{"broadcast escaped", "<@U0BOT> <!channel> ok?", "> &lt;!channel&gt; ok?\nYes."},
+		{"punctuation collapsed", "<@U0BOT> Really sure???", "> Really sure?\nYes."},
+		{"only a mention", "<@U0BOT>", "Yes."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := threadReplyText(tt.question, "Yes."); got != tt.want {
+				t.Errorf("threadReplyText(%q) = %q, want %q", tt.question, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTextInvisible(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"zero-width space", "\u200b\u200b?", "?"},
+		{"BOM", "\ufeffWill it rain?", "Will it rain?"},
+		{"control characters", "Will\x00 it\x07 rain?", "Will it rain?"},
+		{"zero-width inside a word", "ra\u200bin?", "rain?"},
+		{"lone joiners", "\u200d\u200d?", "?"},
+		{"joiner between letters", "a\u200db?", "ab?"},
+		{"joiner in an emoji sequence", "👩\u200d💻 ok?", "👩\u200d💻 ok?"},
+		{"doubled joiner between emoji", "🤔\u200d\u200d🤔?", "🤔🤔?"},
+		{"whitespace kept as spaces", "Will\tit\nrain?", "Will it rain?"},
+		{"already normal", "Will it rain?", "Will it rain?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeText(tt.text); got != tt.want {
+				t.Errorf("normalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeroWidthPaddedQuestions(t *testing.T) {
+	c := withConfig(t, nil)
+	tests := []struct {
+		text string
+		want error
+	}{
+		{"\u200b\u200b\u200b", ErrNoQuestion},
+		{"\ufeff \u200b ", ErrNoQuestion},
+		{"\u200bRight\u200b?", ErrTrivialQuestion},
+		{"Re\u200ball\u200by?", ErrTrivialQuestion},
+		{"\u200b\u200d?", ErrNoWords},
+	}
+	for _, tt := range tests {
+		_, err := Answer(tt.text, c.Responses, true, rand.New(rand.NewSource(1)))
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Answer(%q) error = %v, want %v", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTextFastPath(t *testing.T) {
+	// The slow path on its own, as normalizeText was before isNormalized.
+	slow := func(text string) string {
+		return collapseTrailingPunct(strings.Join(strings.Fields(stripInvisible(text)), " "))
+	}
+	for _, text := range []string{
+		"", "Will it rain?", " Will it rain?", "Will it rain? ", "Will  it rain?",
+		"Will\tit rain?", "Will it\u00a0rain?", "Will it\u200b rain?", "Will it rain???",
+		"👩\u200d💻 ok?", "?", " ", "Ünïcödé ok?",
+	} {
+		if got, want := normalizeText(text), slow(text); got != want {
+			t.Errorf("normalizeText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestNormalizeTextNoAllocs(t *testing.T) {
+	if n := testing.AllocsPerRun(100, func() { normalizeText("Will the deploy work?") }); n != 0 {
+		t.Errorf("normalizeText of a tidy question allocates %v times, want 0", n)
+	}
+}
+
+func TestCollapseTrailingPunct(t *testing.T) {
+	c := withConfig(t, nil)
+	tests := []struct {
+		text string
+		want string
+		err  error
+	}{
+		{"Will it???", "Will it?", nil},
+		{"Really??", "Really?", ErrTrivialQuestion},
+		{"Ok?!", "Ok?", ErrTrivialQuestion},
+		{"Will it rain?!?", "Will it rain?", nil},
+		{"Will it rain…?", "Will it rain?", nil},
+		{"Hmm...", "Hmm.", ErrNoQuestion},
+		{"Will it rain?", "Will it rain?", nil},
+		{"???", "?", ErrNoWords},
+	}
+	for _, tt := range tests {
+		if got := normalizeText(tt.text); got != tt.want {
+			t.Errorf("normalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+		if _, err := Answer(tt.text, c.Responses, true, rand.New(rand.NewSource(1))); !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+			t.Errorf("Answer(%q) error = %v, want %v", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestPrefixEmoji(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for category, set := range categoryEmoji {
+		seen := map[string]bool{}
+		for range 100 {
+			emoji := prefixEmoji(category, r)
+			if !slices.Contains(set, emoji) {
+				t.Fatalf("prefixEmoji(%s) = %s, not in %q", category, emoji, set)
+			}
+			seen[emoji] = true
+		}
+		if len(seen) != len(set) {
+			t.Errorf("100 %s prefixes used only %d of %q", category, len(seen), set)
+		}
+	}
+	for _, category := range []string{"", "perhaps"} {
+		if got := prefixEmoji(category, r); got != "🎱" {
+			t.Errorf("prefixEmoji(%q) = %s, want 🎱", category, got)
+		}
+	}
+}
+
+func TestAnswerPrefix(t *testing.T) {
+	seedRNG(t, 1)
+	for _, on := range []string{"true", "false"} {
+		withConfig(t, map[string]string{"EMOJI_PREFIX": on})
+		for answer, category := range map[string]string{"It is certain.": categoryYes, "Very doubtful.": categoryNo, "Ask again later.": categoryMaybe, "Something new.": ""} {
+			got := answerPrefix(answer)
+			want := []string{"🎱"}
+			if on == "true" && category != "" {
+				want = categoryEmoji[category]
+			}
+			if !slices.Contains(want, got) {
+				t.Errorf("EMOJI_PREFIX=%s: answerPrefix(%q) = %s, want one of %q", on, answer, got, want)
+			}
+		}
+	}
+}
+
+func TestApplyCategorySuffix(t *testing.T) {
+	suffixes := map[string]string{categoryYes: "🎉", categoryNo: "😬"}
+	tests := []struct {
+		resp Response
+		want string
+	}{
+		{Response{Text: "It is certain.", Category: categoryYes}, "It is certain. 🎉"},
+		{Response{Text: "Very doubtful.", Category: categoryNo}, "Very doubtful. 😬"},
+		{Response{Text: "Ask again later.", Category: categoryMaybe}, "Ask again later."},
+		{Response{Text: replyNoQuestion}, replyNoQuestion},
+	}
+	for _, tt := range tests {
+		if got := applyCategorySuffix(tt.resp, suffixes); got != tt.want {
+			t.Errorf("applyCategorySuffix(%q) = %q, want %q", tt.resp.Text, got, tt.want)
+		}
+		if got := applyCategorySuffix(tt.resp, nil); got != tt.resp.Text {
+			t.Errorf("applyCategorySuffix(%q) without suffixes = %q", tt.resp.Text, got)
+		}
+	}
+}
+
+func TestParseCategorySuffix(t *testing.T) {
+	got, err := parseCategorySuffix([]string{"yes:🎉", " NO : 😬 ", "maybe:🤷:ish"})
+	if err != nil {
+		t.Fatalf("parseCategorySuffix: %v", err)
+	}
+	if want := map[string]string{categoryYes: "🎉", categoryNo: "😬", categoryMaybe: "🤷:ish"}; !maps.Equal(got, want) {
+		t.Errorf("parseCategorySuffix = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"yes", "yes:", "perhaps:🤔", ":🎉"} {
+		if _, err := parseCategorySuffix([]string{bad}); err == nil {
+			t.Errorf("parseCategorySuffix(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestCategorySuffixInReplies(t *testing.T) {
+	withConfig(t, map[string]string{"CATEGORY_SUFFIX": "yes:🎉"})
+	if got := displayText(Response{Text: "It is certain.", Category: categoryYes, Reason: "The stars agree."}); got != "It is certain. — The stars agree. 🎉" {
+		t.Errorf("displayText = %q, want the suffix after the reason", got)
+	}
+	if got := displayText(Response{Text: "Very doubtful.", Category: categoryNo}); got != "Very doubtful." {
+		t.Errorf("displayText = %q, want no suffix for no", got)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"Will it rain?", 140, "Will it rain?"},
+		{"Will it rain?", 13, "Will it rain?"},
+		{"Will it rain?", 12, "Will it rai…"},
+		{"Will it rain?", 9, "Will it…"},
+		{"Will it rain?", 0, "Will it rain?"},
+		{"Été à Zürich?", 6, "Été à…"},
+		{"🌧🌧🌧🌧", 3, "🌧🌧…"},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateRunes(tt.text, tt.n); got != tt.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestEchoText(t *testing.T) {
+	long := "Will it " + strings.Repeat("really ", 40) + "rain?"
+	tests := []struct {
+		maxLen string
+		q      string
+		want   string
+	}{
+		{"140", "  Will  it rain? ", "Will it rain?"},
+		{"140", long, truncateRunes(long, 140)},
+		{"20", "Will <it> rain & pour?", "Will &lt;it&gt; rain &amp; po…"},
+		{"0", long, long},
+	}
+	for _, tt := range tests {
+		withConfig(t, map[string]string{"ECHO_MAX_LEN": tt.maxLen})
+		got := echoText(tt.q)
+		if got != tt.want {
+			t.Errorf("ECHO_MAX_LEN=%s: echoText(%q) = %q, want %q", tt.maxLen, tt.q, got, tt.want)
+		}
+		if n := len([]rune(got)); tt.maxLen == "140" && n > 140 {
+			t.Errorf("echoText(%q) is %d runes", tt.q, n)
+		}
+	}
+	for _, bad := range []string{"-1", "1", "lots"} {
+		t.Setenv("ECHO_MAX_LEN", bad)
+		if _, err := loadConfig(); err == nil {
+			t.Errorf("ECHO_MAX_LEN=%s loaded", bad)
+		}
+	}
+}
+
+func TestMatchCase(t *testing.T) {
+	tests := []struct {
+		name     string
+		question string
+		want     string
+	}{
+		{"all caps", "WILL IT RAIN?", "IT IS CERTAIN."},
+		{"mostly caps", "WILL IT RAIN IN PARIS, Bob?", "IT IS CERTAIN."},
+		{"all lowercase", "will it rain?", "it is certain."},
+		{"mixed", "Will it rain?", "It is Certain."},
+		{"an acronym", "Will the API be up?", "It is Certain."},
+		{"short caps", "OK?", "It is Certain."},
+		{"no letters", "???", "It is Certain."},
+		{"accented caps", "ÉTÉ ICI DEMAIN?", "IT IS CERTAIN."},
+	}
+	for _, tt := range tests {
+		if got := matchCase(tt.question, "It is Certain."); got != tt.want {
+			t.Errorf("%s: matchCase(%q) = %q, want %q", tt.name, tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestAnswerTextMirrorCase(t *testing.T) {
+	resp := Response{Text: "It is certain.", Category: categoryYes}
+	for _, tt := range []struct {
+		mirror string
+		want   string
+	}{
+		{"true", "IT IS CERTAIN."},
+		{"false", "It is certain."},
+	} {
+		withConfig(t, map[string]string{"MIRROR_CASE": tt.mirror})
+		if got := answerText("WILL IT RAIN?", resp); got != tt.want {
+			t.Errorf("MIRROR_CASE=%s: answerText = %q, want %q", tt.mirror, got, tt.want)
+		}
+	}
+}
+
+func TestRenderWindow(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{"Yes.", "```\n    ▲\n╱ Yes. ╲\n▔▔▔▔▔▔▔▔\n```"},
+		{"Ask again later.", "```\n          ▲\n╱ Ask again later. ╲\n" + strings.Repeat("▔", 20) + "\n```"},
+		{"Très bien.", "```\n       ▲\n╱ Très bien. ╲\n" + strings.Repeat("▔", 14) + "\n```"},
+	}
+	for _, tt := range tests {
+		if got := renderWindow(tt.answer); got != tt.want {
+			t.Errorf("renderWindow(%q) =\n%s\nwant\n%s", tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestWindowText(t *testing.T) {
+	answer := Response{Text: "It is certain.", Category: categoryYes, Reason: "The stars align."}
+	tests := []struct {
+		name string
+		art  string
+		resp Response
+		want string
+	}{
+		{"on", "true", answer, renderWindow("It is certain.") + "\nThe stars align."},
+		{"off", "false", answer, replyText("Will it rain?", answer)},
+		{"rejected question", "true", Response{Text: replyOpenEnded, Index: -1}, replyOpenEnded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, map[string]string{"WINDOW_ART": tt.art, "SHOW_REASON": "true"})
+			got := windowText("Will it rain?", tt.resp)
+			if got != tt.want {
+				t.Errorf("windowText = %q, want %q", got, tt.want)
+			}
+			if tt.art == "false" && strings.Contains(got, "▲") {
+				t.Errorf("windowText = %q drew the window with WINDOW_ART off", got)
+			}
+		})
+	}
+}
+
+func TestLuckyNumber(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	seen := map[int]bool{}
+	for range 5000 {
+		n := luckyNumber(r)
+		if n < 1 || n > 99 {
+			t.Fatalf("luckyNumber = %d, want 1-99", n)
+		}
+		seen[n] = true
+	}
+	if len(seen) != 99 {
+		t.Errorf("5000 lucky numbers covered %d of 1-99", len(seen))
+	}
+}
+
+func TestReplyTextLuckyNumber(t *testing.T) {
+	answer := Response{Text: "It is certain.", Category: categoryYes}
+	tests := []struct {
+		name string
+		env  map[string]string
+		resp Response
+		want string // "" for no lucky number
+	}{
+		{"enabled", map[string]string{"LUCKY_NUMBER": "true"}, answer, "It is certain.\n🔢 Lucky number: %d"},
+		{"after the suffix", map[string]string{"LUCKY_NUMBER": "true", "CATEGORY_SUFFIX": "yes:🎉"}, answer, "It is certain. 🎉\n🔢 Lucky number: %d"},
+		{"disabled", map[string]string{"LUCKY_NUMBER": "false"}, answer, ""},
+		{"not an answer", map[string]string{"LUCKY_NUMBER": "true"}, Response{Text: replyOpenEnded, Index: -1}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			seedRNG(t, 42)
+			got := replyText("Will it rain?", tt.resp)
+			if tt.want == "" {
+				if strings.Contains(got, "Lucky number") {
+					t.Errorf("replyText = %q, want no lucky number", got)
+				}
+				return
+			}
+			// The same seed brings the same number, on a line of its own so
+			// the answer and its category suffix are untouched.
+			if want := fmt.Sprintf(tt.want, luckyNumber(newLockedRand(42))); got != want {
+				t.Errorf("replyText = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestEmphasize(t *testing.T) {
+	tests := []struct {
+		name string
+		resp Response
+		want string
+	}{
+		{"yes", Response{Text: "It is certain.", Category: categoryYes}, "*It is certain.*"},
+		{"no", Response{Text: "Very doubtful.", Category: categoryNo}, "_Very doubtful._"},
+		{"maybe", Response{Text: "Ask again later.", Category: categoryMaybe}, "Ask again later."},
+		{"no category", Response{Text: "Who *knows*?"}, "Who *knows*?"},
+		{"escaped bold", Response{Text: "Yes, *definitely*.", Category: categoryYes}, "Yes, *definitely*."},
+		{"escaped italics", Response{Text: "No_way, not *ever*.", Category: categoryNo}, "No_way, not *ever*."},
+	}
+	for _, tt := range tests {
+		if got := emphasize(tt.resp); got != tt.want {
+			t.Errorf("%s: emphasize = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReplyTextEmphasis(t *testing.T) {
+	answer := Response{Text: "It is certain.", Category: categoryYes}
+	tests := []struct {
+		name string
+		env  map[string]string
+		resp Response
+		want string
+	}{
+		{"enabled", map[string]string{"MRKDWN_EMPHASIS": "true"}, answer, "*It is certain.*"},
+		{"disabled", map[string]string{"MRKDWN_EMPHASIS": "false"}, answer, "It is certain."},
+		{"not an answer", map[string]string{"MRKDWN_EMPHASIS": "true"}, Response{Text: replyOpenEnded, Index: -1, Category: categoryYes}, replyOpenEnded},
+		{"window art", map[string]string{"MRKDWN_EMPHASIS": "true", "WINDOW_ART": "true"}, answer, "It is certain."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			if got := answerText("Will it rain?", tt.resp); got != tt.want {
+				t.Errorf("answerText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/slack-go/slack"
+)
+
+// defaultTheme is used when THEME is not set.
+const defaultTheme = "classic"
+
+// builtinThemes are the answer lists a workspace or channel can choose from.
+var builtinThemes = map[string][]string{
+	"classic": magicResponse,
+	"pirate": {
+		"Aye, without a doubt.",
+		"Aye aye, cap'n!",
+		"The winds be in yer favour.",
+		"As sure as the tide.",
+		"Shiver me timbers, yes!",
+		"X marks the spot.",
+		"The fog be too thick to tell.",
+		"Ask again when the tide turns.",
+		"Consult the parrot and ask again.",
+		"Me compass be spinnin'.",
+		"Walk the plank, ye scallywag.",
+		"Nay.",
+		"Not on yer life, matey.",
+		"Davy Jones says no.",
+		"The kraken disagrees.",
+	},
+	"corporate": {
+		"Let's circle back: yes.",
+		"That's a win-win.",
+		"Green light from leadership.",
+		"Fully aligned.",
+		"Ship it.",
+		"Let's take that offline.",
+		"Let's park that until next quarter.",
+		"Pending stakeholder review.",
+		"That's not on the roadmap.",
+		"We're deprioritizing that.",
+		"Hard pass from legal.",
+		"Not in this fiscal year.",
+	},
+}
+
+// themeNames returns the configured theme names in sorted order.
+func themeNames() []string {
+	themes := currentResponses().Themes
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// channelThemeStore remembers the theme each channel picked with /8ball-theme.
+type channelThemeStore struct {
+	mu     sync.Mutex
+	themes map[string]string
+}
+
+// channelThemes is shared by all requests.
+var channelThemes = &channelThemeStore{themes: make(map[string]string)}
+
+// set selects theme for channelID, failing if the theme doesn't exist.
+func (s *channelThemeStore) set(channelID, theme string) error {
+	if _, ok := currentResponses().Themes[theme]; !ok {
+		return fmt.Errorf("unknown theme %q; available themes: %s", theme, strings.Join(themeNames(), ", "))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.themes[channelID] = theme
+	return nil
+}
+
+// get returns the theme chosen for channelID, if any.
+func (s *channelThemeStore) get(channelID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	theme, ok := s.themes[channelID]
+	return theme, ok
+}
+
+// themeCommand shows or sets the channel's theme. Answers set up for the
+// whole workspace or organization take precedence over any theme, so the
+// reply says so rather than implying the theme is in use.
+func themeCommand(cmd slack.SlashCommand) *Reply {
+	theme := strings.ToLower(normalizeText(cmd.Text))
+	scope := overrideScope(cmd.EnterpriseID, cmd.TeamID)
+	if theme == "" {
+		if scope != "" {
+			return &Reply{Text: fmt.Sprintf("🎱 This channel uses your %s's own answers, not a theme. Available themes: %s", scope, strings.Join(themeNames(), ", "))}
+		}
+		current, ok := channelThemes.get(cmd.ChannelID)
+		if !ok {
+			current = cfg.Theme
+		}
+		return &Reply{Text: fmt.Sprintf("🎱 This channel uses the %s theme. Available themes: %s", current, strings.Join(themeNames(), ", "))}
+	}
+
+	if err := channelThemes.set(cmd.ChannelID, theme); err != nil {
+		return &Reply{Text: "🎱 " + err.Error()}
+	}
+	log.Printf("Channel %s switched to the %s theme", logID(cmd.ChannelID), theme)
+	if scope != "" {
+		return &Reply{Text: fmt.Sprintf("🎱 This channel's theme is now %s, but your %s's own answers take precedence, so it won't be used while they are set.", theme, scope)}
+	}
+	return &Reply{Text: fmt.Sprintf("🎱 This channel now uses the %s theme.", theme)}
+}
+
+// handleTheme processes the /8ball-theme slash command
+func handleTheme(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, themeCommand)
+}
+
+// parseBlend parses a THEME_BLEND value such as "classic:7,pirate:3".
+func parseBlend(entries []string) (map[string]int, error) {
+	blend := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		name, weight, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid THEME_BLEND entry %q: want theme:weight", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil {
+			return nil, fmt.Errorf("invalid THEME_BLEND weight in %q: %w", entry, err)
+		}
+		blend[strings.ToLower(strings.TrimSpace(name))] += n
+	}
+	return blend, nil
+}
+
+// maxBlendWeight caps each THEME_BLEND weight, and maxBlendPool the number
+// of entries in the blended pool, so a blend can't allocate without bound.
+const (
+	maxBlendWeight = 100
+	maxBlendPool   = 1000
+)
+
+// buildBlendedPool mixes themes, already filtered through DISABLED_RESPONSES,
+// into one response list in which each theme's share of entries is
+// proportional to its weight, however many answers the theme has. Answers
+// are repeated as needed to get there; when the exact mix would need more
+// than maxBlendPool entries, the shares are rounded to fit instead.
+func buildBlendedPool(themes map[string][]string, blend map[string]int) ([]string, error) {
+	if len(blend) == 0 {
+		return nil, errors.New("theme blend is empty")
+	}
+
+	names := make([]string, 0, len(blend))
+	total := 0
+	for name, weight := range blend {
+		resps, ok := themes[name]
+		if !ok {
+			return nil, fmt.Errorf("theme blend: unknown theme %q", name)
+		}
+		if weight <= 0 || weight > maxBlendWeight {
+			return nil, fmt.Errorf("theme blend: weight for %s must be between 1 and %d, got %d", name, maxBlendWeight, weight)
+		}
+		if len(resps) == 0 {
+			return nil, fmt.Errorf("theme blend: theme %s has no responses", name)
+		}
+		names = append(names, name)
+		total += weight
+	}
+	slices.Sort(names)
+
+	// Repeat each answer of theme t weight(t)*L/len(t) times, where L is the
+	// least common multiple of the theme lengths, then reduce by the common
+	// factor so the pool stays as small as possible.
+	l := 1
+	for _, name := range names {
+		l = lcm(l, len(themes[name]))
+	}
+	reps := make(map[string]int, len(names))
+	g := 0
+	size := 0
+	for _, name := range names {
+		reps[name] = blend[name] * l / len(themes[name])
+		g = gcd(g, reps[name])
+		size += blend[name] * l
+	}
+	for _, name := range names {
+		if size/g <= maxBlendPool {
+			reps[name] /= g
+			continue
+		}
+		// Give theme t about weight(t)/total of maxBlendPool entries, and
+		// every answer at least one.
+		share := float64(blend[name]) / float64(total) * maxBlendPool
+		reps[name] = max(1, int(math.Round(share/float64(len(themes[name])))))
+	}
+
+	var pool []string
+	for _, name := range names {
+		for _, text := range themes[name] {
+			for range reps[name] {
+				pool = append(pool, text)
+			}
+		}
+	}
+	return pool, nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func lcm(a, b int) int {
+	return a / gcd(a, b) * b
+}
+
+// themesCommand lists every theme with a sample answer from each.
+func themesCommand(cmd slack.SlashCommand) *Reply {
+	var b strings.Builder
+	b.WriteString("🎱 Available themes:")
+	themes := currentResponses().Themes
+	for _, name := range themeNames() {
+		fmt.Fprintf(&b, "\n• *%s*", name)
+		if resps := themes[name]; len(resps) > 0 {
+			fmt.Fprintf(&b, " - \"%s\"", resps[0])
+		}
+	}
+	b.WriteString("\nPick one with /8ball-theme <name>.")
+	return &Reply{Text: b.String()}
+}
+
+// handleThemes processes the /8ball-themes slash command
+func handleThemes(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, themesCommand)
+}
+
+// previewSize is how many answers /8ball-preview samples from a theme.
+const previewSize = 3
+
+// previewAnswers samples up to n distinct answers from theme's list, in
+// random order, failing if the theme doesn't exist.
+func previewAnswers(theme string, n int, r *rand.Rand) ([]string, error) {
+	resps, ok := currentResponses().Themes[theme]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q; available themes: %s", theme, strings.Join(themeNames(), ", "))
+	}
+	var sample []string
+	for _, i := range r.Perm(len(resps)) {
+		if len(sample) == n {
+			break
+		}
+		if !slices.Contains(sample, resps[i]) {
+			sample = append(sample, resps[i])
+		}
+	}
+	return sample, nil
+}
+
+// previewCommand shows a few answers from a theme without switching the
+// channel to it.
+func previewCommand(cmd slack.SlashCommand) *Reply {
+	theme := strings.ToLower(normalizeText(cmd.Text))
+	if theme == "" {
+		return &Reply{Text: "Usage: /8ball-preview <theme>. Available themes: " + strings.Join(themeNames(), ", ")}
+	}
+	sample, err := previewAnswers(theme, previewSize, rng)
+	if err != nil {
+		return &Reply{Text: "🎱 " + err.Error()}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "🎱 A taste of the %s theme:", theme)
+	for _, text := range sample {
+		fmt.Fprintf(&b, "\n• %s", text)
+	}
+	fmt.Fprintf(&b, "\nSwitch with /8ball-theme %s.", theme)
+	return &Reply{Text: b.String()}
+}
+
+// handlePreview processes the /8ball-preview slash command
+func handlePreview(w http.ResponseWriter, r *http.Request, signingSecret string) {
+	serveCommand(w, r, signingSecret, previewCommand)
+}
+
+// purge forgets every channel's theme, returning how many were set.
+func (s *channelThemeStore) purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.themes)
+	clear(s.themes)
+	return n
+}
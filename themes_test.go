@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestThemeCommand(t *testing.T) {
+	withConfig(t, nil)
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"show default", "", "uses the classic theme"},
+		{"set valid", "Pirate", "now uses the pirate theme"},
+		{"show chosen", "", "uses the pirate theme"},
+		{"set invalid", "klingon", `unknown theme "klingon"; available themes: classic, corporate, pirate`},
+		{"invalid leaves the choice", "", "uses the pirate theme"},
+	}
+	for _, tt := range tests {
+		reply := themeCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", Text: tt.text})
+		if !strings.Contains(reply.Text, tt.want) {
+			t.Errorf("%s: reply %q lacks %q", tt.name, reply.Text, tt.want)
+		}
+	}
+}
+
+func TestChannelThemeIsolation(t *testing.T) {
+	c := withConfig(t, nil)
+	themeCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", Text: "pirate"})
+
+	tests := []struct {
+		channel string
+		want    []string
+	}{
+		{"C1", c.Themes["pirate"]},
+		{"C2", c.Responses},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			resp := ask(askRequest{TeamID: "T1", ChannelID: tt.channel, UserID: "U1", Question: "Will it rain?", Reroll: true})
+			if !slices.Contains(tt.want, resp.Text) {
+				t.Fatalf("%s answered %q, which isn't in its theme", tt.channel, resp.Text)
+			}
+		}
+	}
+}
+
+func TestThemeCommandWithOverride(t *testing.T) {
+	path := writeFile(t, "overrides.json", `{"teams": {"T1": ["Team yes."]}, "enterprises": {"E1": ["Org yes."]}}`)
+	withConfig(t, map[string]string{"RESPONSE_OVERRIDES_FILE": path})
+	tests := []struct {
+		name string
+		cmd  slack.SlashCommand
+		want string
+	}{
+		{"show workspace", slack.SlashCommand{TeamID: "T1", ChannelID: "C1"}, "uses your workspace's own answers"},
+		{"set workspace", slack.SlashCommand{TeamID: "T1", ChannelID: "C1", Text: "pirate"}, "your workspace's own answers take precedence"},
+		{"show organization", slack.SlashCommand{EnterpriseID: "E1", TeamID: "T2", ChannelID: "C2"}, "uses your organization's own answers"},
+		{"no override", slack.SlashCommand{TeamID: "T2", ChannelID: "C3", Text: "pirate"}, "now uses the pirate theme"},
+	}
+	for _, tt := range tests {
+		if reply := themeCommand(tt.cmd); !strings.Contains(reply.Text, tt.want) {
+			t.Errorf("%s: reply %q lacks %q", tt.name, reply.Text, tt.want)
+		}
+	}
+}
+
+// themeShares returns the fraction of pool taken by each theme's answers.
+func themeShares(pool []string, themes map[string][]string) map[string]float64 {
+	shares := map[string]float64{}
+	for _, text := range pool {
+		for name, resps := range themes {
+			if slices.Contains(resps, text) {
+				shares[name] += 1 / float64(len(pool))
+			}
+		}
+	}
+	return shares
+}
+
+// numbered returns n distinct answers starting with prefix.
+func numbered(prefix string, n int) []string {
+	resps := make([]string, n)
+	for i := range resps {
+		resps[i] = fmt.Sprintf("%s %d.", prefix, i)
+	}
+	return resps
+}
+
+func TestBuildBlendedPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		themes    map[string][]string
+		blend     map[string]int
+		want      map[string]float64
+		tolerance float64
+	}{
+		{"exact", builtinThemes, map[string]int{"classic": 7, "pirate": 3}, map[string]float64{"classic": 0.7, "pirate": 0.3}, 1e-9},
+		{"three themes", builtinThemes, map[string]int{"classic": 1, "pirate": 1, "corporate": 2}, map[string]float64{"classic": 0.25, "pirate": 0.25, "corporate": 0.5}, 1e-9},
+		{"rounded to fit", map[string][]string{"a": numbered("a", 7), "b": numbered("b", 11), "c": numbered("c", 13)},
+			map[string]int{"a": 97, "b": 89, "c": 83}, map[string]float64{"a": 97.0 / 269, "b": 89.0 / 269, "c": 83.0 / 269}, 0.02},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := buildBlendedPool(tt.themes, tt.blend)
+			if err != nil {
+				t.Fatalf("buildBlendedPool: %v", err)
+			}
+			// Rounding can add up to one entry per answer.
+			answers := 0
+			for name := range tt.blend {
+				answers += len(tt.themes[name])
+			}
+			if len(pool) > maxBlendPool+answers {
+				t.Errorf("pool has %d entries, want about %d at most", len(pool), maxBlendPool)
+			}
+			for name, share := range themeShares(pool, tt.themes) {
+				if math.Abs(share-tt.want[name]) > tt.tolerance {
+					t.Errorf("%s has %.3f of the pool, want %.3f", name, share, tt.want[name])
+				}
+			}
+			for name := range tt.blend {
+				for _, text := range tt.themes[name] {
+					if !slices.Contains(pool, text) {
+						t.Errorf("pool lacks %q", text)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestThemeBlendDistribution(t *testing.T) {
+	c := withConfig(t, map[string]string{"THEME_BLEND": "classic:7,pirate:3"})
+	r := rand.New(rand.NewSource(3))
+	counts := map[string]int{}
+	const draws = 10000
+	for range draws {
+		resp, err := Answer("Will it rain?", c.Responses, true, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, resps := range c.Themes {
+			if slices.Contains(resps, resp.Text) {
+				counts[name]++
+			}
+		}
+	}
+	for name, want := range map[string]float64{"classic": 0.7, "pirate": 0.3} {
+		if got := float64(counts[name]) / draws; math.Abs(got-want) > 0.02 {
+			t.Errorf("%s answered %.3f of the time, want %.2f±0.02", name, got, want)
+		}
+	}
+}
+
+func TestBuildBlendedPoolSingleTheme(t *testing.T) {
+	pool, err := buildBlendedPool(builtinThemes, map[string]int{"pirate": 5})
+	if err != nil || !slices.Equal(pool, builtinThemes["pirate"]) {
+		t.Errorf("buildBlendedPool = %q, %v; want the pirate theme as is", pool, err)
+	}
+}
+
+func TestBuildBlendedPoolRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		themes map[string][]string
+		blend  map[string]int
+	}{
+		{"unknown theme", builtinThemes, map[string]int{"classic": 1, "klingon": 1}},
+		{"zero weight", builtinThemes, map[string]int{"classic": 0}},
+		{"negative weight", builtinThemes, map[string]int{"classic": 3, "pirate": -1}},
+		{"weight too big", builtinThemes, map[string]int{"classic": maxBlendWeight + 1}},
+		{"empty blend", builtinThemes, nil},
+		{"empty theme", map[string][]string{"empty": nil}, map[string]int{"empty": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if pool, err := buildBlendedPool(tt.themes, tt.blend); err == nil {
+				t.Errorf("buildBlendedPool = %d entries, want an error", len(pool))
+			}
+		})
+	}
+}
+
+func TestParseBlend(t *testing.T) {
+	blend, err := parseBlend([]string{"Classic: 7", "pirate:3", "classic:1"})
+	if err != nil || blend["classic"] != 8 || blend["pirate"] != 3 {
+		t.Errorf("parseBlend = %v, %v", blend, err)
+	}
+	for _, entry := range []string{"classic", "classic:lots"} {
+		if _, err := parseBlend([]string{entry}); err == nil {
+			t.Errorf("parseBlend(%q) succeeded, want an error", entry)
+		}
+	}
+}
+
+func TestThemesCommandListsEveryTheme(t *testing.T) {
+	c := withConfig(t, nil)
+	themes := maps.Clone(c.Themes)
+	themes["space"] = []string{"The stars say yes."}
+	c.Themes = themes
+	setConfig(t, c)
+
+	text := themesCommand(slack.SlashCommand{}).Text
+	for name, resps := range c.Themes {
+		if want := fmt.Sprintf("• *%s* - \"%s\"", name, resps[0]); !strings.Contains(text, want) {
+			t.Errorf("themes list lacks %s with a sample:\n%s", name, text)
+		}
+	}
+	if got, want := strings.Count(text, "• "), len(c.Themes); got != want {
+		t.Errorf("themes list has %d entries, want %d:\n%s", got, want, text)
+	}
+	if !strings.Contains(text, "/8ball-theme <name>") {
+		t.Errorf("themes list doesn't say how to pick one:\n%s", text)
+	}
+}
+
+func TestPreviewAnswers(t *testing.T) {
+	c := withConfig(t, nil)
+	r := rand.New(rand.NewSource(3))
+	for range 20 {
+		sample, err := previewAnswers("pirate", previewSize, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sample) != previewSize || len(slices.Compact(slices.Sorted(slices.Values(sample)))) != previewSize {
+			t.Fatalf("sample = %q, want %d different answers", sample, previewSize)
+		}
+		for _, text := range sample {
+			if !slices.Contains(c.Themes["pirate"], text) {
+				t.Fatalf("sample %q isn't from the pirate theme", text)
+			}
+		}
+	}
+	// A short theme is shown whole.
+	c.Themes["tiny"] = []string{"Aye.", "Aye.", "Nay."}
+	setConfig(t, c)
+	if sample, _ := previewAnswers("tiny", previewSize, r); len(sample) != 2 {
+		t.Errorf("tiny sample = %q, want both answers once", sample)
+	}
+	if _, err := previewAnswers("klingon", previewSize, r); err == nil || !strings.Contains(err.Error(), `unknown theme "klingon"`) {
+		t.Errorf("previewAnswers(klingon) error = %v", err)
+	}
+}
+
+func TestPreviewCommand(t *testing.T) {
+	withConfig(t, nil)
+	themeCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", Text: "corporate"})
+	tests := []struct {
+		text string
+		want string
+	}{
+		{" Pirate ", "🎱 A taste of the pirate theme:\n• "},
+		{"pirate", "\nSwitch with /8ball-theme pirate."},
+		{"klingon", `🎱 unknown theme "klingon"; available themes: classic, corporate, pirate`},
+		{"", "Usage: /8ball-preview <theme>. Available themes: classic, corporate, pirate"},
+	}
+	for _, tt := range tests {
+		reply := previewCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1", Text: tt.text})
+		if !strings.Contains(reply.Text, tt.want) {
+			t.Errorf("/8ball-preview %q: reply %q lacks %q", tt.text, reply.Text, tt.want)
+		}
+	}
+	if n := strings.Count(previewCommand(slack.SlashCommand{Text: "pirate"}).Text, "\n• "); n != previewSize {
+		t.Errorf("preview shows %d answers, want %d", n, previewSize)
+	}
+	// Previewing leaves the channel's theme alone.
+	if reply := themeCommand(slack.SlashCommand{TeamID: "T1", ChannelID: "C1"}); !strings.Contains(reply.Text, "uses the corporate theme") {
+		t.Errorf("after previews, /8ball-theme says %q, want corporate", reply.Text)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sync/atomic"
+
+	"github.com/slack-go/slack"
+)
+
+// fatalTokenErrors are the Slack API errors meaning the bot token will
+// never work again, typically because the app was uninstalled.
+var fatalTokenErrors = []string{"token_revoked", "account_inactive", "invalid_auth", "not_authed", "token_expired"}
+
+// isFatalTokenError reports whether err is one of fatalTokenErrors.
+func isFatalTokenError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		return slices.Contains(fatalTokenErrors, slackErr.Err)
+	}
+	return slices.Contains(fatalTokenErrors, err.Error())
+}
+
+// tokenRevoked is set once an outbound call fails with a fatal token error.
+// It takes the bot out of rotation via /ready.
+var tokenRevoked atomic.Bool
+
+// slackCall runs fn, an outbound call to Slack, through slackBreaker. The
+// first fatal token error is logged once with what to do about it, rather
+// than as another generic failure on every call.
+func slackCall(fn func() error) error {
+	err := slackBreaker.do(fn)
+	noteTokenError(err)
+	return err
+}
+
+// noteTokenError logs the first fatal token error, with what to do about
+// it, and takes the bot out of rotation.
+func noteTokenError(err error) {
+	if isFatalTokenError(err) && tokenRevoked.CompareAndSwap(false, true) {
+		logError("Slack rejected the bot token (%v). The app was probably uninstalled or the token revoked. "+
+			"Reinstall the app and set SLACK_BOT_TOKEN to the new token, then restart. /ready now reports unavailable.", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestIsFatalTokenError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{slack.SlackErrorResponse{Err: "token_revoked"}, true},
+		{slack.SlackErrorResponse{Err: "account_inactive"}, true},
+		{slack.SlackErrorResponse{Err: "invalid_auth"}, true},
+		{slack.SlackErrorResponse{Err: "not_authed"}, true},
+		{slack.SlackErrorResponse{Err: "token_expired"}, true},
+		{errors.New("token_revoked"), true},
+		{fmt.Errorf("posting answer: %w", slack.SlackErrorResponse{Err: "account_inactive"}), true},
+		{slack.SlackErrorResponse{Err: "channel_not_found"}, false},
+		{slack.SlackErrorResponse{Err: "ratelimited"}, false},
+		{errors.New("dial tcp: connection refused"), false},
+		{errors.New("the token_revoked flag was odd"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isFatalTokenError(tt.err); got != tt.want {
+			t.Errorf("isFatalTokenError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRevokedTokenTakesBotOutOfRotation(t *testing.T) {
+	withConfig(t, nil)
+	oldBreaker := slackBreaker
+	slackBreaker = newBreaker(5, 30*time.Second)
+	t.Cleanup(func() {
+		slackBreaker = oldBreaker
+		tokenRevoked.Store(false)
+	})
+	logs := captureLog(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"ok":false,"error":"token_revoked"}`)
+	}))
+	t.Cleanup(srv.Close)
+	client := slack.New("xoxb-revoked", slack.OptionAPIURL(srv.URL+"/"))
+
+	for range 2 {
+		if _, err := postMessage(client, "C1", slack.MsgOptionText("Yes.", false)); !isFatalTokenError(err) {
+			t.Fatalf("postMessage error = %v, want token_revoked", err)
+		}
+	}
+	if n := strings.Count(logs.String(), "Slack rejected the bot token"); n != 1 {
+		t.Errorf("logged the revoked token %d times, want once:\n%s", n, logs)
+	}
+	if !strings.Contains(logs.String(), "Reinstall the app") {
+		t.Errorf("log %q doesn't say what to do", logs)
+	}
+
+	rec := httptest.NewRecorder()
+	handleReady(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "bot token revoked") {
+		t.Errorf("/ready = %d %q, want 503 for the revoked token", rec.Code, rec.Body)
+	}
+}
+
+func TestOtherErrorsKeepBotInRotation(t *testing.T) {
+	withConfig(t, nil)
+	t.Cleanup(func() { tokenRevoked.Store(false) })
+	noteTokenError(slack.SlackErrorResponse{Err: "channel_not_found"})
+	noteTokenError(nil)
+	if tokenRevoked.Load() {
+		t.Error("a non-fatal error took the bot out of rotation")
+	}
+}
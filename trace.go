@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+)
+
+// decisionTrace lists, in order, the rules consulted while answering a
+// question, ending with the one that produced the reply. It is only kept
+// with DEBUG_TRACE, to debug how complex configurations interact.
+type decisionTrace []string
+
+// add records that rule was consulted.
+func (t *decisionTrace) add(rule string) {
+	if liveFlags().DebugTrace {
+		*t = append(*t, rule)
+	}
+}
+
+// String joins the trace into one line.
+func (t decisionTrace) String() string {
+	return strings.Join(t, " → ")
+}
+
+// logTrace logs resp's decision trace with DEBUG_TRACE.
+func logTrace(resp Response) {
+	if liveFlags().DebugTrace {
+		logDebug("decision trace: %s", resp.Trace)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAskDecisionTrace(t *testing.T) {
+	base := map[string]string{"DEBUG_TRACE": "true", "FORCE_YES_WORDS": "pizza", "FORCE_NO_WORDS": "monday"}
+	tests := []struct {
+		name     string
+		env      map[string]string
+		question string
+		want     decisionTrace
+	}{
+		{"forced word", nil, "Will we get pizza?", decisionTrace{"question checks passed", "forced word: yes"}},
+		{"random", nil, "Will it rain?", decisionTrace{"question checks passed", "forced words: no match", "random"}},
+		{"mood", map[string]string{"MOOD": "true"}, "Will it rain?", decisionTrace{"question checks passed", "forced words: no match", "mood", "random"}},
+		{"rejected", nil, "Why is it raining?", decisionTrace{"rejected: " + ErrOpenEnded.Error()}},
+		{"deflected", map[string]string{"DEFLECT_THIRD_PARTY": "true"}, "Does Sam like pizza?", decisionTrace{"deflect third party: Sam"}},
+		{"not deflected", map[string]string{"DEFLECT_THIRD_PARTY": "true"}, "Should I get pizza?", decisionTrace{"deflect third party: no match", "question checks passed", "forced word: yes"}},
+		{"disabled", map[string]string{"DEBUG_TRACE": "false"}, "Will we get pizza?", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range base {
+				t.Setenv(name, value)
+			}
+			withConfig(t, tt.env)
+			// In the morning, so MOOD has a say.
+			setClock(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+			logs := captureDebugLog(t)
+			resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: tt.question})
+			if !slices.Equal(resp.Trace, tt.want) {
+				t.Errorf("trace = %q, want %q", resp.Trace, tt.want)
+			}
+			logged := strings.Contains(logs.String(), "decision trace: "+tt.want.String())
+			if logged != (tt.want != nil) {
+				t.Errorf("log %q, want the trace logged %v", logs, tt.want != nil)
+			}
+		})
+	}
+}
+
+func TestDecisionTraceDoesNotChangeAnswers(t *testing.T) {
+	answers := map[string][]string{}
+	for _, trace := range []string{"false", "true"} {
+		withConfig(t, map[string]string{"DEBUG_TRACE": trace})
+		seedRNG(t, 21)
+		for range 20 {
+			resp := ask(askRequest{TeamID: "T1", ChannelID: "C1", UserID: "U1", Question: "Will it rain?", Reroll: true})
+			answers[trace] = append(answers[trace], resp.Text)
+		}
+	}
+	if !slices.Equal(answers["false"], answers["true"]) {
+		t.Errorf("with the same seed, DEBUG_TRACE changed the answers from %q to %q", answers["false"], answers["true"])
+	}
+}
+
+func TestHandleWebhookAskTrace(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		withConfig(t, map[string]string{"WEBHOOK_TOKEN": "ci-token", "FORCE_YES_WORDS": "pizza", "DEBUG_TRACE": strconv.FormatBool(enabled)})
+		r := httptest.NewRequest("POST", "/webhook/ask", strings.NewReader(`{"question":"Will we get pizza?"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Bearer ci-token")
+		w := httptest.NewRecorder()
+		handleWebhookAsk(w, r)
+		var out map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decoding %q: %v", w.Body, err)
+		}
+		trace, ok := out["trace"]
+		if !enabled {
+			if ok {
+				t.Errorf("DEBUG_TRACE=false: body %s has a trace", w.Body)
+			}
+			continue
+		}
+		if string(trace) != `["question checks passed","forced word: yes"]` {
+			t.Errorf("trace = %s, want the forced word's rules", trace)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxUserHistory bounds how many past answers are kept per user.
+const maxUserHistory = 10
+
+// userState is everything remembered about one user. It is only accessed
+// through userStore, which owns the locking.
+type userState struct {
+	lastQuestion string
+	history      []string // most recent answer last
+	lastSeen     time.Time
+
+	// pendingShake is the normalized question shaken once at pendingAt,
+	// waiting for the second shake SHAKE_TWICE_WINDOW asks for.
+	pendingShake string
+	pendingAt    time.Time
+
+	// lucky is the number set with "/ask8ball set-lucky", if hasLucky.
+	lucky    int
+	hasLucky bool
+}
+
+// userStore holds per-user state shared by concurrent handlers.
+type userStore struct {
+	mu    sync.Mutex
+	users map[string]*userState
+}
+
+func newUserStore() *userStore {
+	return &userStore{users: make(map[string]*userState)}
+}
+
+// users is shared by all requests.
+var users = newUserStore()
+
+// get returns the state for userID, creating it if needed. Callers must hold s.mu.
+func (s *userStore) get(userID string) *userState {
+	u, ok := s.users[userID]
+	if !ok {
+		u = &userState{}
+		s.users[userID] = u
+	}
+	return u
+}
+
+// recordAnswer remembers that userID was given answer to question at t.
+func (s *userStore) recordAnswer(userID, question, answer string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.get(userID)
+	u.lastQuestion = question
+	u.history = append(u.history, answer)
+	if len(u.history) > maxUserHistory {
+		u.history = u.history[len(u.history)-maxUserHistory:]
+	}
+	u.lastSeen = t
+}
+
+// shake records a shake of question by userID at now and reports whether
+// it was the first of two: the same question wasn't already shaken within
+// window. A second shake clears the pending state, so the next ask starts
+// over.
+func (s *userStore) shake(userID, question string, now time.Time, window time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.get(userID)
+	u.lastSeen = now
+	key := strings.ToLower(normalizeText(question))
+	if u.pendingShake == key && now.Sub(u.pendingAt) <= window {
+		u.pendingShake = ""
+		return false
+	}
+	u.pendingShake, u.pendingAt = key, now
+	return true
+}
+
+// needsAnotherShake reports whether userID must shake question again before
+// getting an answer, when SHAKE_TWICE_WINDOW is set.
+func needsAnotherShake(userID, question string) bool {
+	if cfg.ShakeTwiceWindow <= 0 {
+		return false
+	}
+	return users.shake(userID, question, clock(), cfg.ShakeTwiceWindow)
+}
+
+// setLucky sets userID's lucky number to n, or clears it if !ok.
+func (s *userStore) setLucky(userID string, n int, ok bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.get(userID)
+	u.lucky, u.hasLucky = n, ok
+	u.lastSeen = now
+}
+
+// lucky returns userID's lucky number, if they set one.
+func (s *userStore) lucky(userID string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok || !u.hasLucky {
+		return 0, false
+	}
+	return u.lucky, true
+}
+
+// lastAnswer returns the most recent answer given to userID.
+func (s *userStore) lastAnswer(userID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok || len(u.history) == 0 {
+		return "", false
+	}
+	return u.history[len(u.history)-1], true
+}
+
+// history returns a copy of the recent answers given to userID, oldest first.
+func (s *userStore) history(userID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), u.history...)
+}
+
+// reset forgets everything stored about userID, reporting whether there was
+// anything to forget.
+func (s *userStore) reset(userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.users[userID]
+	delete(s.users, userID)
+	return ok
+}
+
+// len returns the number of users with stored state.
+func (s *userStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users)
+}
+
+// prune forgets users not seen for longer than cfg.UserStateTTL, returning
+// how many were pruned. A user with a lucky number keeps it, losing only
+// their recent questions and any pending shake. A zero TTL never prunes.
+func (s *userStore) prune(now time.Time) int {
+	if cfg.UserStateTTL <= 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := now.Add(-cfg.UserStateTTL)
+	removed := 0
+	for id, u := range s.users {
+		if !u.lastSeen.Before(cutoff) {
+			continue
+		}
+		if !u.hasLucky {
+			delete(s.users, id)
+			removed++
+			continue
+		}
+		if u.lastQuestion != "" || len(u.history) > 0 || u.pendingShake != "" {
+			u.lastQuestion, u.history = "", nil
+			u.pendingShake, u.pendingAt = "", time.Time{}
+			removed++
+		}
+	}
+	return removed
+}
+
+// pruneUsers prunes the user store every interval until ctx is done, so
+// state for users who have moved on doesn't accumulate forever.
+func pruneUsers(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := users.prune(clock()); n > 0 {
+				log.Printf("Pruned state for %d inactive users", n)
+			}
+		}
+	}
+}
+
+// purge forgets every user, returning how many there were.
+func (s *userStore) purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.users)
+	clear(s.users)
+	return n
+}
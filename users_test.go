@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestUserStoreHistory(t *testing.T) {
+	s := newUserStore()
+	now := time.Unix(1700000000, 0)
+	if _, ok := s.lastAnswer("U1"); ok {
+		t.Error("lastAnswer for an unknown user is ok")
+	}
+	var want []string
+	for i := range maxUserHistory + 3 {
+		answer := "answer " + strconv.Itoa(i)
+		s.recordAnswer("U1", "Will it rain?", answer, now)
+		want = append(want, answer)
+	}
+	want = want[len(want)-maxUserHistory:]
+	if got := s.history("U1"); !slices.Equal(got, want) {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+	if got, _ := s.lastAnswer("U1"); got != want[len(want)-1] {
+		t.Errorf("lastAnswer = %q, want %q", got, want[len(want)-1])
+	}
+
+	// history hands out a copy.
+	s.history("U1")[0] = "changed"
+	if got := s.history("U1")[0]; got == "changed" {
+		t.Error("history shares its slice with the store")
+	}
+}
+
+func TestUserStoreLuckyAndReset(t *testing.T) {
+	s := newUserStore()
+	now := time.Unix(1700000000, 0)
+	s.setLucky("U1", 7, true, now)
+	if n, ok := s.lucky("U1"); !ok || n != 7 {
+		t.Errorf("lucky = %d, %v; want 7", n, ok)
+	}
+	s.setLucky("U1", 0, false, now)
+	if _, ok := s.lucky("U1"); ok {
+		t.Error("lucky after clearing is still set")
+	}
+	if !s.reset("U1") || s.reset("U1") {
+		t.Error("reset should report state once, then nothing")
+	}
+	if s.len() != 0 {
+		t.Errorf("len = %d after reset, want 0", s.len())
+	}
+}
+
+func TestUserStorePrune(t *testing.T) {
+	withConfig(t, map[string]string{"USER_STATE_TTL": "1h"})
+	s := newUserStore()
+	now := time.Unix(1700000000, 0)
+	s.recordAnswer("old", "q?", "a", now.Add(-2*time.Hour))
+	s.recordAnswer("new", "q?", "a", now.Add(-time.Minute))
+	if n := s.prune(now); n != 1 {
+		t.Errorf("prune = %d, want 1", n)
+	}
+	if _, ok := s.lastAnswer("new"); !ok {
+		t.Error("prune removed a recent user")
+	}
+}
+
+func TestUserStorePruneKeepsActiveUsers(t *testing.T) {
+	withConfig(t, map[string]string{"USER_STATE_TTL": "1h"})
+	s := newUserStore()
+	start := time.Unix(1700000000, 0)
+	s.recordAnswer("asker", "Will it rain?", "Yes.", start)
+	s.setLucky("lucky", 7, true, start)
+	s.recordAnswer("idle", "Will it rain?", "No.", start)
+
+	// Anything a user does counts as activity.
+	later := start.Add(50 * time.Minute)
+	s.recordAnswer("asker", "Will it snow?", "No.", later)
+	s.setLucky("lucky", 8, true, later)
+
+	if n := s.prune(start.Add(90 * time.Minute)); n != 1 {
+		t.Errorf("prune = %d, want only idle pruned", n)
+	}
+	if _, ok := s.lastAnswer("asker"); !ok {
+		t.Error("prune removed a user who asked recently")
+	}
+	if _, ok := s.lucky("lucky"); !ok {
+		t.Error("prune removed a user who set a lucky number recently")
+	}
+	if n := s.prune(start.Add(3 * time.Hour)); n != 1 || s.len() != 1 {
+		t.Errorf("second prune = %d leaving %d, want all but the lucky user gone", n, s.len())
+	}
+}
+
+func TestUserStorePruneKeepsLuckyNumbers(t *testing.T) {
+	withConfig(t, map[string]string{"USER_STATE_TTL": "1h"})
+	s := newUserStore()
+	start := time.Unix(1700000000, 0)
+	s.setLucky("U1", 7, true, start)
+	s.recordAnswer("U1", "Will it rain?", "Yes.", start)
+	s.shake("U1", "Will it snow?", start, 10*time.Hour)
+
+	if n := s.prune(start.Add(2 * time.Hour)); n != 1 {
+		t.Errorf("prune = %d, want U1's questions pruned", n)
+	}
+	if n, ok := s.lucky("U1"); !ok || n != 7 {
+		t.Errorf("lucky after prune = %d, %v; want 7 kept", n, ok)
+	}
+	if answer, ok := s.lastAnswer("U1"); ok {
+		t.Errorf("lastAnswer after prune = %q, want it expired", answer)
+	}
+	if !s.shake("U1", "Will it snow?", start.Add(2*time.Hour), 10*time.Hour) {
+		t.Error("the shake pending before the prune still counted")
+	}
+	// Pruning again has nothing left to expire.
+	if n := s.prune(start.Add(3 * time.Hour)); n != 0 || s.len() != 1 {
+		t.Errorf("second prune = %d leaving %d, want U1 kept as it is", n, s.len())
+	}
+}
+
+func TestUserStorePruneDisabled(t *testing.T) {
+	withConfig(t, map[string]string{"USER_STATE_TTL": "0"})
+	s := newUserStore()
+	s.recordAnswer("U1", "Will it rain?", "Yes.", time.Unix(0, 0))
+	if n := s.prune(time.Now()); n != 0 || s.len() != 1 {
+		t.Errorf("prune with USER_STATE_TTL=0 = %d, want nothing pruned", n)
+	}
+}
+
+func TestPruneUsersInBackground(t *testing.T) {
+	withConfig(t, map[string]string{"USER_STATE_TTL": "1h"})
+	start := time.Unix(1700000000, 0)
+	advance := fakeClock(t, start)
+	users.recordAnswer("old", "Will it rain?", "Yes.", start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pruneUsers(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	advance(30 * time.Minute)
+	users.recordAnswer("new", "Will it rain?", "No.", clock())
+	advance(45 * time.Minute)
+	deadline := time.Now().Add(2 * time.Second)
+	for users.len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d users left, want only the fresh one", users.len())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := users.lastAnswer("new"); !ok {
+		t.Error("background prune removed the fresh user")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("pruneUsers kept running after its context ended")
+	}
+}
+
+// TestUserStoreConcurrent hammers one user from many goroutines. Run it
+// with -race; it also checks that no update is lost or torn.
+func TestUserStoreConcurrent(t *testing.T) {
+	withConfig(t, nil)
+	s := newUserStore()
+	now := time.Unix(1700000000, 0)
+	const workers, rounds = 32, 200
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				s.recordAnswer("U1", "Will it rain?", fmt.Sprintf("%d/%d", w, i), now)
+				s.setLucky("U1", w, true, now)
+				s.shake("U1", "Will it rain?", now, time.Minute)
+				s.lastAnswer("U1")
+				s.lucky("U1")
+				if h := s.history("U1"); len(h) > maxUserHistory {
+					t.Errorf("history has %d entries, more than %d", len(h), maxUserHistory)
+					return
+				}
+				s.reset("U2")
+				s.len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	h := s.history("U1")
+	if len(h) != maxUserHistory {
+		t.Fatalf("history has %d entries, want %d", len(h), maxUserHistory)
+	}
+	// Each worker's answers must appear in the order it recorded them.
+	last := map[string]int{}
+	for _, answer := range h {
+		w, i, _ := strings.Cut(answer, "/")
+		n, err := strconv.Atoi(i)
+		if err != nil {
+			t.Fatalf("torn history entry %q", answer)
+		}
+		if prev, ok := last[w]; ok && n <= prev {
+			t.Errorf("worker %s's answers are out of order in %q", w, h)
+		}
+		last[w] = n
+	}
+	if n, ok := s.lucky("U1"); !ok || n < 0 || n >= workers {
+		t.Errorf("lucky = %d, %v; want one worker's number", n, ok)
+	}
+}
+
+func TestNeedsAnotherShake(t *testing.T) {
+	withConfig(t, map[string]string{"SHAKE_TWICE_WINDOW": "30s"})
+	advance := fakeClock(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	steps := []struct {
+		name     string
+		wait     time.Duration
+		userID   string
+		question string
+		want     bool
+	}{
+		{"first shake", 0, "U1", "Will it rain?", true},
+		{"second shake", 10 * time.Second, "U1", " will it  RAIN? ", false},
+		{"starts over", time.Second, "U1", "Will it rain?", true},
+		{"other user", 0, "U2", "Will it rain?", true},
+		{"other question", time.Second, "U1", "Will it snow?", true},
+		{"expired", 31 * time.Second, "U1", "Will it snow?", true},
+		{"second after expiry", 30 * time.Second, "U1", "Will it snow?", false},
+	}
+	for _, step := range steps {
+		advance(step.wait)
+		if got := needsAnotherShake(step.userID, step.question); got != step.want {
+			t.Errorf("%s: needsAnotherShake(%s, %q) = %v, want %v", step.name, step.userID, step.question, got, step.want)
+		}
+	}
+}
+
+func TestNeedsAnotherShakeDisabled(t *testing.T) {
+	withConfig(t, nil)
+	for range 2 {
+		if needsAnotherShake("U1", "Will it rain?") {
+			t.Fatal("needsAnotherShake = true without SHAKE_TWICE_WINDOW")
+		}
+	}
+}
+
+func TestAskCommandShakeTwice(t *testing.T) {
+	withConfig(t, map[string]string{"SHAKE_TWICE_WINDOW": "1m"})
+	cmd := slack.SlashCommand{Command: "/ask8ball", Text: "Will it rain?", TeamID: "T1", ChannelID: "C1", UserID: "U1"}
+	if reply := askCommand(cmd); reply.Text != replyShakeAgain {
+		t.Errorf("first shake = %q, want %q", reply.Text, replyShakeAgain)
+	}
+	if reply := askCommand(cmd); reply.Text == replyShakeAgain || reply.Text == "" {
+		t.Errorf("second shake = %q, want an answer", reply.Text)
+	}
+}
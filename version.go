@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// version is the build's version, set with -ldflags "-X main.version=...".
+// Without it, the module version from the build info is used.
+var version = ""
+
+// buildVersion is the version reported by /version.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// Where the global response list came from.
+const (
+	provenanceBuiltin = "builtin"
+	provenanceBlend   = "blend"
+	provenanceFile    = "file"
+)
+
+// responsesProvenance records at load time where the active responses came
+// from, to help debug which list is in use.
+type responsesProvenance struct {
+	// Source is provenanceBuiltin, provenanceBlend or provenanceFile.
+	Source string `json:"source"`
+	// Theme is the built-in theme, for provenanceBuiltin.
+	Theme string `json:"theme,omitempty"`
+	// Path is the RESPONSES_FILE, for provenanceFile.
+	Path string `json:"path,omitempty"`
+	// Count is how many responses are active after filtering.
+	Count int `json:"count"`
+
+	// OverridesPath is the RESPONSE_OVERRIDES_FILE, if any, replacing the
+	// list for OverrideEnterprises enterprises and OverrideTeams teams.
+	OverridesPath       string `json:"overrides_path,omitempty"`
+	OverrideEnterprises int    `json:"override_enterprises,omitempty"`
+	OverrideTeams       int    `json:"override_teams,omitempty"`
+}
+
+// String describes p in one line, e.g. for logs.
+func (p responsesProvenance) String() string {
+	var s string
+	switch p.Source {
+	case provenanceFile:
+		s = fmt.Sprintf("%d responses from file %s", p.Count, p.Path)
+	case provenanceBlend:
+		s = fmt.Sprintf("%d responses from a built-in theme blend", p.Count)
+	default:
+		s = fmt.Sprintf("%d responses from built-in theme %q", p.Count, p.Theme)
+	}
+	if p.OverridesPath != "" {
+		s += fmt.Sprintf(", overridden for %d enterprises and %d teams by %s",
+			p.OverrideEnterprises, p.OverrideTeams, p.OverridesPath)
+	}
+	return s
+}
+
+// versionInfo is the body of /version.
+type versionInfo struct {
+	Version   string              `json:"version"`
+	Responses responsesProvenance `json:"responses"`
+	Summary   string              `json:"summary"`
+}
+
+// handleVersion reports the running version and where its responses came
+// from.
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, versionInfo{
+		Version:   buildVersion(),
+		Responses: currentResponses().Provenance,
+		Summary:   currentResponses().Provenance.String(),
+	})
+}
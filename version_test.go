@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionProvenance(t *testing.T) {
+	responses := writeFile(t, "responses.json", `["Yes.", "No.", "Maybe."]`)
+	overrides := writeFile(t, "overrides.json", `{"teams": {"T1": ["Team yes."], "T2": ["Team no."]}}`)
+	tests := []struct {
+		name        string
+		env         map[string]string
+		want        responsesProvenance
+		wantSummary string
+	}{
+		{"built-in", nil,
+			responsesProvenance{Source: provenanceBuiltin, Theme: defaultTheme, Count: len(builtinThemes[defaultTheme])},
+			""},
+		{"file", map[string]string{"RESPONSES_FILE": responses},
+			responsesProvenance{Source: provenanceFile, Path: responses, Count: 3},
+			"3 responses from file " + responses},
+		{"missing file falls back", map[string]string{"RESPONSES_FILE": responses + ".missing", "RESPONSES_EMPTY_FALLBACK": "true"},
+			responsesProvenance{Source: provenanceBuiltin, Theme: defaultTheme, Count: len(builtinThemes[defaultTheme])},
+			""},
+		{"overrides", map[string]string{"RESPONSES_FILE": responses, "RESPONSE_OVERRIDES_FILE": overrides},
+			responsesProvenance{Source: provenanceFile, Path: responses, Count: 3, OverridesPath: overrides, OverrideTeams: 2},
+			"3 responses from file " + responses + ", overridden for 0 enterprises and 2 teams by " + overrides},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.env)
+			rec := httptest.NewRecorder()
+			handleVersion(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET /version = %d", rec.Code)
+			}
+			var info versionInfo
+			if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+				t.Fatal(err)
+			}
+			if info.Responses != tt.want {
+				t.Errorf("responses = %+v, want %+v", info.Responses, tt.want)
+			}
+			wantSummary := tt.wantSummary
+			if wantSummary == "" {
+				wantSummary = tt.want.String()
+			}
+			if info.Summary != wantSummary {
+				t.Errorf("summary = %q, want %q", info.Summary, wantSummary)
+			}
+			if info.Version != buildVersion() {
+				t.Errorf("version = %q, want %q", info.Version, buildVersion())
+			}
+		})
+	}
+}
+
+func TestBuildVersion(t *testing.T) {
+	old := version
+	t.Cleanup(func() { version = old })
+	version = "v1.2.3"
+	if got := buildVersion(); got != "v1.2.3" {
+		t.Errorf("buildVersion = %q, want the -ldflags version", got)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// webhookAsk is the body accepted by /webhook/ask.
+type webhookAsk struct {
+	Question string `json:"question"`
+}
+
+// webhookAnswer is the reply sent by /webhook/ask. Answer is the answer
+// alone; what Slack replies show around it comes in fields of its own.
+type webhookAnswer struct {
+	Answer   string `json:"answer"`
+	Category string `json:"category,omitempty"`
+	// Suffix is CATEGORY_SUFFIX's text for Category.
+	Suffix string `json:"suffix,omitempty"`
+	// Reason is the answer's reason, with SHOW_REASON.
+	Reason string `json:"reason,omitempty"`
+	// Note is the MILESTONES note, if this ask reached one.
+	Note string `json:"note,omitempty"`
+	// LuckyNumber is set with LUCKY_NUMBER.
+	LuckyNumber int `json:"lucky_number,omitempty"`
+	// Confidence is confidenceFor(Category), omitted for canned replies
+	// to rejected questions.
+	Confidence *float64 `json:"confidence,omitempty"`
+	// Trace is the decision trace, with DEBUG_TRACE.
+	Trace decisionTrace `json:"trace,omitempty"`
+}
+
+// handleWebhookAsk answers questions from non-Slack tools such as CI jobs,
+// authenticated with the shared WEBHOOK_TOKEN instead of Slack's signature.
+func handleWebhookAsk(w http.ResponseWriter, r *http.Request) {
+	if cfg.WebhookToken == "" {
+		writeJSONError(w, http.StatusNotFound, "webhook not enabled")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.WebhookToken)) != 1 {
+		logWarn("Rejecting webhook request with a bad token")
+		writeJSONError(w, http.StatusUnauthorized, "invalid bearer token")
+		return
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		logError("Error reading request body: %v", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	var req webhookAsk
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "body must be a JSON object")
+		return
+	}
+	if normalizeText(req.Question) == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing question")
+		return
+	}
+
+	// Webhook questions come from no channel or user, so only the limits
+	// that don't need one, like BUSINESS_HOURS, can refuse them.
+	if refusal := askRefusal("", "", ""); refusal != "" {
+		writeJSON(w, webhookAnswer{Answer: refusal})
+		return
+	}
+	resp := ask(askRequest{Question: req.Question})
+	log.Printf("Webhook Replying with: %s (index %d)", resp.Text, resp.Index)
+	out := webhookAnswer{Answer: resp.Text, Category: resp.Category, Reason: resp.Reason, Note: resp.Note, Trace: resp.Trace}
+	if resp.answered() {
+		out.Suffix = cfg.CategorySuffix[resp.Category]
+		confidence := confidenceFor(resp.Category)
+		out.Confidence = &confidence
+		if liveFlags().LuckyNumber {
+			out.LuckyNumber = luckyNumber(rng)
+		}
+	}
+	writeJSON(w, out)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHandleWebhookAsk(t *testing.T) {
+	c := withConfig(t, map[string]string{"WEBHOOK_TOKEN": "ci-token"})
+	tests := []struct {
+		name   string
+		method string
+		auth   string
+		body   string
+		want   int
+	}{
+		{"valid", "POST", "Bearer ci-token", `{"question":"Will the build pass?"}`, http.StatusOK},
+		{"bad token", "POST", "Bearer wrong", `{"question":"Will the build pass?"}`, http.StatusUnauthorized},
+		{"no bearer prefix", "POST", "ci-token", `{"question":"Will the build pass?"}`, http.StatusUnauthorized},
+		{"no token", "POST", "", `{"question":"Will the build pass?"}`, http.StatusUnauthorized},
+		{"missing question", "POST", "Bearer ci-token", `{}`, http.StatusBadRequest},
+		{"blank question", "POST", "Bearer ci-token", `{"question":"  "}`, http.StatusBadRequest},
+		{"not json", "POST", "Bearer ci-token", `question=Will it?`, http.StatusBadRequest},
+		{"wrong method", "GET", "Bearer ci-token", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/webhook/ask", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			handleWebhookAsk(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var out webhookAnswer
+			if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+				t.Fatalf("decoding %q: %v", w.Body, err)
+			}
+			if !slices.Contains(c.Responses, out.Answer) || out.Category != categoryOf(out.Answer) || out.Confidence == nil {
+				t.Errorf("answered %+v", out)
+			}
+		})
+	}
+}
+
+func TestHandleWebhookAskDisabled(t *testing.T) {
+	withConfig(t, map[string]string{"WEBHOOK_TOKEN": ""})
+	r := httptest.NewRequest("POST", "/webhook/ask", strings.NewReader(`{"question":"Will it rain?"}`))
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	handleWebhookAsk(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 without WEBHOOK_TOKEN", w.Code)
+	}
+}
+
+func TestHandleWebhookAskRejectedQuestion(t *testing.T) {
+	withConfig(t, map[string]string{"WEBHOOK_TOKEN": "ci-token"})
+	r := httptest.NewRequest("POST", "/webhook/ask", strings.NewReader(`{"question":"Why is it red?"}`))
+	r.Header.Set("Authorization", "Bearer ci-token")
+	w := httptest.NewRecorder()
+	handleWebhookAsk(w, r)
+	var out webhookAnswer
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body, err)
+	}
+	if w.Code != http.StatusOK || out.Answer != replyOpenEnded || out.Confidence != nil {
+		t.Errorf("status %d, answered %+v; want the open-ended reply without a confidence", w.Code, out)
+	}
+}
+
+func TestHandleWebhookAskConfidence(t *testing.T) {
+	for _, tt := range []struct {
+		category string
+		want     float64
+	}{
+		{categoryYes, 0.8},
+		{categoryNo, 0.2},
+		{categoryMaybe, 0.5},
+	} {
+		t.Run(tt.category, func(t *testing.T) {
+			responses := writeFile(t, "responses.json", `[{"text": "The stars say so.", "category": "`+tt.category+`"}]`)
+			withConfig(t, map[string]string{"WEBHOOK_TOKEN": "ci-token", "RESPONSES_FILE": responses})
+			r := httptest.NewRequest("POST", "/webhook/ask", strings.NewReader(`{"question":"Will the build pass?"}`))
+			r.Header.Set("Authorization", "Bearer ci-token")
+			w := httptest.NewRecorder()
+			handleWebhookAsk(w, r)
+
+			var out map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+				t.Fatalf("decoding %q: %v", w.Body, err)
+			}
+			if out["category"] != tt.category || out["confidence"] != tt.want {
+				t.Errorf("answered %s, want category %s with confidence %v", w.Body, tt.category, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// boostConfig temporarily weights one answer more heavily, e.g. for a
+// "Friday is always yes day" event.
+type boostConfig struct {
+	Answer     string    `json:"answer,omitempty"`
+	Multiplier float64   `json:"multiplier,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+}
+
+// active reports whether the boost applies at t.
+func (b boostConfig) active(t time.Time) bool {
+	return b.Answer != "" && !t.Before(b.Start) && t.Before(b.End)
+}
+
+// loadBoost reads BOOST_ANSWER, BOOST_MULTIPLIER, BOOST_START and BOOST_END.
+// Times are RFC 3339. A zero boostConfig is returned when no boost is set.
+func loadBoost() (boostConfig, error) {
+	b := boostConfig{Answer: os.Getenv("BOOST_ANSWER")}
+	if b.Answer == "" {
+		return b, nil
+	}
+
+	var err error
+	if b.Multiplier, err = envFloat("BOOST_MULTIPLIER", 2); err != nil {
+		return b, err
+	}
+	if b.Multiplier <= 0 {
+		return b, fmt.Errorf("BOOST_MULTIPLIER must be positive, got %v", b.Multiplier)
+	}
+	if b.Start, err = time.Parse(time.RFC3339, os.Getenv("BOOST_START")); err != nil {
+		return b, fmt.Errorf("invalid BOOST_START: %w", err)
+	}
+	if b.End, err = time.Parse(time.RFC3339, os.Getenv("BOOST_END")); err != nil {
+		return b, fmt.Errorf("invalid BOOST_END: %w", err)
+	}
+	if !b.End.After(b.Start) {
+		return b, errors.New("BOOST_END must be after BOOST_START")
+	}
+	return b, nil
+}
+
+// categoryWeights multiplies the selection weight of answers by category.
+// Categories it doesn't mention keep their weight.
+type categoryWeights map[string]float64
+
+// moodWeights is the 8-ball's MOOD at hour (0-23): grumpy in the morning,
+// favouring no over yes, sunny in the afternoon, and even-tempered
+// otherwise, when it returns nil.
+func moodWeights(hour int) categoryWeights {
+	switch {
+	case hour >= 5 && hour < 12:
+		return categoryWeights{categoryYes: 0.75, categoryNo: 1.5}
+	case hour >= 12 && hour < 18:
+		return categoryWeights{categoryYes: 1.5, categoryNo: 0.75}
+	}
+	return nil
+}
+
+// responseWeights returns a selection weight per response at time t, or nil
+// when every response is equally likely.
+func responseWeights(resps []string, t time.Time) []float64 {
+	boost := cfg.Boost.active(t)
+	var mood categoryWeights
+	if liveFlags().Mood {
+		mood = moodWeights(t.Hour())
+	}
+	if !boost && mood == nil {
+		return nil
+	}
+	weights := make([]float64, len(resps))
+	for i, text := range resps {
+		weights[i] = 1
+		if boost && text == cfg.Boost.Answer {
+			weights[i] = cfg.Boost.Multiplier
+		}
+		if m, ok := mood[categoryOf(text)]; ok {
+			weights[i] *= m
+		}
+	}
+	return weights
+}
+
+// pickWeighted chooses a position with probability proportional to its
+// weight. A nil weights slice means a uniform choice among n.
+func pickWeighted(r *rand.Rand, n int, weights []float64) int {
+	if weights == nil {
+		return pickIndex(r, n)
+	}
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	x := r.Float64() * total
+	for i, w := range weights {
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return len(weights) - 1
+}
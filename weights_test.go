@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// boostEnv boosts "It is certain." 50 times over during 2024-06-07 in UTC.
+var boostEnv = map[string]string{
+	"BOOST_ANSWER":     "It is certain.",
+	"BOOST_MULTIPLIER": "50",
+	"BOOST_START":      "2024-06-07T00:00:00Z",
+	"BOOST_END":        "2024-06-08T00:00:00Z",
+}
+
+func TestBoostWindow(t *testing.T) {
+	c := withConfig(t, boostEnv)
+	tests := []struct {
+		name   string
+		at     string
+		active bool
+	}{
+		{"before", "2024-06-06T23:59:59Z", false},
+		{"at start", "2024-06-07T00:00:00Z", true},
+		{"within", "2024-06-07T13:00:00Z", true},
+		{"at end", "2024-06-08T00:00:00Z", false},
+		{"after", "2024-06-09T10:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, _ := time.Parse(time.RFC3339, tt.at)
+			weights := responseWeights(c.Responses, at)
+			if !tt.active {
+				if weights != nil {
+					t.Errorf("weights = %v outside the window, want uniform", weights)
+				}
+				return
+			}
+			for i, text := range c.Responses {
+				want := 1.0
+				if text == "It is certain." {
+					want = 50
+				}
+				if weights[i] != want {
+					t.Errorf("weight of %q = %v, want %v", text, weights[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestBoostSkewsAnswers(t *testing.T) {
+	tests := []struct {
+		name string
+		at   string
+		min  int
+		max  int
+	}{
+		// 50 in 69 of the weight is about 72%; 1 in 20 is 5%.
+		{"within", "2024-06-07T13:00:00Z", 650, 800},
+		{"outside", "2024-06-09T13:00:00Z", 20, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := withConfig(t, boostEnv)
+			at, _ := time.Parse(time.RFC3339, tt.at)
+			setClock(t, at)
+			r := rand.New(rand.NewSource(7))
+			boosted := 0
+			for range 1000 {
+				resp, err := Answer("Will it be sunny?", c.Responses, true, r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.Text == "It is certain." {
+					boosted++
+				}
+			}
+			if boosted < tt.min || boosted > tt.max {
+				t.Errorf("boosted answer given %d times in 1000, want %d-%d", boosted, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestLoadBoostRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"zero multiplier", map[string]string{"BOOST_MULTIPLIER": "0"}},
+		{"bad start", map[string]string{"BOOST_START": "friday"}},
+		{"end before start", map[string]string{"BOOST_END": "2024-06-06T00:00:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range boostEnv {
+				t.Setenv(name, value)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if b, err := loadBoost(); err == nil {
+				t.Errorf("loadBoost = %+v, want an error", b)
+			}
+		})
+	}
+}
+
+func TestMoodWeights(t *testing.T) {
+	tests := []struct {
+		hour    int
+		yes, no float64 // 0 means the category isn't weighted
+	}{
+		{4, 0, 0},
+		{5, 0.75, 1.5},
+		{11, 0.75, 1.5},
+		{12, 1.5, 0.75},
+		{17, 1.5, 0.75},
+		{18, 0, 0},
+		{23, 0, 0},
+	}
+	for _, tt := range tests {
+		w := moodWeights(tt.hour)
+		if w[categoryYes] != tt.yes || w[categoryNo] != tt.no {
+			t.Errorf("moodWeights(%d) = %v, want yes %v and no %v", tt.hour, w, tt.yes, tt.no)
+		}
+		if _, ok := w[categoryMaybe]; ok {
+			t.Errorf("moodWeights(%d) weights maybe", tt.hour)
+		}
+	}
+}
+
+// moodShares asks 2000 questions at hour and returns the share of yes and
+// no answers.
+func moodShares(t *testing.T, c Config, hour int) (yes, no float64) {
+	t.Helper()
+	setClock(t, time.Date(2024, 5, 1, hour, 30, 0, 0, time.UTC))
+	r := rand.New(rand.NewSource(11))
+	const n = 2000
+	counts := map[string]int{}
+	for range n {
+		resp, err := Answer("Will it be sunny?", c.Responses, true, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[resp.Category]++
+	}
+	return float64(counts[categoryYes]) / n, float64(counts[categoryNo]) / n
+}
+
+func TestMoodSkewsAnswers(t *testing.T) {
+	c := withConfig(t, map[string]string{"MOOD": "true"})
+	morningYes, morningNo := moodShares(t, c, 8)
+	afternoonYes, afternoonNo := moodShares(t, c, 15)
+	eveningYes, eveningNo := moodShares(t, c, 21)
+
+	// Unweighted, half the answers are yes and a quarter no. The morning
+	// shifts that to 37.5% each, the afternoon to about 63% and 16%.
+	if !(morningNo > eveningNo+0.05 && morningYes < eveningYes-0.05) {
+		t.Errorf("morning yes %.2f no %.2f, evening yes %.2f no %.2f: want the morning grumpier", morningYes, morningNo, eveningYes, eveningNo)
+	}
+	if !(afternoonYes > eveningYes+0.05 && afternoonNo < eveningNo-0.05) {
+		t.Errorf("afternoon yes %.2f no %.2f, evening yes %.2f no %.2f: want the afternoon sunnier", afternoonYes, afternoonNo, eveningYes, eveningNo)
+	}
+	if eveningYes < 0.45 || eveningYes > 0.55 {
+		t.Errorf("evening yes share %.2f, want about half", eveningYes)
+	}
+
+	// With MOOD off the hour makes no difference.
+	c = withConfig(t, map[string]string{"MOOD": "false"})
+	setClock(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	if w := responseWeights(c.Responses, clock()); w != nil {
+		t.Errorf("weights with MOOD off = %v, want uniform", w)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// checkWritable reports whether path can be written without changing it:
+// an existing file must open for appending, and a directory, or the
+// directory of a file that doesn't exist yet, must let a file be created in
+// it. A read-only filesystem fails with EROFS.
+func checkWritable(path string) error {
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && !info.IsDir():
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist):
+		path = filepath.Dir(path)
+	default:
+		return err
+	}
+	f, err := os.CreateTemp(path, ".8ball-write-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkWrites checks the paths c's features write to before any of them
+// is used. A feature whose path isn't writable is turned off with a
+// warning, so the bot still answers on a read-only filesystem, or with
+// STRICT_WRITES is a startup error.
+func checkWrites(c *Config) error {
+	disable := func(setting, path, feature string, err error) error {
+		if c.StrictWrites {
+			return fmt.Errorf("%s %s isn't writable: %w", setting, path, err)
+		}
+		logWarn("%s %s isn't writable (%v); %s is off", setting, path, err, feature)
+		return nil
+	}
+	if c.AuditFile != "" {
+		if err := checkWritable(c.AuditFile); err != nil {
+			if err := disable("AUDIT_FILE", c.AuditFile, "audit logging", err); err != nil {
+				return err
+			}
+			c.AuditFile = ""
+		}
+	}
+	if c.PersistAddedResponses && c.Provenance.Path != "" {
+		// Added responses replace the file by renaming a new one over it.
+		if err := checkWritable(filepath.Dir(c.Provenance.Path)); err != nil {
+			if err := disable("RESPONSES_FILE", c.Provenance.Path, "PERSIST_ADDED_RESPONSES", err); err != nil {
+				return err
+			}
+			c.PersistAddedResponses = false
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readOnlyDir returns a directory the test can't write to, skipping the
+// test when running as root, which can write anywhere.
+func readOnlyDir(t *testing.T) string {
+	t.Helper()
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores file permissions")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o700) })
+	return dir
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, "audit.jsonl", "kept\n")
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"existing file", file, true},
+		{"new file", filepath.Join(dir, "audit.jsonl"), true},
+		{"directory", dir, true},
+		{"missing directory", filepath.Join(dir, "missing", "audit.jsonl"), false},
+		{"under a file", filepath.Join(file, "audit.jsonl"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkWritable(tt.path); (err == nil) != tt.ok {
+				t.Errorf("checkWritable(%s) = %v, want ok %v", tt.path, err, tt.ok)
+			}
+		})
+	}
+	// Checking leaves no trace.
+	if data, _ := os.ReadFile(file); string(data) != "kept\n" {
+		t.Errorf("checked file holds %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("checked directory holds %v", entries)
+	}
+}
+
+func TestCheckWritableReadOnly(t *testing.T) {
+	dir := readOnlyDir(t)
+	if err := checkWritable(filepath.Join(dir, "audit.jsonl")); !os.IsPermission(err) {
+		t.Errorf("checkWritable in a read-only directory = %v, want a permission error", err)
+	}
+}
+
+func TestCheckWrites(t *testing.T) {
+	unwritable := filepath.Join(writeFile(t, "blocker", ""), "audit.jsonl")
+	responses := writeFile(t, "responses.json", `["Yes."]`)
+	tests := []struct {
+		name       string
+		strict     bool
+		audit      string
+		wantErr    string
+		wantAudit  string
+		wantLogged string
+	}{
+		{"writable", false, filepath.Join(t.TempDir(), "audit.jsonl"), "", "kept", ""},
+		{"unwritable", false, unwritable, "", "", "AUDIT_FILE " + unwritable + " isn't writable"},
+		{"unwritable strict", true, unwritable, "AUDIT_FILE " + unwritable + " isn't writable", unwritable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLog(t)
+			c := Config{StrictWrites: tt.strict, AuditFile: tt.audit, PersistAddedResponses: true, Provenance: responsesProvenance{Path: responses}}
+			err := checkWrites(&c)
+			if (err == nil) != (tt.wantErr == "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkWrites = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantAudit == "kept" {
+				tt.wantAudit = tt.audit
+			}
+			if c.AuditFile != tt.wantAudit {
+				t.Errorf("AuditFile = %q, want %q", c.AuditFile, tt.wantAudit)
+			}
+			if !c.PersistAddedResponses {
+				t.Error("PERSIST_ADDED_RESPONSES turned off for a writable RESPONSES_FILE")
+			}
+			if !strings.Contains(logs.String(), tt.wantLogged) {
+				t.Errorf("log %q lacks %q", logs, tt.wantLogged)
+			}
+		})
+	}
+}
+
+func TestCheckWritesResponsesFile(t *testing.T) {
+	// The file is replaced by renaming a new one over it, so it's the
+	// directory that must be writable.
+	path := filepath.Join(readOnlyDir(t), "responses.json")
+	for _, strict := range []bool{false, true} {
+		logs := captureLog(t)
+		c := Config{StrictWrites: strict, PersistAddedResponses: true, Provenance: responsesProvenance{Path: path}}
+		err := checkWrites(&c)
+		if strict {
+			if err == nil || !c.PersistAddedResponses {
+				t.Errorf("STRICT_WRITES: checkWrites = %v, want a startup error", err)
+			}
+			continue
+		}
+		if err != nil || c.PersistAddedResponses || !strings.Contains(logs.String(), "PERSIST_ADDED_RESPONSES is off") {
+			t.Errorf("checkWrites = %v, persisting %v, log %q; want persistence off with a warning", err, c.PersistAddedResponses, logs)
+		}
+	}
+}